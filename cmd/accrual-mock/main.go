@@ -0,0 +1,90 @@
+// Команда accrual-mock — локальная имитация системы расчёта начислений
+// для ручной проверки gophermart без бинарника из курса.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/accrual/accrualmock"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	var (
+		cfg         accrualmock.Config
+		addr        string
+		scriptsPath string
+		defaultSpec string
+	)
+	flag.StringVar(&addr, "a", "localhost:8081", "Server address")
+	flag.StringVar(&scriptsPath, "scripts", "", `JSON file with per-order scripts: {"<number>": "REGISTERED,PROCESSING,PROCESSED:500"}`)
+	flag.StringVar(&defaultSpec, "default", "PROCESSING,PROCESSED:100", "Script for orders without their own (empty: respond 204)")
+	flag.IntVar(&cfg.RPM, "rpm", 0, "Requests per minute before responding 429 (0 disables the limit)")
+	flag.DurationVar(&cfg.RetryAfter, "retry-after", 0, "Retry-After of 429 responses (default: until the end of the minute window)")
+	flag.DurationVar(&cfg.MinDelay, "min-delay", 0, "Minimum response delay")
+	flag.DurationVar(&cfg.MaxDelay, "max-delay", 0, "Maximum response delay")
+	flag.Float64Var(&cfg.NoContentRate, "no-content-rate", 0, "Share of random 204 responses")
+	flag.Float64Var(&cfg.ErrorRate, "error-rate", 0, "Share of random 500 responses")
+	flag.Uint64Var(&cfg.Seed, "seed", 0, "Random seed (0: random)")
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize zap logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	if err := run(addr, scriptsPath, defaultSpec, cfg, logger); err != nil {
+		logger.Fatal("accrual mock failed", zap.Error(err))
+	}
+}
+
+func run(addr, scriptsPath, defaultSpec string, cfg accrualmock.Config, logger *zap.Logger) error {
+	if defaultSpec != "" {
+		steps, err := accrualmock.ParseSteps(defaultSpec)
+		if err != nil {
+			return fmt.Errorf("default script: %w", err)
+		}
+		cfg.Default = steps
+	}
+
+	mock := accrualmock.New(cfg)
+	if scriptsPath != "" {
+		f, err := os.Open(scriptsPath)
+		if err != nil {
+			return err
+		}
+		err = mock.LoadScripts(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	srv := &http.Server{Addr: addr, Handler: mock, ReadHeaderTimeout: 5 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("accrual mock listening", zap.String("addr", addr), zap.Int("rpm", cfg.RPM))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
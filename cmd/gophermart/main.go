@@ -2,13 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go-musthave-diploma-tpl/internal/accrual"
 	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/events"
 	"go-musthave-diploma-tpl/internal/handler"
+	"go-musthave-diploma-tpl/internal/metrics"
+	"go-musthave-diploma-tpl/internal/migrations"
+	"go-musthave-diploma-tpl/internal/outbox"
 	"go-musthave-diploma-tpl/internal/repository/postgres"
 	"go-musthave-diploma-tpl/internal/service"
+	"go-musthave-diploma-tpl/internal/storage"
+	"go-musthave-diploma-tpl/internal/tracing"
+	"go-musthave-diploma-tpl/internal/webhook"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -20,6 +30,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	fx.New(
 		fx.Provide(
 			config.InitConfig,
@@ -27,22 +41,35 @@ func main() {
 			newRouter,
 			newStorage,
 
+			newKeyring,
 			NewAuthService,
 			NewAuthHandler,
 			NewUserRepository,
+			NewSessionRepository,
 
 			NewOrderRepository,
 			NewOrdersService,
 			NewOrdersHandler,
+			newEventHub,
+			NewEventsHandler,
 
 			NewWithdrawalRepository,
+			NewLedgerRepository,
 			NewBalanceService,
 			NewBalanceHandler,
 
 			NewAccrualClient,
 			NewAccrualWorker,
+
+			NewIdempotencyRepository,
+
+			NewWebhookRepository,
+			NewWebhookService,
+			NewWebhookHandler,
+
+			newReadiness,
 		),
-		fx.Invoke(startServer, startMigrations, StartAccrualWorker),
+		fx.Invoke(setupTracing, startServer, startAdminServer, startMigrations, StartAccrualWorker, startIdempotencyCleanup, startOrderEventListener, startOutboxRelay, startWebhookWorker),
 	).Run()
 }
 
@@ -56,44 +83,101 @@ func newLogger() (*zap.Logger, error) {
 	return logger, nil
 }
 
-func newStorage(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger) (*postgres.DBStorage, error) {
-	dbStore, err := postgres.NewDBStorage(cfg.DatabaseURI, logger)
+// newStorage выбирает бэкенд по DATABASE_URI: со схемой memory:// или без
+// DATABASE_URI данные хранятся в памяти процесса, со схемой sqlite:// — в файле SQLite.
+func newStorage(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger) (*storage.Storage, error) {
+	pool := postgres.PoolConfig{
+		MaxConns:          int32(cfg.DBMaxConns),
+		MaxConnLifetime:   cfg.DBMaxConnLifetime,
+		MaxConnIdleTime:   cfg.DBMaxConnIdleTime,
+		HealthCheckPeriod: cfg.DBHealthCheckPeriod,
+	}
+	store, err := storage.Open(context.Background(), cfg.DatabaseURI, pool, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
-	logger.Info("Using PostgreSQL storage")
+	if store.Backend == "memory" {
+		if cfg.DatabaseURI == "" {
+			logger.Warn("⚠️ DATABASE_URI is not set: falling back to in-memory storage, ALL DATA WILL BE LOST on restart. " +
+				"Set DATABASE_URI to a PostgreSQL DSN or sqlite://<path>, or to memory:// to silence this warning")
+		} else {
+			logger.Warn("Using in-memory storage: data will not survive a restart")
+		}
+		return store, nil
+	}
+
+	logger.Info("Using storage backend", zap.String("backend", store.Backend))
+	if store.Postgres != nil {
+		if err := metrics.RegisterPoolStats(store.Postgres.Pool); err != nil {
+			return nil, fmt.Errorf("failed to register DB metrics: %w", err)
+		}
+	}
 	lc.Append(fx.Hook{
 		OnStop: func(ctx context.Context) error {
 			logger.Info("closing database connection")
-			return dbStore.Close()
+			return store.Close()
 		},
 	})
-	return dbStore, nil
+	return store, nil
+}
+
+func newKeyring(cfg *config.Config, logger *zap.Logger) (*service.Keyring, error) {
+	if cfg.JWTKeysDir == "" {
+		if !cfg.JWTEphemeralKeys {
+			return nil, errors.New("JWT_KEYS_DIR is required (set JWT_EPHEMERAL_KEYS=true to use a random key in development)")
+		}
+		logger.Warn("⚠️ DEV ONLY: JWT_EPHEMERAL_KEYS is set, signing tokens with a random key: " +
+			"tokens will not survive a restart and are not accepted by other replicas")
+		return service.GenerateEphemeralKeyring()
+	}
+
+	keys, err := service.LoadKeyring(cfg.JWTKeysDir, cfg.JWTActiveKID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+	logger.Info("JWT keyring loaded", zap.String("dir", cfg.JWTKeysDir), zap.String("active_kid", keys.ActiveKeyID()))
+	return keys, nil
 }
 
 // ------------------------ Repos & Services ------------------------
 
-func NewUserRepository(store *postgres.DBStorage) *postgres.UserRepository {
-	return postgres.NewUserRepository(store.DB)
+func NewUserRepository(store *storage.Storage) storage.UserRepository {
+	return store.Users
+}
+
+func NewOrderRepository(store *storage.Storage) storage.OrderRepository {
+	return store.Orders
+}
+
+func NewWithdrawalRepository(store *storage.Storage) storage.WithdrawalRepository {
+	return store.Withdrawals
 }
 
-func NewOrderRepository(store *postgres.DBStorage) *postgres.OrderRepository {
-	return postgres.NewOrderRepository(store.DB)
+func NewLedgerRepository(store *storage.Storage) storage.LedgerRepository {
+	return store.Ledger
 }
 
-func NewWithdrawalRepository(store *postgres.DBStorage) *postgres.WithdrawalRepository {
-	return postgres.NewWithdrawalRepository(store.DB)
+func NewIdempotencyRepository(store *storage.Storage) storage.IdempotencyRepository {
+	return store.Idempotency
 }
 
-func NewAuthService(repo *postgres.UserRepository, cfg *config.Config, logger *zap.Logger) *service.AuthService {
-	return service.NewAuthService(repo, cfg.AuthSecret, logger)
+func NewSessionRepository(store *storage.Storage) storage.SessionRepository {
+	return store.Sessions
+}
+
+func NewWebhookRepository(store *storage.Storage) storage.WebhookRepository {
+	return store.Webhooks
+}
+
+func NewAuthService(repo storage.UserRepository, sessionRepo storage.SessionRepository, keys *service.Keyring, cfg *config.Config, logger *zap.Logger) *service.AuthService {
+	return service.NewAuthService(repo, sessionRepo, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, logger)
 }
 
 func NewAuthHandler(authService *service.AuthService, logger *zap.Logger) *handler.AuthHandler {
 	return handler.NewAuthHandler(authService, logger)
 }
 
-func NewOrdersService(logger *zap.Logger, orderRepo *postgres.OrderRepository) *service.OrdersService {
+func NewOrdersService(logger *zap.Logger, orderRepo storage.OrderRepository) *service.OrdersService {
 	return service.NewOrdersService(logger, orderRepo)
 }
 
@@ -101,44 +185,152 @@ func NewOrdersHandler(ordersService *service.OrdersService, logger *zap.Logger)
 	return handler.NewOrdersHandler(ordersService, logger)
 }
 
-func NewBalanceService(repo *postgres.WithdrawalRepository, logger *zap.Logger) *service.BalanceService {
-	return service.NewBalanceService(repo, logger)
+func NewEventsHandler(ordersService *service.OrdersService, hub *events.Hub, logger *zap.Logger) *handler.EventsHandler {
+	return handler.NewEventsHandler(ordersService, hub, logger)
+}
+
+func NewBalanceService(repo storage.WithdrawalRepository, ledgerRepo storage.LedgerRepository, logger *zap.Logger) *service.BalanceService {
+	return service.NewBalanceService(repo, ledgerRepo, logger)
 }
 
 func NewBalanceHandler(s *service.BalanceService, logger *zap.Logger) *handler.BalanceHandler {
 	return handler.NewBalanceHandler(s, logger)
 }
 
+func NewWebhookService(repo storage.WebhookRepository, cfg *config.Config, logger *zap.Logger) *service.WebhookService {
+	return service.NewWebhookService(repo, cfg, logger)
+}
+
+func NewWebhookHandler(s *service.WebhookService, logger *zap.Logger) *handler.WebhookHandler {
+	return handler.NewWebhookHandler(s, logger)
+}
+
 // ------------------------ Accrual ------------------------
 
 func NewAccrualClient(cfg *config.Config) *accrual.Client {
 	return service.NewAccrualClient(cfg)
 }
 
-func NewAccrualWorker(orderRepo *postgres.OrderRepository, client *accrual.Client, logger *zap.Logger) *service.AccrualWorker {
-	return service.NewAccrualWorker(orderRepo, client, logger)
+func NewAccrualWorker(orderRepo storage.OrderRepository, client *accrual.Client, cfg *config.Config, logger *zap.Logger) *service.AccrualWorker {
+	return service.NewAccrualWorker(orderRepo, client, cfg, logger)
+}
+
+// ------------------------ Order events ------------------------
+
+func newEventHub() *events.Hub {
+	return events.NewHub(64)
+}
+
+// startOrderEventListener пересылает события заказов из хранилища (в PostgreSQL —
+// из LISTEN/NOTIFY) в хаб, так что SSE-клиент получает их независимо от того,
+// какая реплика обработала заказ.
+func startOrderEventListener(lc fx.Lifecycle, store *storage.Storage, hub *events.Hub, logger *zap.Logger) {
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				for {
+					err := store.Events.Listen(runCtx, hub.Publish)
+					if err == nil {
+						return
+					}
+					logger.Error("order event listener failed, restarting", zap.Error(err))
+					select {
+					case <-runCtx.Done():
+						return
+					case <-time.After(5 * time.Second):
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// ------------------------ Health ------------------------
+
+// newReadiness собирает проверки для /readyz: доступность базы, версию схемы
+// и давность последнего успешного цикла воркера начислений.
+func newReadiness(cfg *config.Config, store *storage.Storage, worker *service.AccrualWorker) (*handler.Readiness, error) {
+	checks := []handler.ReadinessCheck{
+		{Name: "database", Check: store.Ping},
+		{Name: "accrual", Check: func(ctx context.Context) error {
+			if age := time.Since(worker.LastSuccess()); age > cfg.ReadinessAccrualMaxAge {
+				return fmt.Errorf("last successful accrual cycle %s ago", age.Round(time.Second))
+			}
+			return nil
+		}},
+	}
+	if store.Postgres == nil {
+		return handler.NewReadiness(2*time.Second, checks...), nil
+	}
+
+	expected, err := migrations.Latest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	checks = append(checks, handler.ReadinessCheck{Name: "migrations", Check: func(ctx context.Context) error {
+		version, dirty, err := store.Postgres.SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("schema is dirty at version %d", version)
+		}
+		if version < expected {
+			return fmt.Errorf("schema version %d, expected %d", version, expected)
+		}
+		return nil
+	}})
+	return handler.NewReadiness(2*time.Second, checks...), nil
 }
 
 // ------------------------ Router ------------------------
 
-func newRouter(cfg *config.Config, logger *zap.Logger, authHandler *handler.AuthHandler, ordersHandler *handler.OrdersHandler, balanceHandler *handler.BalanceHandler) chi.Router {
+func newRouter(cfg *config.Config, logger *zap.Logger, authHandler *handler.AuthHandler, ordersHandler *handler.OrdersHandler, eventsHandler *handler.EventsHandler, balanceHandler *handler.BalanceHandler, webhookHandler *handler.WebhookHandler, idempotencyRepo storage.IdempotencyRepository, sessionRepo storage.SessionRepository, keys *service.Keyring, readiness *handler.Readiness) chi.Router {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(customMiddleware.Tracing())
 	r.Use(middleware.Recoverer)
 	r.Use(customMiddleware.Logger(logger))
+	r.Use(customMiddleware.Metrics())
 
 	r.Get("/health", handler.Health)
+	r.Get("/livez", handler.Health)
+	r.Get("/readyz", readiness.Readyz)
+	r.Get("/.well-known/jwks.json", handler.JWKS(keys))
 	r.Post("/api/user/register", authHandler.Register)
 	r.Post("/api/user/login", authHandler.Login)
+	r.Post("/api/user/token/refresh", authHandler.Refresh)
 
 	r.Group(func(r chi.Router) {
-		r.Use(customMiddleware.AuthMiddleware(cfg.AuthSecret, logger))
-		r.Post("/api/user/orders", ordersHandler.UploadOrder)
+		r.Use(customMiddleware.AuthMiddleware(keys, sessionRepo, logger))
+		r.Post("/api/user/logout", authHandler.Logout)
+
+		idempotent := customMiddleware.Idempotency(idempotencyRepo, cfg.IdempotencyTTL, logger)
+
+		r.With(idempotent).Post("/api/user/orders", ordersHandler.UploadOrder)
+		r.With(idempotent).Post("/api/user/orders/batch", ordersHandler.UploadOrdersBatch)
 		r.Get("/api/user/orders", ordersHandler.ListOrders)
+		r.Get("/api/user/orders/events", eventsHandler.OrderEvents)
+		r.Get("/api/user/orders/{number}", ordersHandler.GetOrder)
 		r.Get("/api/user/balance", balanceHandler.GetBalance)
-		r.Post("/api/user/balance/withdraw", balanceHandler.Withdraw)
+		r.With(idempotent).Post("/api/user/balance/withdraw", balanceHandler.Withdraw)
 		r.Get("/api/user/withdrawals", balanceHandler.ListWithdrawals)
+		r.Route("/api/user/webhooks", webhookHandler.Routes)
 	})
 
 	return r
@@ -146,11 +338,27 @@ func newRouter(cfg *config.Config, logger *zap.Logger, authHandler *handler.Auth
 
 // ------------------------ Server & Migrations ------------------------
 
-func startServer(lc fx.Lifecycle, cfg *config.Config, router chi.Router, logger *zap.Logger) {
+func setupTracing(lc fx.Lifecycle, cfg *config.Config, logger *zap.Logger) error {
+	shutdown, err := tracing.Setup(context.Background(), cfg.TracingExporter)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	logger.Info("tracing configured", zap.String("exporter", cfg.TracingExporter))
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return shutdown(ctx)
+		},
+	})
+	return nil
+}
+
+func startServer(lc fx.Lifecycle, cfg *config.Config, router chi.Router, readiness *handler.Readiness, hub *events.Hub, logger *zap.Logger) {
 	srv := &http.Server{
 		Addr:    cfg.RunAddress,
 		Handler: router,
 	}
+	// SSE-потоки сами не завершаются, и Shutdown ждал бы их до таймаута
+	srv.RegisterOnShutdown(hub.Close)
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			logger.Info("starting HTTP server", zap.String("addr", cfg.RunAddress))
@@ -162,13 +370,79 @@ func startServer(lc fx.Lifecycle, cfg *config.Config, router chi.Router, logger
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
+			// /readyz уже отвечает 503, а запросы ещё обслуживаются,
+			// пока балансировщик не выведет инстанс из ротации
+			readiness.Drain()
+			logger.Info("draining HTTP server", zap.Duration("delay", cfg.ShutdownDrainDelay))
+			select {
+			case <-time.After(cfg.ShutdownDrainDelay):
+			case <-ctx.Done():
+			}
+
 			logger.Info("shutting down HTTP server")
 			return srv.Shutdown(ctx)
 		},
 	})
 }
 
-func startMigrations(cfg *config.Config, logger *zap.Logger) error {
+// startAdminServer поднимает отдельный listener для /metrics, недоступный из публичного API.
+func startAdminServer(lc fx.Lifecycle, cfg *config.Config, orderRepo storage.OrderRepository, webhookService *service.WebhookService, logger *zap.Logger) error {
+	if cfg.AdminAddress == "" {
+		logger.Info("admin server is disabled")
+		return nil
+	}
+
+	err := metrics.RegisterBacklog(func(ctx context.Context) (metrics.OrderBacklog, error) {
+		counts, oldest, err := orderRepo.GetBacklog(ctx, logger)
+		return metrics.OrderBacklog{ByStatus: counts, OldestUpload: oldest}, err
+	}, domain.StatusNames(domain.PendingStatuses), 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to register backlog metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	// партнёрские вебхуки получают события всех пользователей, поэтому
+	// управляются только с админ-сервера и только по ключу партнёра
+	if cfg.PartnerAPIKey != "" {
+		admin := chi.NewRouter()
+		admin.Route("/admin/webhooks", handler.NewPartnerWebhookHandler(webhookService, cfg.PartnerAPIKey, logger).Routes)
+		mux.Handle("/admin/", admin)
+	} else {
+		logger.Info("partner webhook API is disabled: PARTNER_API_KEY is not set")
+	}
+
+	srv := &http.Server{
+		Addr:    cfg.AdminAddress,
+		Handler: mux,
+	}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("starting admin server", zap.String("addr", cfg.AdminAddress))
+			go func() {
+				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Error("admin server failed", zap.Error(err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("shutting down admin server")
+			return srv.Shutdown(ctx)
+		},
+	})
+	return nil
+}
+
+func startMigrations(cfg *config.Config, store *storage.Storage, logger *zap.Logger) error {
+	if store.Postgres == nil {
+		return nil
+	}
+	if !cfg.MigrateOnStart {
+		logger.Info("migrations on startup are disabled")
+		return nil
+	}
 	if err := postgres.RunMigrations(cfg.DatabaseURI, logger); err != nil {
 		return fmt.Errorf("migrations failed: %w", err)
 	}
@@ -177,29 +451,145 @@ func startMigrations(cfg *config.Config, logger *zap.Logger) error {
 
 // ------------------------ Accrual Worker Lifecycle ------------------------
 
-func StartAccrualWorker(lc fx.Lifecycle, worker *service.AccrualWorker, logger *zap.Logger) {
+func StartAccrualWorker(lc fx.Lifecycle, worker *service.AccrualWorker, cfg *config.Config, logger *zap.Logger) {
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			logger.Info("starting accrual worker")
+			logger.Info("starting accrual worker",
+				zap.Int("workers", cfg.AccrualWorkers),
+				zap.Int("batch_size", cfg.AccrualBatchSize),
+				zap.Duration("poll_interval", cfg.AccrualPollInterval),
+			)
 
 			go func() {
-				ticker := time.NewTicker(5 * time.Second)
+				defer close(done)
+				worker.Run(runCtx)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("stopping accrual worker")
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// ------------------------ Outbox Relay ------------------------
+
+// startOutboxRelay запускает relay всегда: кроме приёмников из
+// OUTBOX_SINKS, события нужны очереди вебхуков. Постановка в неё
+// идемпотентна, поэтому повтор из-за сбоя другого приёмника безопасен.
+func startOutboxRelay(lc fx.Lifecycle, store *storage.Storage, cfg *config.Config, logger *zap.Logger) error {
+	sinks, err := outbox.ParseSinks(cfg.OutboxSinks, cfg.OutboxWebhookTimeout)
+	if err != nil {
+		return err
+	}
+	sinks = append(sinks, webhook.NewSink(store.Webhooks, logger))
+
+	relay := outbox.NewRelay(store.Outbox, sinks, cfg, logger)
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("starting outbox relay",
+				zap.String("sinks", cfg.OutboxSinks),
+				zap.Int("batch_size", cfg.OutboxBatchSize),
+				zap.Duration("poll_interval", cfg.OutboxPollInterval),
+			)
+
+			go func() {
+				defer close(done)
+				relay.Run(runCtx)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("stopping outbox relay")
+			cancel()
+			select {
+			case <-done:
+				return sinks.Close()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+	return nil
+}
+
+// ------------------------ Webhooks ------------------------
+
+func startWebhookWorker(lc fx.Lifecycle, store *storage.Storage, cfg *config.Config, logger *zap.Logger) {
+	if cfg.WebhookAllowPrivateNetworks {
+		logger.Warn("⚠️ DEV ONLY: WEBHOOK_ALLOW_PRIVATE_NETWORKS is set, " +
+			"webhooks may be delivered to loopback and private networks")
+	}
+	worker := webhook.NewWorker(store.Webhooks, cfg, logger)
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("starting webhook worker",
+				zap.Int("batch_size", cfg.WebhookBatchSize),
+				zap.Duration("poll_interval", cfg.WebhookPollInterval),
+				zap.Int("max_attempts", cfg.WebhookMaxAttempts),
+			)
+
+			go func() {
+				defer close(done)
+				worker.Run(runCtx)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("stopping webhook worker")
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// ------------------------ Idempotency Keys Cleanup ------------------------
+
+func startIdempotencyCleanup(lc fx.Lifecycle, repo storage.IdempotencyRepository, logger *zap.Logger) {
+	runCtx, cancel := context.WithCancel(context.Background())
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				ticker := time.NewTicker(time.Hour)
 				defer ticker.Stop()
 
 				for {
 					select {
-					case <-ctx.Done():
-						logger.Info("accrual worker stopped", zap.Error(ctx.Err()))
+					case <-runCtx.Done():
 						return
 					case <-ticker.C:
-						worker.Process(ctx)
+						if n, err := repo.DeleteExpired(runCtx, logger); err == nil && n > 0 {
+							logger.Info("expired idempotency keys deleted", zap.Int64("count", n))
+						}
 					}
 				}
 			}()
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
-			logger.Info("stopping accrual worker")
+			cancel()
 			return nil
 		},
 	})
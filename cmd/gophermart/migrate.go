@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/joho/godotenv"
+)
+
+const migrateUsage = `usage: gophermart migrate [-d DSN] <command>
+
+commands:
+  up [N]      apply all pending migrations or the next N
+  down [N]    roll back N migrations (default 1)
+  to V        migrate up or down to version V
+  status      print the current version
+  force V     set the version without running migrations (fixes a dirty state)
+
+DSN defaults to DATABASE_URI. Only PostgreSQL is supported, as a
+postgres:// URL or a key/value string (host=... dbname=...).
+`
+
+// runMigrateCommand выполняет подкоманду migrate и возвращает код выхода.
+func runMigrateCommand(args []string, stdout, stderr io.Writer) int {
+	_ = godotenv.Load()
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, migrateUsage) }
+	dsn := fs.String("d", os.Getenv("DATABASE_URI"), "PostgreSQL DSN")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 || *dsn == "" {
+		fs.Usage()
+		return 2
+	}
+
+	m, err := postgres.NewMigrator(*dsn)
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot open migrations: %v\n", err)
+		return 1
+	}
+	defer m.Close()
+
+	if err := execMigrate(m, fs.Args(), stdout); err != nil {
+		fmt.Fprintf(stderr, "migrate %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+func execMigrate(m *migrate.Migrate, args []string, stdout io.Writer) error {
+	cmd, rest := args[0], args[1:]
+
+	var err error
+	switch cmd {
+	case "up":
+		if len(rest) == 0 {
+			err = m.Up()
+			break
+		}
+		n, convErr := parsePositive(rest[0])
+		if convErr != nil {
+			return convErr
+		}
+		err = m.Steps(n)
+	case "down":
+		n := 1
+		if len(rest) > 0 {
+			var convErr error
+			if n, convErr = parsePositive(rest[0]); convErr != nil {
+				return convErr
+			}
+		}
+		err = m.Steps(-n)
+	case "to":
+		if len(rest) == 0 {
+			return errors.New("version required")
+		}
+		v, convErr := parsePositive(rest[0])
+		if convErr != nil {
+			return convErr
+		}
+		err = m.Migrate(uint(v))
+	case "force":
+		if len(rest) == 0 {
+			return errors.New("version required")
+		}
+		v, convErr := strconv.Atoi(rest[0])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		err = m.Force(v)
+	case "status":
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Fprintln(stdout, "no change")
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return printMigrateStatus(m, stdout)
+}
+
+func printMigrateStatus(m *migrate.Migrate, stdout io.Writer) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(stdout, "version: none")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "version: %d (dirty: %t)\n", version, dirty)
+	return nil
+}
+
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a positive number, got %q", s)
+	}
+	return n, nil
+}
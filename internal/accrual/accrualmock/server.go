@@ -0,0 +1,267 @@
+// Package accrualmock имитирует систему расчёта начислений: отвечает на
+// GET /api/orders/{number} по заданным сценариям, с задержками, 204, 500
+// и 429 при превышении лимита запросов. Server — обычный http.Handler,
+// поэтому его можно поднять через httptest.NewServer прямо в тесте.
+package accrualmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/accrual"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+)
+
+// Step — один ответ сценария. Code 0 означает 200 с телом Status/Accrual,
+// иначе отдаётся пустой ответ с этим кодом (204, 500, 429).
+type Step struct {
+	Code    int
+	Status  string
+	Accrual *decimal.Decimal
+}
+
+// Config задаёт поведение имитации.
+type Config struct {
+	// RPM — лимит запросов в минуту, 0 — без лимита.
+	RPM int
+	// RetryAfter отдаётся в ответах 429. По умолчанию — время до конца
+	// текущего минутного окна.
+	RetryAfter time.Duration
+
+	// MinDelay и MaxDelay — границы случайной задержки ответа.
+	MinDelay, MaxDelay time.Duration
+
+	// NoContentRate и ErrorRate — доли случайных ответов 204 и 500.
+	NoContentRate float64
+	ErrorRate     float64
+
+	// Seed фиксирует случайные решения; 0 — случайное зерно.
+	Seed uint64
+
+	// Default — сценарий для заказов без собственного. Если он пуст,
+	// такие заказы считаются незарегистрированными (204).
+	Default []Step
+}
+
+// Server отвечает на запросы по сценариям. Каждый запрос по заказу
+// продвигает его сценарий на шаг, последний шаг повторяется.
+type Server struct {
+	cfg    Config
+	router chi.Router
+	now    func() time.Time
+
+	mu       sync.Mutex
+	rnd      *rand.Rand
+	scripts  map[string][]Step
+	calls    map[string]int
+	window   time.Time
+	inWindow int
+}
+
+func New(cfg Config) *Server {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	s := &Server{
+		cfg:     cfg,
+		now:     time.Now,
+		rnd:     rand.New(rand.NewPCG(seed, seed)),
+		scripts: map[string][]Step{},
+		calls:   map[string]int{},
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/orders/{number}", s.getOrder)
+	s.router = r
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Script задаёт сценарий ответов по заказу и сбрасывает его прогресс.
+func (s *Server) Script(number string, steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[number] = steps
+	delete(s.calls, number)
+}
+
+// LoadScripts читает сценарии из JSON вида {"<номер>": "<шаги>"},
+// где шаги записаны в формате ParseSteps.
+func (s *Server) LoadScripts(r io.Reader) error {
+	var raw map[string]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return fmt.Errorf("decode scripts: %w", err)
+	}
+	for number, spec := range raw {
+		steps, err := ParseSteps(spec)
+		if err != nil {
+			return fmt.Errorf("order %s: %w", number, err)
+		}
+		s.Script(number, steps...)
+	}
+	return nil
+}
+
+// Calls возвращает, сколько шагов сценария уже отдано по заказу. Ответы 429
+// по лимиту и случайные 204/500 сценарий не продвигают.
+func (s *Server) Calls(number string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[number]
+}
+
+// ParseSteps разбирает сценарий вида "REGISTERED,PROCESSING,PROCESSED:500,204":
+// статус с необязательным начислением после двоеточия или HTTP-код.
+func ParseSteps(spec string) ([]Step, error) {
+	var steps []Step
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if code, err := strconv.Atoi(item); err == nil {
+			if code < 100 || code > 599 {
+				return nil, fmt.Errorf("invalid status code %d", code)
+			}
+			steps = append(steps, Step{Code: code})
+			continue
+		}
+
+		status, points, hasAccrual := strings.Cut(item, ":")
+		switch status {
+		case accrual.StatusRegistered, accrual.StatusProcessing, accrual.StatusInvalid, accrual.StatusProcessed:
+		default:
+			return nil, fmt.Errorf("unknown accrual status %q", status)
+		}
+		step := Step{Status: status}
+		if hasAccrual {
+			dec, err := decimal.NewFromString(points)
+			if err != nil {
+				return nil, fmt.Errorf("invalid accrual %q: %w", points, err)
+			}
+			step.Accrual = &dec
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty script")
+	}
+	return steps, nil
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
+	number := chi.URLParam(r, "number")
+
+	if retryAfter, limited := s.allow(); limited {
+		s.tooManyRequests(w, retryAfter)
+		return
+	}
+
+	delay, step := s.next(number)
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch step.Code {
+	case 0, http.StatusOK:
+	case http.StatusTooManyRequests:
+		s.tooManyRequests(w, s.cfg.RetryAfter)
+		return
+	default:
+		w.WriteHeader(step.Code)
+		return
+	}
+
+	resp := struct {
+		Order   string           `json:"order"`
+		Status  string           `json:"status"`
+		Accrual *decimal.Decimal `json:"accrual,omitempty"`
+	}{Order: number, Status: step.Status, Accrual: step.Accrual}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// allow учитывает запрос в минутном окне и сообщает, превышен ли лимит.
+func (s *Server) allow() (time.Duration, bool) {
+	if s.cfg.RPM <= 0 {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.window) >= time.Minute {
+		s.window, s.inWindow = now, 0
+	}
+	if s.inWindow >= s.cfg.RPM {
+		if s.cfg.RetryAfter > 0 {
+			return s.cfg.RetryAfter, true
+		}
+		return s.window.Add(time.Minute).Sub(now), true
+	}
+	s.inWindow++
+	return 0, false
+}
+
+// next выбирает задержку и ответ для очередного запроса по заказу.
+func (s *Server) next(number string) (time.Duration, Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var delay time.Duration
+	if spread := s.cfg.MaxDelay - s.cfg.MinDelay; spread > 0 {
+		delay = s.cfg.MinDelay + time.Duration(s.rnd.Int64N(int64(spread)))
+	} else {
+		delay = s.cfg.MinDelay
+	}
+
+	// случайные сбои не продвигают сценарий
+	if s.cfg.ErrorRate > 0 && s.rnd.Float64() < s.cfg.ErrorRate {
+		return delay, Step{Code: http.StatusInternalServerError}
+	}
+	if s.cfg.NoContentRate > 0 && s.rnd.Float64() < s.cfg.NoContentRate {
+		return delay, Step{Code: http.StatusNoContent}
+	}
+
+	steps, ok := s.scripts[number]
+	if !ok {
+		steps = s.cfg.Default
+	}
+	if len(steps) == 0 {
+		return delay, Step{Code: http.StatusNoContent}
+	}
+
+	i := min(s.calls[number], len(steps)-1)
+	s.calls[number]++
+	return delay, steps[i]
+}
+
+func (s *Server) tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = time.Minute
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusTooManyRequests)
+	fmt.Fprintf(w, "No more than %d requests per minute allowed", s.cfg.RPM)
+}
@@ -0,0 +1,103 @@
+package accrualmock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/accrual"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{name: "#1 статусы, начисление и коды", spec: "REGISTERED, PROCESSING,PROCESSED:729.98,204,500", want: 5},
+		{name: "#2 неизвестный статус", spec: "DONE", wantErr: true},
+		{name: "#3 битое начисление", spec: "PROCESSED:abc", wantErr: true},
+		{name: "#4 пустой сценарий", spec: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := ParseSteps(tt.spec)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, steps, tt.want)
+		})
+	}
+}
+
+func TestServer_ScriptedProgression(t *testing.T) {
+	mock := New(Config{})
+	require.NoError(t, mock.LoadScripts(strings.NewReader(`{"12345678903": "500,REGISTERED,PROCESSING,PROCESSED:500"}`)))
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	client := accrual.NewClient(srv.URL)
+	ctx := context.Background()
+
+	_, err := client.GetOrder(ctx, "12345678903")
+	require.Error(t, err)
+
+	for _, want := range []string{accrual.StatusRegistered, accrual.StatusProcessing, accrual.StatusProcessed, accrual.StatusProcessed} {
+		resp, err := client.GetOrder(ctx, "12345678903")
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Status)
+	}
+	resp, _ := client.GetOrder(ctx, "12345678903")
+	assert.Equal(t, "500", resp.Accrual.String())
+	assert.Equal(t, 6, mock.Calls("12345678903"))
+
+	_, err = client.GetOrder(ctx, "79927398713")
+	assert.ErrorIs(t, err, accrual.ErrOrderNotFound, "заказ без сценария не зарегистрирован")
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock := New(Config{RPM: 2, Default: []Step{{Status: accrual.StatusProcessing}}})
+	mock.now = func() time.Time { return now }
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	client := accrual.NewClient(srv.URL)
+	for i := 0; i < 2; i++ {
+		_, err := client.GetOrder(context.Background(), "1")
+		require.NoError(t, err)
+	}
+
+	now = now.Add(20 * time.Second)
+	_, err := client.GetOrder(context.Background(), "1")
+	var rlErr *accrual.RateLimitError
+	require.ErrorAs(t, err, &rlErr)
+	assert.Equal(t, 40*time.Second, rlErr.RetryAfter)
+	assert.Equal(t, 2, rlErr.RPM)
+	assert.Equal(t, 2, mock.Calls("1"), "отклонённый запрос не продвигает сценарий")
+
+	// новое окно
+	now = now.Add(40 * time.Second)
+	_, err = client.GetOrder(context.Background(), "1")
+	assert.NoError(t, err)
+}
+
+func TestServer_RandomFailures(t *testing.T) {
+	mock := New(Config{ErrorRate: 1, Default: []Step{{Status: accrual.StatusProcessed}}})
+
+	rec := httptest.NewRecorder()
+	mock.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/orders/1", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Zero(t, mock.Calls("1"))
+}
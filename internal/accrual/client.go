@@ -5,10 +5,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"strconv"
 	"time"
 
+	"go-musthave-diploma-tpl/internal/metrics"
+
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -23,10 +34,35 @@ var (
 	ErrOrderNotFound   = errors.New("order not found")
 )
 
+// defaultRetryAfter используется, если система расчёта не прислала Retry-After.
+const defaultRetryAfter = 60 * time.Second
+
+var tracer = otel.Tracer("go-musthave-diploma-tpl/internal/accrual")
+
+var rpmPattern = regexp.MustCompile(`No more than (\d+) requests per minute allowed`)
+
+// RateLimitError описывает ответ 429: сколько ждать и сколько запросов в минуту разрешено.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	RPM        int
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many requests: retry after %s, limit %d rpm", e.RetryAfter, e.RPM)
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrTooManyRequests).
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
+
 type OrderResponse struct {
 	Order   string          `json:"order"`
 	Status  string          `json:"status"`
 	Accrual decimal.Decimal `json:"accrual,omitempty"`
+
+	// Raw — тело ответа как есть, для истории статусов заказа.
+	Raw json.RawMessage `json:"-"`
 }
 
 type Client struct {
@@ -44,6 +80,42 @@ func NewClient(baseURL string) *Client {
 }
 
 func (c *Client) GetOrder(ctx context.Context, number string) (*OrderResponse, error) {
+	ctx, span := tracer.Start(ctx, "accrual.GetOrder",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("order.number", number)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	res, err := c.getOrder(ctx, number)
+	observe(time.Since(start), err)
+
+	// 204 и 429 — штатные ответы, ошибкой спана считаем только сбои
+	if err != nil && !errors.Is(err, ErrOrderNotFound) && !errors.Is(err, ErrTooManyRequests) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
+
+// observe учитывает запрос в метриках: задержку по результату и счётчики ошибок и 429.
+func observe(d time.Duration, err error) {
+	result := "ok"
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrOrderNotFound):
+		result = "not_found"
+	case errors.Is(err, ErrTooManyRequests):
+		result = "rate_limited"
+		metrics.AccrualRateLimited.Inc()
+	default:
+		result = "error"
+		metrics.AccrualErrors.Inc()
+	}
+	metrics.AccrualRequestDuration.WithLabelValues(result).Observe(d.Seconds())
+}
+
+func (c *Client) getOrder(ctx context.Context, number string) (*OrderResponse, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
@@ -53,28 +125,70 @@ func (c *Client) GetOrder(ctx context.Context, number string) (*OrderResponse, e
 	if err != nil {
 		return nil, err
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 
 	switch resp.StatusCode {
 	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
 		var res OrderResponse
-		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		if err := json.Unmarshal(body, &res); err != nil {
 			return nil, err
 		}
+		res.Raw = body
 		return &res, nil
 
 	case http.StatusNoContent:
 		return nil, ErrOrderNotFound
 
 	case http.StatusTooManyRequests:
-		return nil, ErrTooManyRequests
+		return nil, parseRateLimit(resp)
 
 	default:
 		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
 }
+
+func parseRateLimit(resp *http.Response) *RateLimitError {
+	rlErr := &RateLimitError{
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if m := rpmPattern.FindSubmatch(body); m != nil {
+		if rpm, err := strconv.Atoi(string(m[1])); err == nil && rpm > 0 {
+			rlErr.RPM = rpm
+		}
+	}
+
+	return rlErr
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в формате HTTP-date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return defaultRetryAfter
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return defaultRetryAfter
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
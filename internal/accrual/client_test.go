@@ -0,0 +1,141 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/tracing/tracingtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestClient_GetOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantErr     error
+		wantStatus  string
+		wantRetry   time.Duration
+		wantRPM     int
+		isRateLimit bool
+	}{
+		{
+			name: "#1 processed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":500}`))
+			},
+			wantStatus: StatusProcessed,
+		},
+		{
+			name: "#2 not registered",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantErr: ErrOrderNotFound,
+		},
+		{
+			name: "#3 rate limited with header and body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte("No more than 120 requests per minute allowed"))
+			},
+			wantErr:     ErrTooManyRequests,
+			wantRetry:   60 * time.Second,
+			wantRPM:     120,
+			isRateLimit: true,
+		},
+		{
+			name: "#4 rate limited without hints",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantErr:     ErrTooManyRequests,
+			wantRetry:   defaultRetryAfter,
+			isRateLimit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			resp, err := NewClient(srv.URL).GetOrder(context.Background(), "12345678903")
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tt.wantErr))
+				assert.Nil(t, resp)
+
+				var rlErr *RateLimitError
+				assert.Equal(t, tt.isRateLimit, errors.As(err, &rlErr))
+				if tt.isRateLimit {
+					assert.Equal(t, tt.wantRetry, rlErr.RetryAfter)
+					assert.Equal(t, tt.wantRPM, rlErr.RPM)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.NotEmpty(t, resp.Raw, "сырой ответ сохраняется для истории статусов")
+		})
+	}
+}
+
+func TestClient_GetOrder_PropagatesTrace(t *testing.T) {
+	exporter := tracingtest.Install(t)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"order":"12345678903","status":"PROCESSED","accrual":500}`))
+	}))
+	defer srv.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, err := NewClient(srv.URL).GetOrder(ctx, "12345678903")
+	parent.End()
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	client := spans[0]
+	assert.Equal(t, "accrual.GetOrder", client.Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), client.Parent.SpanID())
+
+	// система расчёта получает traceparent со спаном клиента
+	want := fmt.Sprintf("00-%s-%s-01", client.SpanContext.TraceID(), client.SpanContext.SpanID())
+	assert.Equal(t, want, traceparent)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"#1 seconds", "15", 15 * time.Second},
+		{"#2 http date", now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{"#3 date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"#4 empty", "", defaultRetryAfter},
+		{"#5 garbage", "soon", defaultRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRetryAfter(tt.value, now))
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Package apperr описывает доменные ошибки со стабильным машиночитаемым кодом.
+// Код — часть публичного API: фронтенд ветвится по нему, поэтому коды не переименовываются.
+package apperr
+
+import "errors"
+
+type Code string
+
+const (
+	CodeInternal       Code = "internal_error"
+	CodeInvalidRequest Code = "invalid_request"
+	CodeInvalidQuery   Code = "invalid_query_parameter"
+	CodeInvalidCursor  Code = "invalid_cursor"
+	CodeBodyTooLarge   Code = "request_body_too_large"
+
+	CodeCredentialsRequired   Code = "credentials_required"
+	CodePasswordTooShort      Code = "password_too_short"
+	CodePasswordEqualsLogin   Code = "password_equals_login"
+	CodeLoginTaken            Code = "login_taken"
+	CodeInvalidCredentials    Code = "invalid_credentials"
+	CodeUnauthorized          Code = "unauthorized"
+	CodeInvalidToken          Code = "invalid_token"
+	CodeSessionRevoked        Code = "session_revoked"
+	CodeInvalidRefreshToken   Code = "invalid_refresh_token"
+	CodeOrderNumberRequired   Code = "order_number_required"
+	CodeInvalidOrderNumber    Code = "invalid_order_number"
+	CodeOrderOwnedByOtherUser Code = "order_owned_by_other_user"
+	CodeOrderAlreadyUploaded  Code = "order_already_uploaded"
+	CodeOrderBatchTooLarge    Code = "order_batch_too_large"
+	CodeOrderNotFound         Code = "order_not_found"
+	CodeNotEnoughFunds        Code = "not_enough_funds"
+	CodeInvalidWithdrawalSum  Code = "invalid_withdrawal_sum"
+
+	CodeInvalidWebhookURL       Code = "invalid_webhook_url"
+	CodeWebhookURLNotAllowed    Code = "webhook_url_not_allowed"
+	CodeInvalidWebhookEventType Code = "invalid_webhook_event_type"
+	CodeInvalidWebhookSecret    Code = "invalid_webhook_secret"
+	CodeWebhookNotFound         Code = "webhook_not_found"
+	CodeWebhookDeliveryNotFound Code = "webhook_delivery_not_found"
+
+	CodeIdempotencyKeyTooLong    Code = "idempotency_key_too_long"
+	CodeIdempotencyKeyMismatch   Code = "idempotency_key_mismatch"
+	CodeIdempotencyKeyInProgress Code = "idempotency_key_in_progress"
+)
+
+// Error — доменная ошибка с кодом. Сообщение предназначено для логов и поля detail,
+// а не для показа пользователю: заголовок на нужном языке выбирается по коду.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeOf возвращает код первой доменной ошибки в цепочке или CodeInternal.
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
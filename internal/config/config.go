@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,8 +14,57 @@ import (
 type Config struct {
 	RunAddress           string `env:"RUN_ADDRESS"`
 	DatabaseURI          string `env:"DATABASE_URI"`
+	MigrateOnStart       bool   `env:"MIGRATE_ON_START"`
 	AccrualSystemAddress string `env:"ACCRUAL_SYSTEM_ADDRESS"`
-	AuthSecret           string `env:"AUTH_SECRET"`
+	AdminAddress         string `env:"ADMIN_ADDRESS"`
+	PartnerAPIKey        string `env:"PARTNER_API_KEY"`
+	TracingExporter      string `env:"TRACING_EXPORTER"`
+
+	DBMaxConns          int           `env:"DB_MAX_CONNS"`
+	DBMaxConnLifetime   time.Duration `env:"DB_MAX_CONN_LIFETIME"`
+	DBMaxConnIdleTime   time.Duration `env:"DB_MAX_CONN_IDLE_TIME"`
+	DBHealthCheckPeriod time.Duration `env:"DB_HEALTH_CHECK_PERIOD"`
+
+	JWTKeysDir   string `env:"JWT_KEYS_DIR"`
+	JWTActiveKID string `env:"JWT_ACTIVE_KID"`
+	// JWTEphemeralKeys разрешает запуск без JWTKeysDir со случайным ключом;
+	// только для локальной разработки
+	JWTEphemeralKeys bool `env:"JWT_EPHEMERAL_KEYS"`
+
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL"`
+
+	AccrualWorkers        int           `env:"ACCRUAL_WORKERS"`
+	AccrualBatchSize      int           `env:"ACCRUAL_BATCH_SIZE"`
+	AccrualPollInterval   time.Duration `env:"ACCRUAL_POLL_INTERVAL"`
+	AccrualRequestTimeout time.Duration `env:"ACCRUAL_REQUEST_TIMEOUT"`
+	AccrualWorkerID       string        `env:"ACCRUAL_WORKER_ID"`
+	AccrualLease          time.Duration `env:"ACCRUAL_LEASE"`
+	AccrualBackoffBase    time.Duration `env:"ACCRUAL_BACKOFF_BASE"`
+	AccrualBackoffMax     time.Duration `env:"ACCRUAL_BACKOFF_MAX"`
+	AccrualMaxOrderAge    time.Duration `env:"ACCRUAL_MAX_ORDER_AGE"`
+
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL"`
+
+	OutboxSinks          string        `env:"OUTBOX_SINKS"`
+	OutboxBatchSize      int           `env:"OUTBOX_BATCH_SIZE"`
+	OutboxPollInterval   time.Duration `env:"OUTBOX_POLL_INTERVAL"`
+	OutboxLease          time.Duration `env:"OUTBOX_LEASE"`
+	OutboxBackoffBase    time.Duration `env:"OUTBOX_BACKOFF_BASE"`
+	OutboxBackoffMax     time.Duration `env:"OUTBOX_BACKOFF_MAX"`
+	OutboxWebhookTimeout time.Duration `env:"OUTBOX_WEBHOOK_TIMEOUT"`
+	WebhookBatchSize     int           `env:"WEBHOOK_BATCH_SIZE"`
+	WebhookPollInterval  time.Duration `env:"WEBHOOK_POLL_INTERVAL"`
+	WebhookTimeout       time.Duration `env:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts   int           `env:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookBackoffBase   time.Duration `env:"WEBHOOK_BACKOFF_BASE"`
+	WebhookBackoffMax    time.Duration `env:"WEBHOOK_BACKOFF_MAX"`
+	// WebhookAllowPrivateNetworks разрешает доставку вебхуков в loopback и
+	// частные сети; только для локальной разработки
+	WebhookAllowPrivateNetworks bool `env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+
+	ReadinessAccrualMaxAge time.Duration `env:"READINESS_ACCRUAL_MAX_AGE"`
+	ShutdownDrainDelay     time.Duration `env:"SHUTDOWN_DRAIN_DELAY"`
 }
 
 func (c *Config) String() string {
@@ -33,8 +84,47 @@ func InitConfig() *Config {
 	var cfg Config
 
 	flag.StringVar(&cfg.RunAddress, "a", "", "Server address (e.g. :8080)")
-	flag.StringVar(&cfg.DatabaseURI, "d", "", "PostgreSQL DSN")
+	flag.StringVar(&cfg.DatabaseURI, "d", "", "PostgreSQL DSN, sqlite://<path> for SQLite or memory:// to keep data in memory (also used when empty)")
 	flag.StringVar(&cfg.AccrualSystemAddress, "r", "", "Accrual system base URL")
+	flag.IntVar(&cfg.DBMaxConns, "db-max-conns", 10, "Maximum size of the PostgreSQL connection pool")
+	flag.DurationVar(&cfg.DBMaxConnLifetime, "db-max-conn-lifetime", time.Hour, "PostgreSQL connections older than this are closed")
+	flag.DurationVar(&cfg.DBMaxConnIdleTime, "db-max-conn-idle-time", 30*time.Minute, "Idle PostgreSQL connections are closed after this long")
+	flag.DurationVar(&cfg.DBHealthCheckPeriod, "db-health-check-period", time.Minute, "How often idle PostgreSQL connections are checked")
+	flag.StringVar(&cfg.TracingExporter, "tracing-exporter", "none", "Trace exporter: otlp, stdout or none")
+	flag.StringVar(&cfg.AdminAddress, "admin-address", "localhost:9090", "Admin server address with /metrics (empty disables it)")
+	flag.StringVar(&cfg.PartnerAPIKey, "partner-api-key", "", "Bearer key for partner webhooks on the admin server (empty disables /admin/webhooks)")
+	flag.IntVar(&cfg.AccrualWorkers, "accrual-workers", 4, "Number of concurrent accrual pollers")
+	flag.IntVar(&cfg.AccrualBatchSize, "accrual-batch-size", 50, "Orders fetched per accrual polling cycle")
+	flag.DurationVar(&cfg.AccrualPollInterval, "accrual-poll-interval", 5*time.Second, "Accrual polling interval")
+	flag.DurationVar(&cfg.AccrualRequestTimeout, "accrual-request-timeout", 5*time.Second, "Timeout of a single accrual request")
+	flag.StringVar(&cfg.AccrualWorkerID, "accrual-worker-id", defaultWorkerID(), "Identifier of this replica in order leases")
+	flag.DurationVar(&cfg.AccrualLease, "accrual-lease", time.Minute, "How long a claimed order stays locked by this replica")
+	flag.DurationVar(&cfg.AccrualBackoffBase, "accrual-backoff-base", 5*time.Second, "Initial delay before re-polling an unfinished order")
+	flag.DurationVar(&cfg.AccrualBackoffMax, "accrual-backoff-max", 10*time.Minute, "Maximum delay between polls of one order")
+	flag.DurationVar(&cfg.AccrualMaxOrderAge, "accrual-max-order-age", 72*time.Hour, "Order age after which polling gives up and marks it INVALID")
+	flag.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", 24*time.Hour, "How long Idempotency-Key responses are kept")
+	flag.StringVar(&cfg.OutboxSinks, "outbox-sinks", "", "Comma-separated extra outbox sinks: stdout, file:<path>, http(s)://<webhook>; webhook subscriptions are always served")
+	flag.IntVar(&cfg.OutboxBatchSize, "outbox-batch-size", 100, "Outbox events fetched per relay cycle")
+	flag.DurationVar(&cfg.OutboxPollInterval, "outbox-poll-interval", time.Second, "Outbox polling interval")
+	flag.DurationVar(&cfg.OutboxLease, "outbox-lease", 30*time.Second, "How long claimed outbox events stay locked by this replica")
+	flag.DurationVar(&cfg.OutboxBackoffBase, "outbox-backoff-base", time.Second, "Initial delay before redelivering a failed outbox event")
+	flag.DurationVar(&cfg.OutboxBackoffMax, "outbox-backoff-max", 5*time.Minute, "Maximum delay between deliveries of one outbox event")
+	flag.DurationVar(&cfg.OutboxWebhookTimeout, "outbox-webhook-timeout", 10*time.Second, "Timeout of a single outbox webhook request")
+	flag.IntVar(&cfg.WebhookBatchSize, "webhook-batch-size", 20, "Webhook deliveries sent concurrently per cycle")
+	flag.DurationVar(&cfg.WebhookPollInterval, "webhook-poll-interval", time.Second, "Webhook delivery polling interval")
+	flag.DurationVar(&cfg.WebhookTimeout, "webhook-timeout", 10*time.Second, "Timeout of a single webhook delivery request")
+	flag.IntVar(&cfg.WebhookMaxAttempts, "webhook-max-attempts", 10, "Delivery attempts before a webhook delivery is marked failed")
+	flag.DurationVar(&cfg.WebhookBackoffBase, "webhook-backoff-base", 10*time.Second, "Initial delay before retrying a webhook delivery")
+	flag.DurationVar(&cfg.WebhookBackoffMax, "webhook-backoff-max", time.Hour, "Maximum delay between attempts of one webhook delivery")
+	flag.BoolVar(&cfg.WebhookAllowPrivateNetworks, "webhook-allow-private-networks", false, "DEV ONLY: allow webhook URLs in loopback and private networks")
+	flag.DurationVar(&cfg.AccessTokenTTL, "access-token-ttl", 15*time.Minute, "Lifetime of JWT access tokens")
+	flag.DurationVar(&cfg.RefreshTokenTTL, "refresh-token-ttl", 30*24*time.Hour, "Lifetime of refresh tokens")
+	flag.StringVar(&cfg.JWTKeysDir, "jwt-keys-dir", "", "Directory with PEM signing keys (RSA or Ed25519)")
+	flag.StringVar(&cfg.JWTActiveKID, "jwt-active-kid", "", "Key id used to sign new tokens")
+	flag.BoolVar(&cfg.JWTEphemeralKeys, "jwt-ephemeral-keys", false, "DEV ONLY: sign tokens with a random key when -jwt-keys-dir is not set")
+	flag.DurationVar(&cfg.ReadinessAccrualMaxAge, "readiness-accrual-max-age", 10*time.Minute, "Service is not ready if the accrual worker has not completed a cycle for this long")
+	flag.DurationVar(&cfg.ShutdownDrainDelay, "shutdown-drain-delay", 5*time.Second, "How long /readyz reports not ready before the HTTP server stops")
+	flag.BoolVar(&cfg.MigrateOnStart, "migrate-on-start", true, "Apply pending migrations on startup")
 	flag.Parse()
 
 	if envRunAddress := os.Getenv("RUN_ADDRESS"); envRunAddress != "" {
@@ -43,10 +133,9 @@ func InitConfig() *Config {
 		cfg.RunAddress = "localhost:8080"
 	}
 
+	// пустой DATABASE_URI означает хранилище в памяти, newStorage предупреждает об этом в логе
 	if envDatabaseURI := os.Getenv("DATABASE_URI"); envDatabaseURI != "" {
 		cfg.DatabaseURI = envDatabaseURI
-	} else {
-		panic("❌ CONFIG ERROR: DATABASE_URI is required (set via -d flag or DATABASE_URI env/.env)")
 	}
 
 	if envAccrualSystemAddress := os.Getenv("ACCRUAL_SYSTEM_ADDRESS"); envAccrualSystemAddress != "" {
@@ -55,11 +144,193 @@ func InitConfig() *Config {
 		panic("❌ CONFIG ERROR: ACCRUAL_SYSTEM_ADDRESS is required (set via -r flag or ACCRUAL_SYSTEM_ADDRESS env/.env)")
 	}
 
-	if envAuthSecret := os.Getenv("AUTH_SECRET"); envAuthSecret != "" {
-		cfg.AuthSecret = envAuthSecret
-	} else {
-		cfg.AuthSecret = "very-hard-secrets"
+	if envAdminAddress, ok := os.LookupEnv("ADMIN_ADDRESS"); ok {
+		cfg.AdminAddress = envAdminAddress
+	}
+
+	if envPartnerAPIKey := os.Getenv("PARTNER_API_KEY"); envPartnerAPIKey != "" {
+		cfg.PartnerAPIKey = envPartnerAPIKey
+	}
+
+	if envTracingExporter := os.Getenv("TRACING_EXPORTER"); envTracingExporter != "" {
+		cfg.TracingExporter = envTracingExporter
 	}
 
+	if envJWTKeysDir := os.Getenv("JWT_KEYS_DIR"); envJWTKeysDir != "" {
+		cfg.JWTKeysDir = envJWTKeysDir
+	}
+	if envJWTActiveKID := os.Getenv("JWT_ACTIVE_KID"); envJWTActiveKID != "" {
+		cfg.JWTActiveKID = envJWTActiveKID
+	}
+
+	if envJWTEphemeralKeys := os.Getenv("JWT_EPHEMERAL_KEYS"); envJWTEphemeralKeys != "" {
+		v, err := strconv.ParseBool(envJWTEphemeralKeys)
+		if err != nil {
+			panic(fmt.Sprintf("❌ CONFIG ERROR: JWT_EPHEMERAL_KEYS must be a boolean, got %q", envJWTEphemeralKeys))
+		}
+		cfg.JWTEphemeralKeys = v
+	}
+
+	// AUTH_SECRET (HS256) заменён ключами из JWT_KEYS_DIR: без подсказки сервер
+	// со старой конфигурацией упал бы с непонятной ошибкой про ключи
+	if os.Getenv("AUTH_SECRET") != "" && cfg.JWTKeysDir == "" && !cfg.JWTEphemeralKeys {
+		panic("❌ CONFIG ERROR: AUTH_SECRET is no longer supported: put PEM signing keys into JWT_KEYS_DIR " +
+			"(or set JWT_EPHEMERAL_KEYS=true for development) and unset AUTH_SECRET")
+	}
+
+	if envWebhookAllowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"); envWebhookAllowPrivate != "" {
+		v, err := strconv.ParseBool(envWebhookAllowPrivate)
+		if err != nil {
+			panic(fmt.Sprintf("❌ CONFIG ERROR: WEBHOOK_ALLOW_PRIVATE_NETWORKS must be a boolean, got %q", envWebhookAllowPrivate))
+		}
+		cfg.WebhookAllowPrivateNetworks = v
+	}
+
+	if envMigrateOnStart := os.Getenv("MIGRATE_ON_START"); envMigrateOnStart != "" {
+		v, err := strconv.ParseBool(envMigrateOnStart)
+		if err != nil {
+			panic(fmt.Sprintf("❌ CONFIG ERROR: MIGRATE_ON_START must be a boolean, got %q", envMigrateOnStart))
+		}
+		cfg.MigrateOnStart = v
+	}
+
+	cfg.DBMaxConns = envInt("DB_MAX_CONNS", cfg.DBMaxConns)
+	cfg.DBMaxConnLifetime = envDuration("DB_MAX_CONN_LIFETIME", cfg.DBMaxConnLifetime)
+	cfg.DBMaxConnIdleTime = envDuration("DB_MAX_CONN_IDLE_TIME", cfg.DBMaxConnIdleTime)
+	cfg.DBHealthCheckPeriod = envDuration("DB_HEALTH_CHECK_PERIOD", cfg.DBHealthCheckPeriod)
+
+	cfg.AccessTokenTTL = envDuration("ACCESS_TOKEN_TTL", cfg.AccessTokenTTL)
+	cfg.RefreshTokenTTL = envDuration("REFRESH_TOKEN_TTL", cfg.RefreshTokenTTL)
+
+	cfg.AccrualWorkers = envInt("ACCRUAL_WORKERS", cfg.AccrualWorkers)
+	cfg.AccrualBatchSize = envInt("ACCRUAL_BATCH_SIZE", cfg.AccrualBatchSize)
+	cfg.AccrualPollInterval = envDuration("ACCRUAL_POLL_INTERVAL", cfg.AccrualPollInterval)
+	cfg.AccrualRequestTimeout = envDuration("ACCRUAL_REQUEST_TIMEOUT", cfg.AccrualRequestTimeout)
+	cfg.AccrualLease = envDuration("ACCRUAL_LEASE", cfg.AccrualLease)
+	cfg.AccrualBackoffBase = envDuration("ACCRUAL_BACKOFF_BASE", cfg.AccrualBackoffBase)
+	cfg.AccrualBackoffMax = envDuration("ACCRUAL_BACKOFF_MAX", cfg.AccrualBackoffMax)
+	cfg.AccrualMaxOrderAge = envDuration("ACCRUAL_MAX_ORDER_AGE", cfg.AccrualMaxOrderAge)
+	cfg.IdempotencyTTL = envDuration("IDEMPOTENCY_TTL", cfg.IdempotencyTTL)
+	cfg.OutboxBatchSize = envInt("OUTBOX_BATCH_SIZE", cfg.OutboxBatchSize)
+	cfg.OutboxPollInterval = envDuration("OUTBOX_POLL_INTERVAL", cfg.OutboxPollInterval)
+	cfg.OutboxLease = envDuration("OUTBOX_LEASE", cfg.OutboxLease)
+	cfg.OutboxBackoffBase = envDuration("OUTBOX_BACKOFF_BASE", cfg.OutboxBackoffBase)
+	cfg.OutboxBackoffMax = envDuration("OUTBOX_BACKOFF_MAX", cfg.OutboxBackoffMax)
+	cfg.OutboxWebhookTimeout = envDuration("OUTBOX_WEBHOOK_TIMEOUT", cfg.OutboxWebhookTimeout)
+	cfg.WebhookBatchSize = envInt("WEBHOOK_BATCH_SIZE", cfg.WebhookBatchSize)
+	cfg.WebhookPollInterval = envDuration("WEBHOOK_POLL_INTERVAL", cfg.WebhookPollInterval)
+	cfg.WebhookTimeout = envDuration("WEBHOOK_TIMEOUT", cfg.WebhookTimeout)
+	cfg.WebhookMaxAttempts = envInt("WEBHOOK_MAX_ATTEMPTS", cfg.WebhookMaxAttempts)
+	cfg.WebhookBackoffBase = envDuration("WEBHOOK_BACKOFF_BASE", cfg.WebhookBackoffBase)
+	cfg.WebhookBackoffMax = envDuration("WEBHOOK_BACKOFF_MAX", cfg.WebhookBackoffMax)
+	if envOutboxSinks, ok := os.LookupEnv("OUTBOX_SINKS"); ok {
+		cfg.OutboxSinks = envOutboxSinks
+	}
+	cfg.ReadinessAccrualMaxAge = envDuration("READINESS_ACCRUAL_MAX_AGE", cfg.ReadinessAccrualMaxAge)
+	cfg.ShutdownDrainDelay = envDuration("SHUTDOWN_DRAIN_DELAY", cfg.ShutdownDrainDelay)
+	if envWorkerID := os.Getenv("ACCRUAL_WORKER_ID"); envWorkerID != "" {
+		cfg.AccrualWorkerID = envWorkerID
+	}
+
+	if err := cfg.validate(); err != nil {
+		panic("❌ CONFIG ERROR: " + err.Error())
+	}
 	return &cfg
 }
+
+// minPartnerAPIKeyLen — минимальная длина ключа партнёра, чтобы его нельзя
+// было подобрать.
+const minPartnerAPIKeyLen = 32
+
+// validate проверяет итоговые значения после флагов и окружения: envInt и
+// envDuration ловят только переменные окружения, а ноль из флага
+// останавливает пул воркеров или роняет time.NewTicker.
+func (c *Config) validate() error {
+	if c.PartnerAPIKey != "" && len(c.PartnerAPIKey) < minPartnerAPIKeyLen {
+		return fmt.Errorf("PARTNER_API_KEY (-partner-api-key) must be at least %d characters", minPartnerAPIKeyLen)
+	}
+
+	ints := []struct {
+		name string
+		v    int
+	}{
+		{"DB_MAX_CONNS (-db-max-conns)", c.DBMaxConns},
+		{"ACCRUAL_WORKERS (-accrual-workers)", c.AccrualWorkers},
+		{"ACCRUAL_BATCH_SIZE (-accrual-batch-size)", c.AccrualBatchSize},
+		{"OUTBOX_BATCH_SIZE (-outbox-batch-size)", c.OutboxBatchSize},
+		{"WEBHOOK_BATCH_SIZE (-webhook-batch-size)", c.WebhookBatchSize},
+		{"WEBHOOK_MAX_ATTEMPTS (-webhook-max-attempts)", c.WebhookMaxAttempts},
+	}
+	for _, f := range ints {
+		if f.v <= 0 {
+			return fmt.Errorf("%s must be a positive integer, got %d", f.name, f.v)
+		}
+	}
+
+	durations := []struct {
+		name string
+		v    time.Duration
+	}{
+		{"DB_MAX_CONN_LIFETIME (-db-max-conn-lifetime)", c.DBMaxConnLifetime},
+		{"DB_MAX_CONN_IDLE_TIME (-db-max-conn-idle-time)", c.DBMaxConnIdleTime},
+		{"DB_HEALTH_CHECK_PERIOD (-db-health-check-period)", c.DBHealthCheckPeriod},
+		{"ACCESS_TOKEN_TTL (-access-token-ttl)", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL (-refresh-token-ttl)", c.RefreshTokenTTL},
+		{"ACCRUAL_POLL_INTERVAL (-accrual-poll-interval)", c.AccrualPollInterval},
+		{"ACCRUAL_REQUEST_TIMEOUT (-accrual-request-timeout)", c.AccrualRequestTimeout},
+		{"ACCRUAL_LEASE (-accrual-lease)", c.AccrualLease},
+		{"ACCRUAL_BACKOFF_BASE (-accrual-backoff-base)", c.AccrualBackoffBase},
+		{"ACCRUAL_BACKOFF_MAX (-accrual-backoff-max)", c.AccrualBackoffMax},
+		{"ACCRUAL_MAX_ORDER_AGE (-accrual-max-order-age)", c.AccrualMaxOrderAge},
+		{"IDEMPOTENCY_TTL (-idempotency-ttl)", c.IdempotencyTTL},
+		{"OUTBOX_POLL_INTERVAL (-outbox-poll-interval)", c.OutboxPollInterval},
+		{"OUTBOX_LEASE (-outbox-lease)", c.OutboxLease},
+		{"OUTBOX_BACKOFF_BASE (-outbox-backoff-base)", c.OutboxBackoffBase},
+		{"OUTBOX_BACKOFF_MAX (-outbox-backoff-max)", c.OutboxBackoffMax},
+		{"OUTBOX_WEBHOOK_TIMEOUT (-outbox-webhook-timeout)", c.OutboxWebhookTimeout},
+		{"WEBHOOK_POLL_INTERVAL (-webhook-poll-interval)", c.WebhookPollInterval},
+		{"WEBHOOK_TIMEOUT (-webhook-timeout)", c.WebhookTimeout},
+		{"WEBHOOK_BACKOFF_BASE (-webhook-backoff-base)", c.WebhookBackoffBase},
+		{"WEBHOOK_BACKOFF_MAX (-webhook-backoff-max)", c.WebhookBackoffMax},
+		{"READINESS_ACCRUAL_MAX_AGE (-readiness-accrual-max-age)", c.ReadinessAccrualMaxAge},
+		{"SHUTDOWN_DRAIN_DELAY (-shutdown-drain-delay)", c.ShutdownDrainDelay},
+	}
+	for _, f := range durations {
+		if f.v <= 0 {
+			return fmt.Errorf("%s must be a positive duration (e.g. 5s), got %s", f.name, f.v)
+		}
+	}
+	return nil
+}
+
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		panic(fmt.Sprintf("❌ CONFIG ERROR: %s must be a positive integer, got %q", key, v))
+	}
+	return n
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		panic(fmt.Sprintf("❌ CONFIG ERROR: %s must be a positive duration (e.g. 5s), got %q", key, v))
+	}
+	return d
+}
+
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "gophermart"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
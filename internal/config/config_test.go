@@ -0,0 +1,97 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defaults возвращает конфигурацию со значениями флагов по умолчанию.
+func defaults(t *testing.T) *Config {
+	t.Helper()
+	oldArgs, oldFlags := os.Args, flag.CommandLine
+	t.Cleanup(func() { os.Args, flag.CommandLine = oldArgs, oldFlags })
+	os.Args = []string{"gophermart"}
+	flag.CommandLine = flag.NewFlagSet("gophermart", flag.PanicOnError)
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://localhost:8081")
+	t.Setenv("DATABASE_URI", "memory://")
+	return InitConfig()
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr string
+	}{
+		{name: "#1 значения по умолчанию", mutate: func(*Config) {}},
+		{name: "#2 ноль воркеров из флага", mutate: func(c *Config) { c.AccrualWorkers = 0 }, wantErr: "ACCRUAL_WORKERS"},
+		{name: "#3 нулевой интервал опроса", mutate: func(c *Config) { c.AccrualPollInterval = 0 }, wantErr: "ACCRUAL_POLL_INTERVAL"},
+		{name: "#4 отрицательный интервал вебхуков", mutate: func(c *Config) { c.WebhookPollInterval = -time.Second }, wantErr: "WEBHOOK_POLL_INTERVAL"},
+		{name: "#5 пустой пул соединений", mutate: func(c *Config) { c.DBMaxConns = 0 }, wantErr: "DB_MAX_CONNS"},
+		{name: "#6 короткий ключ партнёра", mutate: func(c *Config) { c.PartnerAPIKey = "secret" }, wantErr: "PARTNER_API_KEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults(t)
+			tt.mutate(cfg)
+			err := cfg.validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestInitConfigRejectsZeroFlag(t *testing.T) {
+	oldArgs, oldFlags := os.Args, flag.CommandLine
+	t.Cleanup(func() { os.Args, flag.CommandLine = oldArgs, oldFlags })
+	os.Args = []string{"gophermart", "-accrual-workers", "0"}
+	flag.CommandLine = flag.NewFlagSet("gophermart", flag.PanicOnError)
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://localhost:8081")
+	t.Setenv("DATABASE_URI", "memory://")
+
+	assert.PanicsWithValue(t,
+		"❌ CONFIG ERROR: ACCRUAL_WORKERS (-accrual-workers) must be a positive integer, got 0",
+		func() { InitConfig() })
+}
+
+func TestInitConfigAllowsEmptyDatabaseURI(t *testing.T) {
+	oldArgs, oldFlags := os.Args, flag.CommandLine
+	t.Cleanup(func() { os.Args, flag.CommandLine = oldArgs, oldFlags })
+	os.Args = []string{"gophermart"}
+	flag.CommandLine = flag.NewFlagSet("gophermart", flag.PanicOnError)
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://localhost:8081")
+	t.Setenv("DATABASE_URI", "")
+
+	var cfg *Config
+	require.NotPanics(t, func() { cfg = InitConfig() }, "без DATABASE_URI сервер запускается с хранилищем в памяти")
+	assert.Empty(t, cfg.DatabaseURI)
+}
+
+func TestInitConfigRejectsAuthSecret(t *testing.T) {
+	oldArgs, oldFlags := os.Args, flag.CommandLine
+	t.Cleanup(func() { os.Args, flag.CommandLine = oldArgs, oldFlags })
+	os.Args = []string{"gophermart"}
+	flag.CommandLine = flag.NewFlagSet("gophermart", flag.PanicOnError)
+	t.Setenv("ACCRUAL_SYSTEM_ADDRESS", "http://localhost:8081")
+	t.Setenv("DATABASE_URI", "memory://")
+	t.Setenv("AUTH_SECRET", "very-hard-secrets")
+
+	assert.PanicsWithValue(t,
+		"❌ CONFIG ERROR: AUTH_SECRET is no longer supported: put PEM signing keys into JWT_KEYS_DIR "+
+			"(or set JWT_EPHEMERAL_KEYS=true for development) and unset AUTH_SECRET",
+		func() { InitConfig() })
+
+	t.Setenv("JWT_KEYS_DIR", t.TempDir())
+	flag.CommandLine = flag.NewFlagSet("gophermart", flag.PanicOnError)
+	assert.NotPanics(t, func() { InitConfig() }, "при настроенных ключах старый секрет не мешает запуску")
+}
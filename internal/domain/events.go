@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderEvent — запись об изменении статуса или начисления заказа.
+// ID служит Last-Event-ID для SSE. ID выдаётся при вставке, а не при
+// коммите, поэтому событие с меньшим ID может стать видимым позже большего.
+type OrderEvent struct {
+	ID        int64               `json:"id"`
+	OrderID   string              `json:"order_id"`
+	UserID    string              `json:"user_id"`
+	Number    string              `json:"number"`
+	Status    OrderStatus         `json:"status"`
+	Accrual   decimal.NullDecimal `json:"accrual"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// OrderOutboxEvent строит событие outbox о переходе заказа change;
+// ev описывает заказ после перехода.
+func OrderOutboxEvent(ev OrderEvent, change StatusChange) outbox.Event {
+	data := outbox.OrderStatusChanged{
+		OrderID:        ev.OrderID,
+		Number:         ev.Number,
+		PreviousStatus: string(change.From),
+		Status:         string(change.Status),
+		Reason:         change.Reason,
+	}
+	if ev.Accrual.Valid {
+		accrual := ev.Accrual.Decimal
+		data.Accrual = &accrual
+	}
+	return outbox.NewOrderStatusChanged(ev.UserID, data)
+}
+
+// EventReplayWindow — на сколько ID назад перечитываются события при
+// возобновлении потока: так находятся события, закоммиченные позже
+// событий с большими ID. Повторы отсеиваются через EventIDSet.
+const EventReplayWindow = 1000
+
+// EventIDSet — ограниченное множество уже отправленных ID событий. При
+// переполнении забываются самые старые ID. Не безопасно для конкурентного
+// использования.
+type EventIDSet struct {
+	ids  map[int64]struct{}
+	ring []int64
+	next int
+}
+
+func NewEventIDSet(limit int) *EventIDSet {
+	return &EventIDSet{ids: make(map[int64]struct{}, limit), ring: make([]int64, limit)}
+}
+
+// Add запоминает id и сообщает, что его ещё не было.
+func (s *EventIDSet) Add(id int64) bool {
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	if len(s.ids) == len(s.ring) {
+		delete(s.ids, s.ring[s.next])
+	}
+	s.ring[s.next] = id
+	s.next = (s.next + 1) % len(s.ring)
+	s.ids[id] = struct{}{}
+	return true
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventIDSet(t *testing.T) {
+	s := NewEventIDSet(3)
+
+	assert.True(t, s.Add(5))
+	assert.True(t, s.Add(4), "меньший ID после большего не считается повтором")
+	assert.False(t, s.Add(5))
+	assert.True(t, s.Add(7))
+
+	// переполнение вытесняет самый старый ID
+	assert.True(t, s.Add(8))
+	assert.True(t, s.Add(5))
+	assert.False(t, s.Add(8))
+	assert.Len(t, s.ids, 3)
+}
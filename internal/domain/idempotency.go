@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord хранит сохранённый ответ на запрос с Idempotency-Key.
+// StatusCode == 0 означает, что первый запрос ещё выполняется.
+type IdempotencyRecord struct {
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	ExpiresAt    time.Time
+}
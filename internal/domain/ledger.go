@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Счета журнала. Каждая проводка переносит сумму с одного счёта на другой,
+// поэтому сумма записей любой транзакции равна нулю.
+const (
+	AccountUserPoints     = "user_points"
+	AccountAccrualSource  = "accrual_source"
+	AccountWithdrawalSink = "withdrawal_sink"
+)
+
+// Виды транзакций журнала.
+const (
+	LedgerKindAccrual    = "accrual"
+	LedgerKindWithdrawal = "withdrawal"
+)
+
+var (
+	ErrPostingExists  = errors.New("ledger posting already exists")
+	ErrInvalidPosting = errors.New("invalid ledger posting")
+)
+
+// Posting описывает перенос Amount со счёта From на счёт To.
+// Пара (Kind, Reference) уникальна и защищает от повторной проводки.
+type Posting struct {
+	Kind      string
+	Reference string
+	UserID    string
+	From      string
+	To        string
+	Amount    decimal.Decimal
+}
+
+type LedgerEntry struct {
+	ID            int64
+	TransactionID string
+	Kind          string
+	Reference     string
+	Account       string
+	UserID        string
+	Amount        decimal.Decimal
+	CreatedAt     time.Time
+}
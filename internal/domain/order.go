@@ -0,0 +1,57 @@
+// Package domain описывает сущности и доменные ошибки, общие для всех
+// бэкендов хранилища, сервисов и обработчиков.
+package domain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrOrderExists         = apperr.New(apperr.CodeOrderOwnedByOtherUser, "order already exists")
+	ErrOrderUploadedByUser = apperr.New(apperr.CodeOrderAlreadyUploaded, "order already uploaded by this user")
+	ErrOrderNotFound       = apperr.New(apperr.CodeOrderNotFound, "order not found")
+)
+
+type Order struct {
+	ID         string              `db:"id"`
+	Number     string              `db:"number"`
+	UserID     string              `db:"user_id"`
+	Status     OrderStatus         `db:"status"`
+	Accrual    decimal.NullDecimal `db:"accrual"`
+	UploadedAt time.Time           `db:"uploaded_at"`
+	Attempts   int                 `db:"attempts"`
+}
+
+// Источники изменения статуса в order_status_history.
+const (
+	StatusSourceWorker = "worker"
+	StatusSourceAdmin  = "admin"
+)
+
+// StatusChange описывает новое состояние заказа и его происхождение.
+type StatusChange struct {
+	From     OrderStatus
+	Status   OrderStatus
+	Accrual  *decimal.Decimal
+	Source   string
+	Reason   string
+	Response json.RawMessage // ответ системы начислений, если статус пришёл из неё
+}
+
+// StatusHistoryEntry — запись о смене статуса заказа.
+type StatusHistoryEntry struct {
+	ID        int64
+	OldStatus OrderStatus
+	NewStatus OrderStatus
+	Accrual   decimal.NullDecimal
+	Source    string
+	Reason    sql.NullString
+	Response  json.RawMessage
+	ChangedAt time.Time
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Cursor — позиция keyset-пагинации: время записи и её id для разрешения равенства времени.
+type Cursor struct {
+	At time.Time
+	ID string
+}
+
+// ListQuery задаёт фильтры, направление сортировки и страницу списка пользователя.
+// Нулевое значение возвращает все записи от новых к старым.
+type ListQuery struct {
+	Statuses  []OrderStatus
+	From      time.Time // включительно
+	To        time.Time // не включительно
+	Ascending bool
+	After     *Cursor
+	Limit     int
+}
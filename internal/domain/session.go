@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionExpired     = errors.New("session expired")
+	ErrSessionRevoked     = errors.New("session revoked")
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// Session — одно звено цепочки refresh-токенов. Все звенья, выпущенные
+// ротацией из одного логина, имеют общий FamilyID.
+type Session struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	ExpiresAt time.Time
+}
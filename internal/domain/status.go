@@ -0,0 +1,62 @@
+package domain
+
+import "errors"
+
+// OrderStatus — статус заказа в системе лояльности.
+type OrderStatus string
+
+const (
+	OrderStatusNew        OrderStatus = "NEW"
+	OrderStatusProcessing OrderStatus = "PROCESSING"
+	OrderStatusInvalid    OrderStatus = "INVALID"
+	OrderStatusProcessed  OrderStatus = "PROCESSED"
+)
+
+// ErrStatusConflict — статус заказа в базе уже не тот, из которого выполнялся переход:
+// его успел изменить другой воркер или администратор.
+var ErrStatusConflict = errors.New("order status changed concurrently")
+
+// ErrTransitionNotAllowed — переход не предусмотрен машиной состояний.
+var ErrTransitionNotAllowed = errors.New("order status transition not allowed")
+
+// transitions — допустимые переходы. Из терминальных статусов переходов нет.
+var transitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew:        {OrderStatusProcessing, OrderStatusProcessed, OrderStatusInvalid},
+	OrderStatusProcessing: {OrderStatusProcessed, OrderStatusInvalid},
+}
+
+// PendingStatuses — статусы, которые ещё ждут расчёта: по ним воркер
+// опрашивает систему расчёта, а метрики считают очередь.
+var PendingStatuses = []OrderStatus{OrderStatusNew, OrderStatusProcessing}
+
+// StatusNames переводит статусы в строки.
+func StatusNames(statuses []OrderStatus) []string {
+	names := make([]string, len(statuses))
+	for i, s := range statuses {
+		names[i] = string(s)
+	}
+	return names
+}
+
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderStatusNew, OrderStatusProcessing, OrderStatusInvalid, OrderStatusProcessed:
+		return true
+	}
+	return false
+}
+
+// Terminal сообщает, что статус окончательный и больше не меняется.
+func (s OrderStatus) Terminal() bool {
+	return s == OrderStatusInvalid || s == OrderStatusProcessed
+}
+
+// CanTransitionTo сообщает, допустим ли переход из s в next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
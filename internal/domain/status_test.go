@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{OrderStatusNew, OrderStatusProcessing, true},
+		{OrderStatusNew, OrderStatusProcessed, true},
+		{OrderStatusNew, OrderStatusInvalid, true},
+		{OrderStatusProcessing, OrderStatusProcessed, true},
+		{OrderStatusProcessing, OrderStatusInvalid, true},
+		{OrderStatusProcessing, OrderStatusNew, false},
+		{OrderStatusProcessing, OrderStatusProcessing, false},
+		{OrderStatusProcessed, OrderStatusProcessing, false},
+		{OrderStatusProcessed, OrderStatusInvalid, false},
+		{OrderStatusInvalid, OrderStatusProcessed, false},
+		{"", OrderStatusProcessing, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
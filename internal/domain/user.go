@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+)
+
+var (
+	ErrUserExists   = apperr.New(apperr.CodeLoginTaken, "user already exists")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type User struct {
+	ID           string
+	Login        string
+	PasswordHash string
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+)
+
+var (
+	ErrWebhookNotFound  = apperr.New(apperr.CodeWebhookNotFound, "webhook subscription not found")
+	ErrDeliveryNotFound = apperr.New(apperr.CodeWebhookDeliveryNotFound, "webhook delivery not found")
+)
+
+// WebhookSubscription — подписка на события по HTTP. Подписка с пустым
+// UserID принадлежит партнёру и получает события всех пользователей,
+// пользовательская — только события своего владельца.
+type WebhookSubscription struct {
+	ID         string
+	UserID     string
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery — доставка одного события в одну подписку и итог
+// последней попытки. URL и Secret заполняются только при взятии в работу.
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	ResponseStatus int // 0, если ответа не было
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+
+	URL    string
+	Secret string
+}
+
+// DeliveryResult — итог попытки доставки. NextAttemptAt учитывается
+// только для DeliveryPending.
+type DeliveryResult struct {
+	Status         DeliveryStatus
+	ResponseStatus int
+	Error          string
+	NextAttemptAt  time.Time
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+)
+
+var (
+	ErrNotEnoughFunds = apperr.New(apperr.CodeNotEnoughFunds, "not enough funds")
+	ErrInvalidOrder   = apperr.New(apperr.CodeInvalidOrderNumber, "invalid order number")
+	ErrInvalidSum     = apperr.New(apperr.CodeInvalidWithdrawalSum, "withdrawal sum must be positive with at most 2 decimal places")
+)
+
+type Withdrawal struct {
+	ID          string
+	UserID      string
+	OrderNumber string
+	Sum         string
+	ProcessedAt time.Time
+}
@@ -0,0 +1,110 @@
+// Package events раздаёт события заказов подписчикам внутри процесса.
+package events
+
+import (
+	"sync"
+
+	"go-musthave-diploma-tpl/internal/domain"
+)
+
+// Hub рассылает события заказов подписчикам их владельца.
+// Publish не блокируется: подписчик, не успевающий читать, отключается,
+// и клиент переподключается с Last-Event-ID.
+type Hub struct {
+	buffer int
+
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription — подписка на события одного пользователя.
+// Канал C закрывается при Close, переполнении буфера или закрытии хаба.
+type Subscription struct {
+	C <-chan domain.OrderEvent
+
+	ch     chan domain.OrderEvent
+	hub    *Hub
+	userID string
+}
+
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		buffer: buffer,
+		subs:   make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Subscribe(userID string) *Subscription {
+	ch := make(chan domain.OrderEvent, h.buffer)
+	sub := &Subscription{C: ch, ch: ch, hub: h, userID: userID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Publish(ev domain.OrderEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[ev.UserID] {
+		select {
+		case sub.ch <- ev:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Close отключает всех подписчиков; новые подписки сразу получают закрытый канал.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribers возвращает число активных подписок.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, subs := range h.subs {
+		n += len(subs)
+	}
+	return n
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove вызывается под h.mu и безопасен для повторного вызова.
+func (h *Hub) remove(sub *Subscription) {
+	subs, ok := h.subs[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+	close(sub.ch)
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubDeliversOnlyToOwner(t *testing.T) {
+	hub := NewHub(4)
+	alice := hub.Subscribe("alice")
+	bob := hub.Subscribe("bob")
+	defer alice.Close()
+	defer bob.Close()
+
+	hub.Publish(domain.OrderEvent{ID: 1, UserID: "alice", Number: "1"})
+
+	require.Len(t, alice.C, 1)
+	assert.Equal(t, int64(1), (<-alice.C).ID)
+	assert.Empty(t, bob.C)
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("alice")
+
+	hub.Publish(domain.OrderEvent{ID: 1, UserID: "alice"})
+	hub.Publish(domain.OrderEvent{ID: 2, UserID: "alice"})
+
+	ev, ok := <-sub.C
+	require.True(t, ok)
+	assert.Equal(t, int64(1), ev.ID)
+	_, ok = <-sub.C
+	assert.False(t, ok, "переполненная подписка закрывается")
+	assert.Zero(t, hub.Subscribers())
+
+	sub.Close() // повторное закрытие не паникует
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("alice")
+
+	hub.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	late := hub.Subscribe("alice")
+	_, ok = <-late.C
+	assert.False(t, ok)
+	assert.Zero(t, hub.Subscribers())
+}
@@ -3,16 +3,25 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/middleware"
+	"go-musthave-diploma-tpl/internal/service"
 	"net/http"
 
 	"go.uber.org/zap"
 )
 
 type AuthServicer interface {
-	Register(ctx context.Context, login, password string) (string, error)
-	Login(ctx context.Context, login, password string) (string, error)
+	Register(ctx context.Context, login, password string) (*service.TokenPair, error)
+	Login(ctx context.Context, login, password string) (*service.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*service.TokenPair, error)
+	Logout(ctx context.Context, familyID string) error
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
 }
 
 type AuthHandler struct {
@@ -37,31 +46,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Warn("failed to decode request", zap.Error(err))
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		writeError(w, r, h.logger, errInvalidJSON)
 		return
 	}
 
-	token, err := h.authService.Register(ctx, req.Login, req.Password)
+	tokens, err := h.authService.Register(ctx, req.Login, req.Password)
 	if err != nil {
-		if errors.Is(err, postgres.ErrUserExists) {
-			http.Error(w, "login already taken", http.StatusConflict)
-			return
-		}
-		if err.Error() == "login and password required" {
-			http.Error(w, "login and password required", http.StatusBadRequest)
-			return
-		}
-		if err.Error() == "password too short" {
-			http.Error(w, "password too short", http.StatusBadRequest)
-			return
-		}
-		h.logger.Error("registration error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, err)
 		return
 	}
-	w.Header().Set("Authorization", "Bearer "+token)
-	w.WriteHeader(http.StatusOK)
-
+	writeTokens(w, tokens)
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -71,25 +65,61 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		writeError(w, r, h.logger, errInvalidJSON)
+		return
+	}
+
+	tokens, err := h.authService.Login(ctx, req.Login, req.Password)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, h.logger, errInvalidJSON)
 		return
 	}
 
-	token, err := h.authService.Login(ctx, req.Login, req.Password)
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
 	if err != nil {
-		if err.Error() == "invalid credentials" {
-			http.Error(w, "invalid credentials", http.StatusUnauthorized)
-			return
-		}
-		if err.Error() == "login and password required" {
-			http.Error(w, "login and password required", http.StatusBadRequest)
-			return
-		}
-		h.logger.Error("login error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	writeTokens(w, tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	familyID, _ := middleware.GetFamilyID(r)
+
+	if err := h.authService.Logout(r.Context(), familyID); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeTokens(w http.ResponseWriter, tokens *service.TokenPair) {
+	if tokens == nil {
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	w.Header().Set("Authorization", "Bearer "+token)
+	w.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	})
 }
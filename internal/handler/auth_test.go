@@ -3,8 +3,12 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"fmt"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/middleware"
+	"go-musthave-diploma-tpl/internal/service"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,18 +22,28 @@ import (
 */
 
 type mockAuthService struct {
-	registerFn func(ctx context.Context, login, password string) (string, error)
-	loginFn    func(ctx context.Context, login, password string) (string, error)
+	registerFn func(ctx context.Context, login, password string) (*service.TokenPair, error)
+	loginFn    func(ctx context.Context, login, password string) (*service.TokenPair, error)
+	refreshFn  func(ctx context.Context, refreshToken string) (*service.TokenPair, error)
+	logoutFn   func(ctx context.Context, familyID string) error
 }
 
-func (m *mockAuthService) Register(ctx context.Context, login, password string) (string, error) {
+func (m *mockAuthService) Register(ctx context.Context, login, password string) (*service.TokenPair, error) {
 	return m.registerFn(ctx, login, password)
 }
 
-func (m *mockAuthService) Login(ctx context.Context, login, password string) (string, error) {
+func (m *mockAuthService) Login(ctx context.Context, login, password string) (*service.TokenPair, error) {
 	return m.loginFn(ctx, login, password)
 }
 
+func (m *mockAuthService) Refresh(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+	return m.refreshFn(ctx, refreshToken)
+}
+
+func (m *mockAuthService) Logout(ctx context.Context, familyID string) error {
+	return m.logoutFn(ctx, familyID)
+}
+
 /*
 ================ TESTS =================
 */
@@ -46,8 +60,8 @@ func TestAuthHandler_Register(t *testing.T) {
 			name:    "#1 success",
 			reqBody: `{"login":"user","password":"pass1234"}`,
 			mockService: &mockAuthService{
-				registerFn: func(ctx context.Context, login, password string) (string, error) {
-					return "token123", nil
+				registerFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return &service.TokenPair{AccessToken: "token123", RefreshToken: "refresh123"}, nil
 				},
 			},
 			wantStatusCode: http.StatusOK,
@@ -57,8 +71,8 @@ func TestAuthHandler_Register(t *testing.T) {
 			name:    "#2 invalid JSON",
 			reqBody: `invalid-json`,
 			mockService: &mockAuthService{
-				registerFn: func(ctx context.Context, login, password string) (string, error) {
-					return "", nil
+				registerFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return nil, nil
 				},
 			},
 			wantStatusCode: http.StatusBadRequest,
@@ -67,8 +81,8 @@ func TestAuthHandler_Register(t *testing.T) {
 			name:    "#3 login exists",
 			reqBody: `{"login":"user","password":"pass1234"}`,
 			mockService: &mockAuthService{
-				registerFn: func(ctx context.Context, login, password string) (string, error) {
-					return "", postgres.ErrUserExists
+				registerFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return nil, domain.ErrUserExists
 				},
 			},
 			wantStatusCode: http.StatusConflict,
@@ -77,8 +91,8 @@ func TestAuthHandler_Register(t *testing.T) {
 			name:    "#4 internal error",
 			reqBody: `{"login":"user","password":"pass1234"}`,
 			mockService: &mockAuthService{
-				registerFn: func(ctx context.Context, login, password string) (string, error) {
-					return "", errors.New("some error")
+				registerFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return nil, errors.New("some error")
 				},
 			},
 			wantStatusCode: http.StatusInternalServerError,
@@ -117,8 +131,8 @@ func TestAuthHandler_Login(t *testing.T) {
 			name:    "#1 success",
 			reqBody: `{"login":"user","password":"pass1234"}`,
 			mockService: &mockAuthService{
-				loginFn: func(ctx context.Context, login, password string) (string, error) {
-					return "token123", nil
+				loginFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return &service.TokenPair{AccessToken: "token123", RefreshToken: "refresh123"}, nil
 				},
 			},
 			wantStatusCode: http.StatusOK,
@@ -128,8 +142,8 @@ func TestAuthHandler_Login(t *testing.T) {
 			name:    "#2 invalid JSON",
 			reqBody: `invalid-json`,
 			mockService: &mockAuthService{
-				loginFn: func(ctx context.Context, login, password string) (string, error) {
-					return "", nil
+				loginFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return nil, nil
 				},
 			},
 			wantStatusCode: http.StatusBadRequest,
@@ -138,8 +152,8 @@ func TestAuthHandler_Login(t *testing.T) {
 			name:    "#3 invalid credentials",
 			reqBody: `{"login":"user","password":"pass1234"}`,
 			mockService: &mockAuthService{
-				loginFn: func(ctx context.Context, login, password string) (string, error) {
-					return "", errors.New("invalid credentials")
+				loginFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return nil, service.ErrInvalidCredentials
 				},
 			},
 			wantStatusCode: http.StatusUnauthorized,
@@ -148,8 +162,8 @@ func TestAuthHandler_Login(t *testing.T) {
 			name:    "#4 internal error",
 			reqBody: `{"login":"user","password":"pass1234"}`,
 			mockService: &mockAuthService{
-				loginFn: func(ctx context.Context, login, password string) (string, error) {
-					return "", errors.New("some error")
+				loginFn: func(ctx context.Context, login, password string) (*service.TokenPair, error) {
+					return nil, errors.New("some error")
 				},
 			},
 			wantStatusCode: http.StatusInternalServerError,
@@ -175,3 +189,88 @@ func TestAuthHandler_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	tests := []struct {
+		name           string
+		reqBody        string
+		refreshFn      func(ctx context.Context, refreshToken string) (*service.TokenPair, error)
+		wantStatusCode int
+		wantHeader     string
+		wantRefresh    string
+	}{
+		{
+			name:    "#1 success",
+			reqBody: `{"refresh_token":"old"}`,
+			refreshFn: func(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+				assert.Equal(t, "old", refreshToken)
+				return &service.TokenPair{AccessToken: "token456", RefreshToken: "new"}, nil
+			},
+			wantStatusCode: http.StatusOK,
+			wantHeader:     "Bearer token456",
+			wantRefresh:    "new",
+		},
+		{
+			name:           "#2 invalid JSON",
+			reqBody:        `{`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "#3 reused token",
+			reqBody: `{"refresh_token":"old"}`,
+			refreshFn: func(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+				return nil, fmt.Errorf("%w: %w", service.ErrInvalidRefreshToken, domain.ErrRefreshTokenReused)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:    "#4 internal error",
+			reqBody: `{"refresh_token":"old"}`,
+			refreshFn: func(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+				return nil, errors.New("db error")
+			},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{refreshFn: tt.refreshFn}, zaptest.NewLogger(t))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/token/refresh", bytes.NewBufferString(tt.reqBody))
+			w := httptest.NewRecorder()
+
+			handler.Refresh(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			if tt.wantHeader != "" {
+				assert.Equal(t, tt.wantHeader, res.Header.Get("Authorization"))
+
+				var body tokenResponse
+				assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+				assert.Equal(t, tt.wantRefresh, body.RefreshToken)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Logout(t *testing.T) {
+	var revoked string
+	handler := NewAuthHandler(&mockAuthService{
+		logoutFn: func(ctx context.Context, familyID string) error {
+			revoked = familyID
+			return nil
+		},
+	}, zaptest.NewLogger(t))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/logout", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.FamilyCtxKey, "family-1"))
+	w := httptest.NewRecorder()
+
+	handler.Logout(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "family-1", revoked)
+}
@@ -7,8 +7,6 @@ import (
 	"net/http"
 	"time"
 
-	"go-musthave-diploma-tpl/internal/repository/postgres"
-
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
@@ -31,13 +29,13 @@ func NewBalanceHandler(s service.BalanceServicer, logger *zap.Logger) *BalanceHa
 func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, errUnauthorized)
 		return
 	}
 
 	current, withdrawn, err := h.service.GetBalance(r.Context(), userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, h.logger, err)
 		return
 	}
 
@@ -56,7 +54,7 @@ func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 func (h *BalanceHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, errUnauthorized)
 		return
 	}
 
@@ -66,35 +64,36 @@ func (h *BalanceHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, h.logger, errInvalidJSON)
 		return
 	}
 
-	err := h.service.Withdraw(r.Context(), userID, req.Order, req.Sum)
-	switch {
-	case err == nil:
-		w.WriteHeader(http.StatusOK)
-	case err == postgres.ErrNotEnoughFunds:
-		w.WriteHeader(http.StatusPaymentRequired)
-	case err == postgres.ErrInvalidOrder:
-		w.WriteHeader(http.StatusUnprocessableEntity)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+	if err := h.service.Withdraw(r.Context(), userID, req.Order, req.Sum); err != nil {
+		writeError(w, r, h.logger, err)
+		return
 	}
+	w.WriteHeader(http.StatusOK)
 }
 
 func (h *BalanceHandler) ListWithdrawals(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	params, err := parseListParams(r, "processed_at", false)
+	if err != nil {
+		writeError(w, r, h.logger, err)
 		return
 	}
 
-	list, err := h.service.ListWithdrawals(r.Context(), userID)
+	list, next, err := h.service.ListWithdrawals(r.Context(), userID, params)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, h.logger, err)
 		return
 	}
+	setNextPage(w, r, next)
 
 	if len(list) == 0 {
 		w.WriteHeader(http.StatusNoContent)
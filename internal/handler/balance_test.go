@@ -4,9 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"go-musthave-diploma-tpl/internal/domain"
 	"go-musthave-diploma-tpl/internal/handler"
 	"go-musthave-diploma-tpl/internal/middleware"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/service"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -20,7 +21,7 @@ import (
 type mockBalanceService struct {
 	GetBalanceFunc      func(ctx context.Context, userID string) (decimal.Decimal, decimal.Decimal, error)
 	WithdrawFunc        func(ctx context.Context, userID, order string, sum decimal.Decimal) error
-	ListWithdrawalsFunc func(ctx context.Context, userID string) ([]postgres.Withdrawal, error)
+	ListWithdrawalsFunc func(ctx context.Context, userID string, params service.ListParams) ([]domain.Withdrawal, string, error)
 }
 
 func (m *mockBalanceService) GetBalance(ctx context.Context, userID string) (decimal.Decimal, decimal.Decimal, error) {
@@ -31,8 +32,8 @@ func (m *mockBalanceService) Withdraw(ctx context.Context, userID, order string,
 	return m.WithdrawFunc(ctx, userID, order, sum)
 }
 
-func (m *mockBalanceService) ListWithdrawals(ctx context.Context, userID string) ([]postgres.Withdrawal, error) {
-	return m.ListWithdrawalsFunc(ctx, userID)
+func (m *mockBalanceService) ListWithdrawals(ctx context.Context, userID string, params service.ListParams) ([]domain.Withdrawal, string, error) {
+	return m.ListWithdrawalsFunc(ctx, userID, params)
 }
 
 // --- Тест GetBalance ---
@@ -75,7 +76,7 @@ func TestBalanceHandler_Withdraw(t *testing.T) {
 	mockSvc := &mockBalanceService{
 		WithdrawFunc: func(ctx context.Context, userID, order string, sum decimal.Decimal) error {
 			if sum.Equal(decimal.NewFromInt(0)) {
-				return postgres.ErrInvalidOrder
+				return domain.ErrInvalidOrder
 			}
 			return nil
 		},
@@ -115,17 +116,17 @@ func TestBalanceHandler_ListWithdrawals(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 
 	mockSvc := &mockBalanceService{
-		ListWithdrawalsFunc: func(ctx context.Context, userID string) ([]postgres.Withdrawal, error) {
+		ListWithdrawalsFunc: func(ctx context.Context, userID string, params service.ListParams) ([]domain.Withdrawal, string, error) {
 			if userID == "empty" {
-				return []postgres.Withdrawal{}, nil
+				return []domain.Withdrawal{}, "", nil
 			}
-			return []postgres.Withdrawal{
+			return []domain.Withdrawal{
 				{
 					OrderNumber: "123",
 					Sum:         "100.50",
 					ProcessedAt: time.Now(),
 				},
-			}, nil
+			}, "", nil
 		},
 	}
 
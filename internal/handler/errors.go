@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/problem"
+
+	"go.uber.org/zap"
+)
+
+var (
+	errInvalidJSON  = apperr.New(apperr.CodeInvalidRequest, "invalid JSON")
+	errUnauthorized = apperr.New(apperr.CodeUnauthorized, "unauthorized")
+)
+
+// writeError отдаёт ошибку как problem+json; внутренние ошибки перед этим логируются,
+// потому что клиент их текста не увидит.
+func writeError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, err error) {
+	if apperr.CodeOf(err) == apperr.CodeInternal {
+		logger.Error("request failed",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Error(err),
+		)
+	}
+	problem.Write(w, r, err)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/events"
+	"go-musthave-diploma-tpl/internal/middleware"
+
+	"go.uber.org/zap"
+)
+
+// sseHeartbeat — период комментариев-пингов, не дающих прокси закрыть простаивающий поток.
+const sseHeartbeat = 15 * time.Second
+
+var errInvalidLastEventID = apperr.New(apperr.CodeInvalidRequest, "Last-Event-ID must be a non-negative integer")
+
+type OrderEventsServicer interface {
+	ListOrderEvents(ctx context.Context, userID string, afterID int64) ([]domain.OrderEvent, error)
+}
+
+type orderEventResponse struct {
+	Number    string   `json:"number"`
+	Status    string   `json:"status"`
+	Accrual   *float32 `json:"accrual,omitempty"`
+	ChangedAt string   `json:"changed_at"`
+}
+
+type EventsHandler struct {
+	history OrderEventsServicer
+	hub     *events.Hub
+	logger  *zap.Logger
+}
+
+func NewEventsHandler(history OrderEventsServicer, hub *events.Hub, logger *zap.Logger) *EventsHandler {
+	return &EventsHandler{history: history, hub: hub, logger: logger}
+}
+
+// OrderEvents отдаёт поток Server-Sent Events об изменениях заказов пользователя.
+// С заголовком Last-Event-ID сначала досылаются события, пропущенные с этого ID;
+// доставка «хотя бы один раз», клиент может получить событие повторно.
+func (h *EventsHandler) OrderEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	var lastID int64
+	resume := r.Header.Get("Last-Event-ID")
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || id < 0 {
+			writeError(w, r, h.logger, errInvalidLastEventID)
+			return
+		}
+		lastID = id
+	}
+
+	// подписка оформляется до чтения истории, чтобы не потерять события между ними
+	sub := h.hub.Subscribe(userID)
+	defer sub.Close()
+
+	// события из истории и из хаба могут пересекаться, а ID приходят не
+	// по порядку, поэтому повторы отсеиваются по множеству отправленных ID
+	seen := domain.NewEventIDSet(2 * domain.EventReplayWindow)
+	var backlog []domain.OrderEvent
+	if resume != "" {
+		seen.Add(lastID)
+		// окно назад от Last-Event-ID находит события, закоммиченные позже него
+		after := max(lastID-domain.EventReplayWindow, 0)
+		var err error
+		backlog, err = h.history.ListOrderEvents(r.Context(), userID, after)
+		if err != nil {
+			writeError(w, r, h.logger, err)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev domain.OrderEvent) error {
+		if !seen.Add(ev.ID) {
+			return nil
+		}
+		if err := writeOrderEvent(w, ev); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for _, ev := range backlog {
+		if err := send(ev); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger.Warn("streaming is not supported by the response writer", zap.Error(err))
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// хаб отключил подписку; клиент переподключится с Last-Event-ID
+				return
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeOrderEvent(w http.ResponseWriter, ev domain.OrderEvent) error {
+	resp := orderEventResponse{
+		Number:    ev.Number,
+		Status:    string(ev.Status),
+		Accrual:   accrualPoints(ev.Accrual),
+		ChangedAt: ev.CreatedAt.Format(time.RFC3339),
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", ev.ID, data)
+	return err
+}
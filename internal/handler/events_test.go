@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/events"
+	"go-musthave-diploma-tpl/internal/handler"
+	"go-musthave-diploma-tpl/internal/middleware"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type mockOrderEvents struct {
+	afterID int64
+	events  []domain.OrderEvent
+}
+
+func (m *mockOrderEvents) ListOrderEvents(ctx context.Context, userID string, afterID int64) ([]domain.OrderEvent, error) {
+	m.afterID = afterID
+	return m.events, nil
+}
+
+func TestEventsHandler_OrderEvents(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	history := &mockOrderEvents{events: []domain.OrderEvent{
+		// 4 закоммичено позже 5 и клиент его ещё не видел, 5 клиент уже получил
+		{ID: 4, UserID: "user1", Number: "4561261212345467", Status: "NEW", CreatedAt: at},
+		{ID: 5, UserID: "user1", Number: "12345678903", Status: "NEW", CreatedAt: at},
+		{ID: 6, UserID: "user1", Number: "12345678903", Status: "PROCESSING", CreatedAt: at},
+		{ID: 7, UserID: "user1", Number: "12345678903", Status: "PROCESSED", CreatedAt: at,
+			Accrual: decimal.NullDecimal{Decimal: decimal.NewFromInt(500), Valid: true}},
+	}}
+	hub := events.NewHub(8)
+	h := handler.NewEventsHandler(history, hub, zaptest.NewLogger(t))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.OrderEvents(w, r.WithContext(context.WithValue(r.Context(), middleware.UserCtxKey, "user1")))
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "5")
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+	// повтор события из истории отсеивается по ID
+	hub.Publish(domain.OrderEvent{ID: 7, UserID: "user1", Number: "12345678903", Status: "PROCESSED", CreatedAt: at})
+	hub.Publish(domain.OrderEvent{ID: 9, UserID: "user2", Number: "4561261212345467", Status: "NEW", CreatedAt: at})
+	hub.Publish(domain.OrderEvent{ID: 8, UserID: "user1", Number: "79927398713", Status: "NEW", CreatedAt: at})
+
+	var frames []string
+	reader := bufio.NewReader(resp.Body)
+	for len(frames) < 4 {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				break
+			}
+			frame.WriteString(line)
+		}
+		frames = append(frames, frame.String())
+	}
+
+	assert.Equal(t, int64(0), history.afterID, "история перечитывается с запасом назад")
+	assert.Equal(t, []string{
+		"id: 4\nevent: order\ndata: {\"number\":\"4561261212345467\",\"status\":\"NEW\",\"changed_at\":\"2024-01-02T03:04:05Z\"}\n",
+		"id: 6\nevent: order\ndata: {\"number\":\"12345678903\",\"status\":\"PROCESSING\",\"changed_at\":\"2024-01-02T03:04:05Z\"}\n",
+		"id: 7\nevent: order\ndata: {\"number\":\"12345678903\",\"status\":\"PROCESSED\",\"accrual\":500,\"changed_at\":\"2024-01-02T03:04:05Z\"}\n",
+		"id: 8\nevent: order\ndata: {\"number\":\"79927398713\",\"status\":\"NEW\",\"changed_at\":\"2024-01-02T03:04:05Z\"}\n",
+	}, frames)
+
+	hub.Close()
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err, "закрытие хаба завершает поток")
+}
+
+// ID выдаются при вставке, поэтому событие с меньшим ID может прийти позже.
+func TestEventsHandler_OutOfOrderIDs(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	hub := events.NewHub(8)
+	h := handler.NewEventsHandler(&mockOrderEvents{}, hub, zaptest.NewLogger(t))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.OrderEvents(w, r.WithContext(context.WithValue(r.Context(), middleware.UserCtxKey, "user1")))
+	}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+	hub.Publish(domain.OrderEvent{ID: 5, UserID: "user1", Number: "12345678903", Status: "NEW", CreatedAt: at})
+	hub.Publish(domain.OrderEvent{ID: 4, UserID: "user1", Number: "79927398713", Status: "NEW", CreatedAt: at})
+	hub.Publish(domain.OrderEvent{ID: 5, UserID: "user1", Number: "12345678903", Status: "NEW", CreatedAt: at})
+	hub.Publish(domain.OrderEvent{ID: 6, UserID: "user1", Number: "12345678903", Status: "PROCESSING", CreatedAt: at})
+
+	var ids []string
+	reader := bufio.NewReader(resp.Body)
+	for len(ids) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	assert.Equal(t, []string{"5", "4", "6"}, ids, "повтор 5 отсеян, опоздавшее 4 доставлено")
+
+	hub.Close()
+}
+
+func TestEventsHandler_InvalidLastEventID(t *testing.T) {
+	h := handler.NewEventsHandler(&mockOrderEvents{}, events.NewHub(1), zaptest.NewLogger(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/orders/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, "user1"))
+	rr := httptest.NewRecorder()
+	h.OrderEvents(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Health — проверка живости: процесс запущен и обслуживает HTTP.
+// Зависимости здесь не проверяются, иначе падение базы приводило бы к рестарту подов.
 func Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(struct {
@@ -12,3 +18,94 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	}{Status: "OK"})
 
 }
+
+const (
+	readinessReady        = "ready"
+	readinessNotReady     = "not_ready"
+	readinessShuttingDown = "shutting_down"
+
+	componentOK   = "ok"
+	componentFail = "fail"
+)
+
+// ReadinessCheck проверяет одну зависимость сервиса.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type componentReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type readinessReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentReport `json:"components,omitempty"`
+}
+
+// Readiness отвечает на /readyz: готов ли сервис принимать трафик.
+type Readiness struct {
+	checks       []ReadinessCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewReadiness(timeout time.Duration, checks ...ReadinessCheck) *Readiness {
+	return &Readiness{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// Drain переводит сервис в состояние not ready перед остановкой,
+// чтобы балансировщик успел вывести его из ротации.
+func (h *Readiness) Drain() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *Readiness) Readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(readinessReport{Status: readinessShuttingDown})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	report := readinessReport{
+		Status:     readinessReady,
+		Components: make(map[string]componentReport, len(h.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := componentReport{Status: componentOK}
+			if err := c.Check(ctx); err != nil {
+				res = componentReport{Status: componentFail, Error: err.Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[c.Name] = res
+			if res.Status == componentFail {
+				report.Status = readinessNotReady
+			}
+		}()
+	}
+	wg.Wait()
+
+	if report.Status != readinessReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
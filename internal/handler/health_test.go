@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,3 +47,65 @@ func TestHealth(t *testing.T) {
 		})
 	}
 }
+
+func TestReadiness_Readyz(t *testing.T) {
+	okCheck := func(ctx context.Context) error { return nil }
+	failCheck := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name           string
+		checks         []ReadinessCheck
+		drain          bool
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name: "#1 all components ok",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: okCheck},
+				{Name: "accrual", Check: okCheck},
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"status":"ready","components":{"accrual":{"status":"ok"},"database":{"status":"ok"}}}`,
+		},
+		{
+			name: "#2 database down",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: failCheck},
+				{Name: "accrual", Check: okCheck},
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"status":"not_ready","components":{"accrual":{"status":"ok"},"database":{"status":"fail","error":"connection refused"}}}`,
+		},
+		{
+			name: "#3 shutting down",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: okCheck},
+			},
+			drain:          true,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"status":"shutting_down"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewReadiness(time.Second, tt.checks...)
+			if tt.drain {
+				h.Drain()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			w := httptest.NewRecorder()
+
+			h.Readyz(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+			assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-musthave-diploma-tpl/internal/service"
+	"net/http"
+)
+
+type JWKSProvider interface {
+	JWKS() service.JWKS
+}
+
+// JWKS отдаёт открытые ключи, которыми другие сервисы проверяют наши токены.
+func JWKS(keys JWKSProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(keys.JWKS())
+	}
+}
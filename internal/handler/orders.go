@@ -4,20 +4,25 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"go-musthave-diploma-tpl/internal/domain"
 	"go-musthave-diploma-tpl/internal/middleware"
 	"go-musthave-diploma-tpl/internal/service"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
-	"go-musthave-diploma-tpl/internal/repository/postgres"
-
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 type OrdersServicer interface {
 	UploadOrder(ctx context.Context, userID, number string) error
-	ListOrders(ctx context.Context, userID string) ([]postgres.Order, error)
+	UploadOrders(ctx context.Context, userID string, numbers []string) ([]service.BatchResult, error)
+	ListOrders(ctx context.Context, userID string, params service.ListParams) ([]domain.Order, string, error)
+	GetOrder(ctx context.Context, userID, number string) (domain.Order, []domain.StatusHistoryEntry, error)
 }
 
 type orderResponse struct {
@@ -27,6 +32,25 @@ type orderResponse struct {
 	UploadedAt string   `json:"uploaded_at"`
 }
 
+type orderDetailResponse struct {
+	orderResponse
+	Timeline []statusChangeResponse `json:"timeline"`
+}
+
+type statusChangeResponse struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Accrual   *float32 `json:"accrual,omitempty"`
+	Source    string   `json:"source"`
+	Reason    string   `json:"reason,omitempty"`
+	ChangedAt string   `json:"changed_at"`
+}
+
+type batchItemResponse struct {
+	Number string `json:"number"`
+	Status string `json:"status"`
+}
+
 type OrdersHandler struct {
 	ordersService OrdersServicer
 	logger        *zap.Logger
@@ -42,13 +66,13 @@ func NewOrdersHandler(ordersService OrdersServicer, logger *zap.Logger) *OrdersH
 func (h *OrdersHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, errUnauthorized)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil || len(body) == 0 {
-		http.Error(w, "order number required", http.StatusBadRequest)
+		writeError(w, r, h.logger, service.ErrOrderNumberRequired)
 		return
 	}
 
@@ -56,38 +80,71 @@ func (h *OrdersHandler) UploadOrder(w http.ResponseWriter, r *http.Request) {
 
 	err = h.ordersService.UploadOrder(r.Context(), userID, number)
 	if err != nil {
-		switch {
-		case errors.Is(err, postgres.ErrInvalidOrder):
-			http.Error(w, "invalid order number", http.StatusUnprocessableEntity)
-		case errors.Is(err, postgres.ErrOrderExists):
-			http.Error(w, "order already exists", http.StatusConflict)
-		case errors.Is(err, service.ErrOrderAlreadyUploaded):
+		// повторная загрузка своего заказа — не ошибка для клиента
+		if errors.Is(err, service.ErrOrderAlreadyUploaded) {
 			w.WriteHeader(http.StatusOK)
 			return
-		default:
-			h.logger.Error("upload order error", zap.Error(err))
-			http.Error(w, "internal error", http.StatusInternalServerError)
 		}
+		writeError(w, r, h.logger, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// UploadOrdersBatch принимает JSON-массив номеров или список номеров по одному в строке
+// и отвечает 200 с результатом по каждому номеру в порядке запроса.
+func (h *OrdersHandler) UploadOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	numbers, err := readOrderNumbers(w, r)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	results, err := h.ordersService.UploadOrders(r.Context(), userID, numbers)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	resp := make([]batchItemResponse, 0, len(results))
+	for _, res := range results {
+		resp = append(resp, batchItemResponse{
+			Number: res.Number,
+			Status: batchItemStatus(res.Err),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 func (h *OrdersHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, errUnauthorized)
 		return
 	}
 
-	orders, err := h.ordersService.ListOrders(r.Context(), userID)
+	params, err := parseListParams(r, "uploaded_at", true)
 	if err != nil {
-		h.logger.Error("list orders error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, err)
 		return
 	}
 
+	orders, next, err := h.ordersService.ListOrders(r.Context(), userID, params)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	setNextPage(w, r, next)
+
 	if len(orders) == 0 {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("[]"))
@@ -95,23 +152,108 @@ func (h *OrdersHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := make([]orderResponse, 0, len(orders))
-
 	for _, o := range orders {
-		r := orderResponse{
-			Number:     o.Number,
-			Status:     o.Status,
-			UploadedAt: o.UploadedAt.Format(time.RFC3339),
-		}
+		resp = append(resp, newOrderResponse(o))
+	}
 
-		if o.Accrual.Valid {
-			f64, _ := o.Accrual.Decimal.Float64()
-			f32 := float32(f64)
-			r.Accrual = &f32
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
 
-		resp = append(resp, r)
+// GetOrder отдаёт заказ пользователя по номеру вместе с историей смены статусов.
+func (h *OrdersHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	order, history, err := h.ordersService.GetOrder(r.Context(), userID, chi.URLParam(r, "number"))
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	resp := orderDetailResponse{
+		orderResponse: newOrderResponse(order),
+		Timeline:      make([]statusChangeResponse, 0, len(history)),
+	}
+	for _, e := range history {
+		resp.Timeline = append(resp.Timeline, statusChangeResponse{
+			From:      string(e.OldStatus),
+			To:        string(e.NewStatus),
+			Accrual:   accrualPoints(e.Accrual),
+			Source:    e.Source,
+			Reason:    e.Reason.String,
+			ChangedAt: e.ChangedAt.Format(time.RFC3339),
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+func newOrderResponse(o domain.Order) orderResponse {
+	return orderResponse{
+		Number:     o.Number,
+		Status:     string(o.Status),
+		Accrual:    accrualPoints(o.Accrual),
+		UploadedAt: o.UploadedAt.Format(time.RFC3339),
+	}
+}
+
+// accrualPoints переводит начисление в float32 ответа API; NULL даёт nil.
+func accrualPoints(d decimal.NullDecimal) *float32 {
+	if !d.Valid {
+		return nil
+	}
+	f64, _ := d.Decimal.Float64()
+	f32 := float32(f64)
+	return &f32
+}
+
+// maxBatchBodySize с запасом вмещает service.MaxBatchOrders номеров.
+const maxBatchBodySize = 1 << 20
+
+// readOrderNumbers читает тело пакетной загрузки: application/json — массив строк,
+// любой другой тип — номера по одному в строке, пустые строки пропускаются.
+func readOrderNumbers(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, service.ErrOrderBatchTooLarge
+		}
+		return nil, errInvalidJSON
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var numbers []string
+		if err := json.Unmarshal(body, &numbers); err != nil {
+			return nil, errInvalidJSON
+		}
+		return numbers, nil
+	}
+
+	var numbers []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if n := strings.TrimSpace(line); n != "" {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers, nil
+}
+
+func batchItemStatus(err error) string {
+	switch {
+	case err == nil:
+		return "accepted"
+	case errors.Is(err, service.ErrOrderAlreadyUploaded):
+		return "already_uploaded"
+	case errors.Is(err, domain.ErrOrderExists):
+		return "owned_by_other_user"
+	default:
+		return "invalid_number"
+	}
+}
@@ -3,22 +3,28 @@ package handler_test
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"go-musthave-diploma-tpl/internal/domain"
 	"go-musthave-diploma-tpl/internal/handler"
 	"go-musthave-diploma-tpl/internal/middleware"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/service"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
 // --- Мок для OrdersServicer ---
 type mockOrdersService struct {
 	UploadFunc func(ctx context.Context, userID, number string) error
-	ListFunc   func(ctx context.Context, userID string) ([]postgres.Order, error)
+	BatchFunc  func(ctx context.Context, userID string, numbers []string) ([]service.BatchResult, error)
+	GetFunc    func(ctx context.Context, userID, number string) (domain.Order, []domain.StatusHistoryEntry, error)
+	ListFunc   func(ctx context.Context, userID string, params service.ListParams) ([]domain.Order, string, error)
 }
 
 func (m *mockOrdersService) UploadOrder(ctx context.Context, userID, number string) error {
@@ -28,20 +34,31 @@ func (m *mockOrdersService) UploadOrder(ctx context.Context, userID, number stri
 	return nil
 }
 
-func (m *mockOrdersService) ListOrders(ctx context.Context, userID string) ([]postgres.Order, error) {
-	if m.ListFunc != nil {
-		return m.ListFunc(ctx, userID)
+func (m *mockOrdersService) UploadOrders(ctx context.Context, userID string, numbers []string) ([]service.BatchResult, error) {
+	if m.BatchFunc != nil {
+		return m.BatchFunc(ctx, userID, numbers)
 	}
 	return nil, nil
 }
 
+func (m *mockOrdersService) GetOrder(ctx context.Context, userID, number string) (domain.Order, []domain.StatusHistoryEntry, error) {
+	return m.GetFunc(ctx, userID, number)
+}
+
+func (m *mockOrdersService) ListOrders(ctx context.Context, userID string, params service.ListParams) ([]domain.Order, string, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, userID, params)
+	}
+	return nil, "", nil
+}
+
 // --- Тест UploadOrder ---
 func TestOrdersHandler_UploadOrder(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockSvc := &mockOrdersService{
 		UploadFunc: func(ctx context.Context, userID, number string) error {
 			if number == "exists" {
-				return postgres.ErrOrderExists
+				return domain.ErrOrderExists
 			}
 			return nil
 		},
@@ -80,11 +97,11 @@ func TestOrdersHandler_UploadOrder(t *testing.T) {
 func TestOrdersHandler_ListOrders(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	mockSvc := &mockOrdersService{
-		ListFunc: func(ctx context.Context, userID string) ([]postgres.Order, error) {
+		ListFunc: func(ctx context.Context, userID string, params service.ListParams) ([]domain.Order, string, error) {
 			if userID == "empty" {
-				return []postgres.Order{}, nil
+				return []domain.Order{}, "", nil
 			}
-			return []postgres.Order{
+			return []domain.Order{
 				{
 					ID:         "1",
 					Number:     "12345678903",
@@ -92,7 +109,7 @@ func TestOrdersHandler_ListOrders(t *testing.T) {
 					Status:     "NEW",
 					UploadedAt: time.Now(),
 				},
-			}, nil
+			}, "", nil
 		},
 	}
 
@@ -121,7 +138,7 @@ func TestOrdersHandler_ListOrders(t *testing.T) {
 				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatusCode)
 			}
 			if tt.wantBodyCount > 0 {
-				var orders []postgres.Order
+				var orders []domain.Order
 				if err := json.NewDecoder(rr.Body).Decode(&orders); err != nil {
 					t.Fatalf("failed to decode body: %v", err)
 				}
@@ -132,3 +149,233 @@ func TestOrdersHandler_ListOrders(t *testing.T) {
 		})
 	}
 }
+
+// --- Тест пагинации ListOrders ---
+func TestOrdersHandler_ListOrdersPagination(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	var got service.ListParams
+	mockSvc := &mockOrdersService{
+		ListFunc: func(ctx context.Context, userID string, params service.ListParams) ([]domain.Order, string, error) {
+			got = params
+			next := ""
+			if params.Limit > 0 {
+				next = "next-token"
+			}
+			return []domain.Order{{ID: "1", Number: "12345678903", Status: "NEW", UploadedAt: time.Now()}}, next, nil
+		},
+	}
+	h := handler.NewOrdersHandler(mockSvc, logger)
+
+	tests := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantParams     service.ListParams
+		wantLink       string
+	}{
+		{
+			name:           "#1 без параметров — весь список без Link",
+			query:          "",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "#2 страница с фильтрами",
+			query:          "?limit=10&status=new,PROCESSED&from=2024-01-01T00:00:00Z&sort=uploaded_at",
+			wantStatusCode: http.StatusOK,
+			wantParams: service.ListParams{
+				Limit:     10,
+				Statuses:  []domain.OrderStatus{domain.OrderStatusNew, domain.OrderStatusProcessed},
+				From:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Ascending: true,
+			},
+			wantLink: `</api/user/orders?cursor=next-token&from=2024-01-01T00%3A00%3A00Z&limit=10&sort=uploaded_at&status=new%2CPROCESSED>; rel="next"`,
+		},
+		{"#3 limit вне диапазона", "?limit=1000", http.StatusBadRequest, service.ListParams{}, ""},
+		{"#4 неизвестный статус", "?status=DONE", http.StatusBadRequest, service.ListParams{}, ""},
+		{"#5 неверная дата", "?from=yesterday", http.StatusBadRequest, service.ListParams{}, ""},
+		{"#6 неизвестная сортировка", "?sort=number", http.StatusBadRequest, service.ListParams{}, ""},
+		{"#7 пустой интервал", "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", http.StatusBadRequest, service.ListParams{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = service.ListParams{}
+			req := httptest.NewRequest(http.MethodGet, "/api/user/orders"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, "user1"))
+			rr := httptest.NewRecorder()
+			h.ListOrders(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("got status %d, want %d", rr.Code, tt.wantStatusCode)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			if got.Limit != tt.wantParams.Limit || got.Ascending != tt.wantParams.Ascending ||
+				!got.From.Equal(tt.wantParams.From) || len(got.Statuses) != len(tt.wantParams.Statuses) {
+				t.Errorf("got params %+v, want %+v", got, tt.wantParams)
+			}
+			if link := rr.Header().Get("Link"); link != tt.wantLink {
+				t.Errorf("got Link %q, want %q", link, tt.wantLink)
+			}
+			if tt.wantLink != "" && rr.Header().Get("X-Next-Cursor") != "next-token" {
+				t.Errorf("got X-Next-Cursor %q", rr.Header().Get("X-Next-Cursor"))
+			}
+		})
+	}
+}
+
+// --- Тест UploadOrdersBatch ---
+func TestOrdersHandler_UploadOrdersBatch(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	var got []string
+	mockSvc := &mockOrdersService{
+		BatchFunc: func(ctx context.Context, userID string, numbers []string) ([]service.BatchResult, error) {
+			got = numbers
+			if len(numbers) == 0 {
+				return nil, service.ErrOrderNumberRequired
+			}
+			errs := []error{nil, service.ErrOrderAlreadyUploaded, domain.ErrOrderExists, domain.ErrInvalidOrder}
+			results := make([]service.BatchResult, len(numbers))
+			for i, n := range numbers {
+				results[i] = service.BatchResult{Number: n, Err: errs[i%len(errs)]}
+			}
+			return results, nil
+		},
+	}
+	h := handler.NewOrdersHandler(mockSvc, logger)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		wantStatusCode int
+		wantNumbers    []string
+		wantStatuses   []string
+	}{
+		{
+			name:           "#1 JSON-массив",
+			contentType:    "application/json",
+			body:           `["1","2","3","4"]`,
+			wantStatusCode: http.StatusOK,
+			wantNumbers:    []string{"1", "2", "3", "4"},
+			wantStatuses:   []string{"accepted", "already_uploaded", "owned_by_other_user", "invalid_number"},
+		},
+		{
+			name:           "#2 список по строкам",
+			contentType:    "text/plain",
+			body:           "1\r\n\n 2 \n",
+			wantStatusCode: http.StatusOK,
+			wantNumbers:    []string{"1", "2"},
+			wantStatuses:   []string{"accepted", "already_uploaded"},
+		},
+		{
+			name:           "#3 битый JSON",
+			contentType:    "application/json",
+			body:           `["1",`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "#4 пустое тело",
+			contentType:    "text/plain",
+			body:           "",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodPost, "/api/user/orders/batch", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, "user1"))
+			rr := httptest.NewRecorder()
+			h.UploadOrdersBatch(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("got status %d, want %d", rr.Code, tt.wantStatusCode)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			if len(got) != len(tt.wantNumbers) {
+				t.Fatalf("got numbers %q, want %q", got, tt.wantNumbers)
+			}
+			var resp []struct {
+				Number string `json:"number"`
+				Status string `json:"status"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			for i, item := range resp {
+				if item.Number != tt.wantNumbers[i] || item.Status != tt.wantStatuses[i] {
+					t.Errorf("item %d: got %+v, want %s/%s", i, item, tt.wantNumbers[i], tt.wantStatuses[i])
+				}
+			}
+		})
+	}
+}
+
+// --- Тест GetOrder ---
+func TestOrdersHandler_GetOrder(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mockSvc := &mockOrdersService{
+		GetFunc: func(ctx context.Context, userID, number string) (domain.Order, []domain.StatusHistoryEntry, error) {
+			if number != "12345678903" {
+				return domain.Order{}, nil, domain.ErrOrderNotFound
+			}
+			return domain.Order{Number: number, Status: "INVALID", UploadedAt: at},
+				[]domain.StatusHistoryEntry{
+					{OldStatus: "NEW", NewStatus: "PROCESSING", Source: domain.StatusSourceWorker, ChangedAt: at},
+					{OldStatus: "PROCESSING", NewStatus: "INVALID", Source: domain.StatusSourceWorker, ChangedAt: at,
+						Reason: sql.NullString{String: "accrual polling gave up", Valid: true}},
+				}, nil
+		},
+	}
+	h := handler.NewOrdersHandler(mockSvc, logger)
+
+	router := chi.NewRouter()
+	router.Get("/api/user/orders/{number}", h.GetOrder)
+
+	tests := []struct {
+		name           string
+		number         string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "#1 заказ с историей",
+			number:         "12345678903",
+			wantStatusCode: http.StatusOK,
+			wantBody: `{"number":"12345678903","status":"INVALID","uploaded_at":"2024-01-02T03:04:05Z","timeline":[` +
+				`{"from":"NEW","to":"PROCESSING","source":"worker","changed_at":"2024-01-02T03:04:05Z"},` +
+				`{"from":"PROCESSING","to":"INVALID","source":"worker","reason":"accrual polling gave up","changed_at":"2024-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:           "#2 чужой или несуществующий заказ",
+			number:         "79927398713",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/user/orders/"+tt.number, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, "user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("got status %d, want %d", rr.Code, tt.wantStatusCode)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rr.Body.String()) != tt.wantBody {
+				t.Errorf("got body %s, want %s", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/service"
+)
+
+const maxPageLimit = 100
+
+func invalidQuery(format string, args ...any) error {
+	return apperr.New(apperr.CodeInvalidQuery, fmt.Sprintf(format, args...))
+}
+
+// parseListParams разбирает limit, cursor, from, to, sort и (если withStatus) status.
+// sort принимает имя поля времени, с минусом — по убыванию (по умолчанию).
+func parseListParams(r *http.Request, timeField string, withStatus bool) (service.ListParams, error) {
+	query := r.URL.Query()
+	var p service.ListParams
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, invalidQuery("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+		p.Limit = limit
+	}
+	p.Cursor = query.Get("cursor")
+
+	if v := query.Get("status"); v != "" {
+		if !withStatus {
+			return p, invalidQuery("status filter is not supported here")
+		}
+		for _, s := range strings.Split(v, ",") {
+			status := domain.OrderStatus(strings.ToUpper(strings.TrimSpace(s)))
+			if !status.Valid() {
+				return p, invalidQuery("unknown status %q", status)
+			}
+			p.Statuses = append(p.Statuses, status)
+		}
+	}
+
+	var err error
+	if p.From, err = parseTime(query.Get("from")); err != nil {
+		return p, invalidQuery("from must be an RFC 3339 timestamp")
+	}
+	if p.To, err = parseTime(query.Get("to")); err != nil {
+		return p, invalidQuery("to must be an RFC 3339 timestamp")
+	}
+	if !p.From.IsZero() && !p.To.IsZero() && !p.From.Before(p.To) {
+		return p, invalidQuery("from must be earlier than to")
+	}
+
+	switch query.Get("sort") {
+	case "", "-" + timeField:
+	case timeField:
+		p.Ascending = true
+	default:
+		return p, invalidQuery("sort must be %s or -%s", timeField, timeField)
+	}
+	return p, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// setNextPage отдаёт курсор следующей страницы в X-Next-Cursor и Link (RFC 8288).
+func setNextPage(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	u := *r.URL
+	query := u.Query()
+	query.Set("cursor", next)
+	u.RawQuery = query.Encode()
+
+	w.Header().Set("X-Next-Cursor", next)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/middleware"
+	"go-musthave-diploma-tpl/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type webhookResponse struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"` // только в ответе на создание
+	CreatedAt  string   `json:"created_at"`
+}
+
+type webhookDeliveryResponse struct {
+	ID             int64           `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	DeliveredAt    string          `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// WebhookHandler управляет подписками на вебхуки. Один и тот же обработчик
+// обслуживает пользователей в публичном API и партнёров на админ-сервере:
+// отличается только то, как определяется владелец подписок.
+type WebhookHandler struct {
+	service service.WebhookServicer
+	logger  *zap.Logger
+	owner   func(r *http.Request) (string, bool)
+}
+
+// NewWebhookHandler — подписки аутентифицированного пользователя на его события.
+func NewWebhookHandler(s service.WebhookServicer, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{service: s, logger: logger, owner: middleware.GetUserID}
+}
+
+// NewPartnerWebhookHandler — партнёрские подписки на события всех
+// пользователей; монтируется только на админ-сервер. Партнёр передаёт
+// apiKey в заголовке Authorization: Bearer <apiKey>; с пустым apiKey
+// отклоняется любой запрос.
+func NewPartnerWebhookHandler(s service.WebhookServicer, apiKey string, logger *zap.Logger) *WebhookHandler {
+	partner := func(r *http.Request) (string, bool) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || apiKey == "" {
+			return "", false
+		}
+		return "", subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) == 1
+	}
+	return &WebhookHandler{service: s, logger: logger, owner: partner}
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	var req struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret"`
+		EventTypes []string `json:"event_types"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, h.logger, errInvalidJSON)
+		return
+	}
+
+	sub, err := h.service.Create(r.Context(), userID, req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+
+	resp := newWebhookResponse(sub)
+	resp.Secret = sub.Secret
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	subs, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	if len(subs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp := make([]webhookResponse, 0, len(subs))
+	for _, sub := range subs {
+		resp = append(resp, newWebhookResponse(sub))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries отдаёт журнал доставок подписки, начиная с последних.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	if len(deliveries) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		item := webhookDeliveryResponse{
+			ID:             d.ID,
+			EventID:        d.EventID,
+			EventType:      d.EventType,
+			Status:         string(d.Status),
+			Attempts:       d.Attempts,
+			ResponseStatus: d.ResponseStatus,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt.Format(time.RFC3339),
+			Payload:        d.Payload,
+		}
+		if d.Status == domain.DeliveryPending {
+			item.NextAttemptAt = d.NextAttemptAt.Format(time.RFC3339)
+		}
+		if !d.DeliveredAt.IsZero() {
+			item.DeliveredAt = d.DeliveredAt.Format(time.RFC3339)
+		}
+		resp = append(resp, item)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Redeliver ставит доставку в очередь заново; отправит её воркер.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.owner(r)
+	if !ok {
+		writeError(w, r, h.logger, errUnauthorized)
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		writeError(w, r, h.logger, domain.ErrDeliveryNotFound)
+		return
+	}
+	if err := h.service.Redeliver(r.Context(), userID, chi.URLParam(r, "id"), deliveryID); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Routes регистрирует обработчики относительно r: пользовательские
+// подписки монтируются в /api/user/webhooks, партнёрские — в /admin/webhooks.
+func (h *WebhookHandler) Routes(r chi.Router) {
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Delete("/{id}", h.Delete)
+	r.Get("/{id}/deliveries", h.ListDeliveries)
+	r.Post("/{id}/deliveries/{deliveryID}/redeliver", h.Redeliver)
+}
+
+func newWebhookResponse(sub domain.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:         sub.ID,
+		URL:        sub.URL,
+		EventTypes: sub.EventTypes,
+		CreatedAt:  sub.CreatedAt.Format(time.RFC3339),
+	}
+}
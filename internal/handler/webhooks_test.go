@@ -0,0 +1,264 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/handler"
+	"go-musthave-diploma-tpl/internal/middleware"
+	"go-musthave-diploma-tpl/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// --- Мок для WebhookService ---
+type mockWebhookService struct {
+	CreateFunc         func(ctx context.Context, userID, rawURL, secret string, eventTypes []string) (domain.WebhookSubscription, error)
+	ListFunc           func(ctx context.Context, userID string) ([]domain.WebhookSubscription, error)
+	DeleteFunc         func(ctx context.Context, userID, id string) error
+	ListDeliveriesFunc func(ctx context.Context, userID, id string) ([]domain.WebhookDelivery, error)
+	RedeliverFunc      func(ctx context.Context, userID, id string, deliveryID int64) error
+}
+
+func (m *mockWebhookService) Create(ctx context.Context, userID, rawURL, secret string, eventTypes []string) (domain.WebhookSubscription, error) {
+	return m.CreateFunc(ctx, userID, rawURL, secret, eventTypes)
+}
+
+func (m *mockWebhookService) List(ctx context.Context, userID string) ([]domain.WebhookSubscription, error) {
+	return m.ListFunc(ctx, userID)
+}
+
+func (m *mockWebhookService) Delete(ctx context.Context, userID, id string) error {
+	return m.DeleteFunc(ctx, userID, id)
+}
+
+func (m *mockWebhookService) ListDeliveries(ctx context.Context, userID, id string) ([]domain.WebhookDelivery, error) {
+	return m.ListDeliveriesFunc(ctx, userID, id)
+}
+
+func (m *mockWebhookService) Redeliver(ctx context.Context, userID, id string, deliveryID int64) error {
+	return m.RedeliverFunc(ctx, userID, id, deliveryID)
+}
+
+// --- Тест Create ---
+func TestWebhookHandler_Create(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	const partnerKey = "pk_0123456789abcdef0123456789abcdef"
+
+	var gotOwner string
+	mockSvc := &mockWebhookService{
+		CreateFunc: func(ctx context.Context, userID, rawURL, secret string, eventTypes []string) (domain.WebhookSubscription, error) {
+			gotOwner = userID
+			if rawURL == "ftp://example.com" {
+				return domain.WebhookSubscription{}, service.ErrInvalidWebhookURL
+			}
+			return domain.WebhookSubscription{
+				ID: "hook-1", UserID: userID, URL: rawURL, Secret: "whsec_generated", EventTypes: eventTypes, CreatedAt: at,
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name           string
+		handler        *handler.WebhookHandler
+		userID         string
+		authorization  string
+		body           string
+		wantStatusCode int
+		wantOwner      string
+		wantBody       string
+	}{
+		{
+			name:           "#1 подписка пользователя, секрет виден в ответе",
+			handler:        handler.NewWebhookHandler(mockSvc, logger),
+			userID:         "user1",
+			body:           `{"url":"https://hooks.example.com","event_types":["order.processed"]}`,
+			wantStatusCode: http.StatusCreated,
+			wantOwner:      "user1",
+			wantBody: `{"id":"hook-1","url":"https://hooks.example.com","event_types":["order.processed"],` +
+				`"secret":"whsec_generated","created_at":"2024-01-02T03:04:05Z"}`,
+		},
+		{
+			name:           "#2 партнёрская подписка без владельца",
+			handler:        handler.NewPartnerWebhookHandler(mockSvc, partnerKey, logger),
+			authorization:  "Bearer " + partnerKey,
+			body:           `{"url":"https://partner.example.com","event_types":["withdrawal.created"]}`,
+			wantStatusCode: http.StatusCreated,
+			wantOwner:      "",
+		},
+		{
+			name:           "#3 неверный адрес",
+			handler:        handler.NewWebhookHandler(mockSvc, logger),
+			userID:         "user1",
+			body:           `{"url":"ftp://example.com","event_types":["order.processed"]}`,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantOwner:      "user1",
+		},
+		{
+			name:           "#4 невалидный JSON",
+			handler:        handler.NewWebhookHandler(mockSvc, logger),
+			userID:         "user1",
+			body:           `{`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "#5 без аутентификации",
+			handler:        handler.NewWebhookHandler(mockSvc, logger),
+			body:           `{"url":"https://hooks.example.com","event_types":["order.processed"]}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "#6 партнёр без ключа",
+			handler:        handler.NewPartnerWebhookHandler(mockSvc, partnerKey, logger),
+			body:           `{"url":"https://partner.example.com","event_types":["withdrawal.created"]}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "#7 партнёр с неверным ключом",
+			handler:        handler.NewPartnerWebhookHandler(mockSvc, partnerKey, logger),
+			authorization:  "Bearer " + partnerKey + "x",
+			body:           `{"url":"https://partner.example.com","event_types":["withdrawal.created"]}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "#8 ключ партнёра не задан",
+			handler:        handler.NewPartnerWebhookHandler(mockSvc, "", logger),
+			authorization:  "Bearer ",
+			body:           `{"url":"https://partner.example.com","event_types":["withdrawal.created"]}`,
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOwner = ""
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, tt.userID))
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			tt.handler.Create(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("got status %d, want %d", rr.Code, tt.wantStatusCode)
+			}
+			if gotOwner != tt.wantOwner {
+				t.Errorf("got owner %q, want %q", gotOwner, tt.wantOwner)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rr.Body.String()) != tt.wantBody {
+				t.Errorf("got body %s, want %s", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+// --- Тест журнала доставок и повторной отправки ---
+func TestWebhookHandler_Deliveries(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var redelivered int64
+	mockSvc := &mockWebhookService{
+		ListDeliveriesFunc: func(ctx context.Context, userID, id string) ([]domain.WebhookDelivery, error) {
+			if id != "hook-1" {
+				return nil, domain.ErrWebhookNotFound
+			}
+			return []domain.WebhookDelivery{
+				{
+					ID: 2, EventID: "event-2", EventType: "order.invalid", Status: domain.DeliveryPending, Attempts: 3,
+					ResponseStatus: 503, LastError: "unexpected status 503", NextAttemptAt: at, CreatedAt: at, Payload: []byte(`{"id":"event-2"}`),
+				},
+				{
+					ID: 1, EventID: "event-1", EventType: "order.processed", Status: domain.DeliverySucceeded, Attempts: 1,
+					ResponseStatus: 200, NextAttemptAt: at, CreatedAt: at, DeliveredAt: at, Payload: []byte(`{"id":"event-1"}`),
+				},
+			}, nil
+		},
+		RedeliverFunc: func(ctx context.Context, userID, id string, deliveryID int64) error {
+			if id != "hook-1" || deliveryID != 2 {
+				return domain.ErrDeliveryNotFound
+			}
+			redelivered = deliveryID
+			return nil
+		},
+	}
+	router := chi.NewRouter()
+	router.Route("/api/user/webhooks", handler.NewWebhookHandler(mockSvc, logger).Routes)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "#1 журнал доставок",
+			method:         http.MethodGet,
+			path:           "/api/user/webhooks/hook-1/deliveries",
+			wantStatusCode: http.StatusOK,
+			wantBody: `[{"id":2,"event_id":"event-2","event_type":"order.invalid","status":"pending","attempts":3,` +
+				`"response_status":503,"last_error":"unexpected status 503","next_attempt_at":"2024-01-02T03:04:05Z",` +
+				`"created_at":"2024-01-02T03:04:05Z","payload":{"id":"event-2"}},` +
+				`{"id":1,"event_id":"event-1","event_type":"order.processed","status":"succeeded","attempts":1,` +
+				`"response_status":200,"created_at":"2024-01-02T03:04:05Z","delivered_at":"2024-01-02T03:04:05Z","payload":{"id":"event-1"}}]`,
+		},
+		{
+			name:           "#2 журнал чужой подписки",
+			method:         http.MethodGet,
+			path:           "/api/user/webhooks/hook-2/deliveries",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "#3 повторная отправка",
+			method:         http.MethodPost,
+			path:           "/api/user/webhooks/hook-1/deliveries/2/redeliver",
+			wantStatusCode: http.StatusAccepted,
+		},
+		{
+			name:           "#4 повторная отправка несуществующей доставки",
+			method:         http.MethodPost,
+			path:           "/api/user/webhooks/hook-1/deliveries/abc/redeliver",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, "user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatusCode {
+				t.Fatalf("got status %d, want %d", rr.Code, tt.wantStatusCode)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			var got, want any
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			json.Unmarshal([]byte(tt.wantBody), &want)
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("got body %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+	if redelivered != 2 {
+		t.Errorf("got redelivered %d, want 2", redelivered)
+	}
+}
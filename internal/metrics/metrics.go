@@ -0,0 +1,274 @@
+// Package metrics содержит коллекторы Prometheus сервиса и реестр, который отдаёт /metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shopspring/decimal"
+)
+
+const namespace = "gophermart"
+
+// Registry — отдельный реестр сервиса, чтобы не зависеть от глобального DefaultRegisterer.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by chi route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	AccrualRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the accrual system by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	AccrualErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "errors_total",
+		Help:      "Failed requests to the accrual system, excluding 204 and 429.",
+	})
+
+	AccrualRateLimited = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "rate_limited_total",
+		Help:      "Responses 429 received from the accrual system.",
+	})
+
+	OrderTransitionsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_transitions_rejected_total",
+		Help:      "Order status updates rejected by the state machine or lost to a concurrent change.",
+	}, []string{"from", "to", "reason"})
+
+	UsersRegistered = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_registered_total",
+		Help:      "Registered users.",
+	})
+
+	OrdersUploaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_uploaded_total",
+		Help:      "Orders accepted for processing.",
+	})
+
+	PointsAccrued = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_accrued_total",
+		Help:      "Loyalty points accrued to users.",
+	})
+
+	PointsWithdrawn = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_withdrawn_total",
+		Help:      "Loyalty points withdrawn by users.",
+	})
+
+	OutboxPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "published_total",
+		Help:      "Domain events delivered to outbox sinks by type.",
+	}, []string{"type"})
+
+	OutboxPublishErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "publish_errors_total",
+		Help:      "Failed deliveries of domain events; each is retried later.",
+	})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "deliveries_total",
+		Help:      "Webhook delivery attempts by result: succeeded, retry or failed.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		AccrualRequestDuration,
+		AccrualErrors,
+		AccrualRateLimited,
+		OrderTransitionsRejected,
+		UsersRegistered,
+		OrdersUploaded,
+		PointsAccrued,
+		PointsWithdrawn,
+		OutboxPublished,
+		OutboxPublishErrors,
+		WebhookDeliveries,
+	)
+}
+
+// Handler отдаёт метрики из Registry в текстовом формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// AddPoints увеличивает счётчик баллов на значение decimal.
+func AddPoints(c prometheus.Counter, amount decimal.Decimal) {
+	if amount.IsPositive() {
+		c.Add(amount.InexactFloat64())
+	}
+}
+
+// poolCollector читает статистику пула pgxpool при каждом scrape.
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+
+	maxConns        *prometheus.Desc
+	totalConns      *prometheus.Desc
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+	acquireDuration *prometheus.Desc
+}
+
+func newPoolCollector(stat func() *pgxpool.Stat) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		stat:            stat,
+		maxConns:        desc("max_conns", "Maximum size of the connection pool."),
+		totalConns:      desc("total_conns", "Connections currently open, including ones being established."),
+		acquiredConns:   desc("acquired_conns", "Connections currently in use."),
+		idleConns:       desc("idle_conns", "Idle connections in the pool."),
+		acquireCount:    desc("acquires_total", "Successful connection acquisitions."),
+		emptyAcquire:    desc("empty_acquires_total", "Acquisitions that had to wait because the pool had no idle connection."),
+		canceledAcquire: desc("canceled_acquires_total", "Acquisitions canceled by their context."),
+		acquireDuration: desc("acquire_duration_seconds_total", "Total time spent waiting for successful acquisitions."),
+	}
+}
+
+// RegisterPoolStats публикует статистику пула соединений PostgreSQL.
+func RegisterPoolStats(pool *pgxpool.Pool) error {
+	return Registry.Register(newPoolCollector(pool.Stat))
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.totalConns
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.acquireCount
+	ch <- c.emptyAcquire
+	ch <- c.canceledAcquire
+	ch <- c.acquireDuration
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
+
+// OrderBacklog — состояние очереди заказов, ожидающих расчёта.
+type OrderBacklog struct {
+	ByStatus     map[string]int
+	OldestUpload time.Time
+}
+
+// BacklogSource возвращает текущее состояние очереди заказов.
+type BacklogSource func(ctx context.Context) (OrderBacklog, error)
+
+// backlogCollector опрашивает источник при каждом scrape,
+// поэтому значения не устаревают между циклами воркера.
+type backlogCollector struct {
+	source   BacklogSource
+	statuses []string
+	timeout  time.Duration
+	now      func() time.Time
+
+	pending   *prometheus.Desc
+	oldestAge *prometheus.Desc
+	up        *prometheus.Desc
+}
+
+// statuses — статусы, по которым воркер ещё опрашивает систему расчёта;
+// по каждому отдаётся значение, даже нулевое.
+func newBacklogCollector(source BacklogSource, statuses []string, timeout time.Duration) *backlogCollector {
+	return &backlogCollector{
+		source:   source,
+		statuses: statuses,
+		timeout:  timeout,
+		now:      time.Now,
+		pending: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "accrual", "backlog_orders"),
+			"Orders waiting for accrual by status.",
+			[]string{"status"}, nil,
+		),
+		oldestAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "accrual", "backlog_oldest_age_seconds"),
+			"Age of the oldest order waiting for accrual.",
+			nil, nil,
+		),
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "accrual", "backlog_scrape_success"),
+			"Whether the last backlog query succeeded.",
+			nil, nil,
+		),
+	}
+}
+
+// RegisterBacklog публикует метрики очереди заказов воркера начислений
+// по статусам statuses.
+func RegisterBacklog(source BacklogSource, statuses []string, timeout time.Duration) error {
+	return Registry.Register(newBacklogCollector(source, statuses, timeout))
+}
+
+func (c *backlogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pending
+	ch <- c.oldestAge
+	ch <- c.up
+}
+
+func (c *backlogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	backlog, err := c.source(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+
+	for _, status := range c.statuses {
+		ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(backlog.ByStatus[status]), status)
+	}
+
+	var age float64
+	if !backlog.OldestUpload.IsZero() {
+		age = c.now().Sub(backlog.OldestUpload).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(c.oldestAge, prometheus.GaugeValue, age)
+}
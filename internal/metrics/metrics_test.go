@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBacklogCollector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		source   BacklogSource
+		expected string
+	}{
+		{
+			name: "#1 pending orders",
+			source: func(ctx context.Context) (OrderBacklog, error) {
+				return OrderBacklog{
+					ByStatus:     map[string]int{"NEW": 3, "PROCESSING": 2},
+					OldestUpload: now.Add(-90 * time.Second),
+				}, nil
+			},
+			expected: `
+# HELP gophermart_accrual_backlog_oldest_age_seconds Age of the oldest order waiting for accrual.
+# TYPE gophermart_accrual_backlog_oldest_age_seconds gauge
+gophermart_accrual_backlog_oldest_age_seconds 90
+# HELP gophermart_accrual_backlog_orders Orders waiting for accrual by status.
+# TYPE gophermart_accrual_backlog_orders gauge
+gophermart_accrual_backlog_orders{status="NEW"} 3
+gophermart_accrual_backlog_orders{status="PROCESSING"} 2
+# HELP gophermart_accrual_backlog_scrape_success Whether the last backlog query succeeded.
+# TYPE gophermart_accrual_backlog_scrape_success gauge
+gophermart_accrual_backlog_scrape_success 1
+`,
+		},
+		{
+			name: "#2 empty backlog",
+			source: func(ctx context.Context) (OrderBacklog, error) {
+				return OrderBacklog{ByStatus: map[string]int{}}, nil
+			},
+			expected: `
+# HELP gophermart_accrual_backlog_oldest_age_seconds Age of the oldest order waiting for accrual.
+# TYPE gophermart_accrual_backlog_oldest_age_seconds gauge
+gophermart_accrual_backlog_oldest_age_seconds 0
+# HELP gophermart_accrual_backlog_orders Orders waiting for accrual by status.
+# TYPE gophermart_accrual_backlog_orders gauge
+gophermart_accrual_backlog_orders{status="NEW"} 0
+gophermart_accrual_backlog_orders{status="PROCESSING"} 0
+# HELP gophermart_accrual_backlog_scrape_success Whether the last backlog query succeeded.
+# TYPE gophermart_accrual_backlog_scrape_success gauge
+gophermart_accrual_backlog_scrape_success 1
+`,
+		},
+		{
+			name: "#3 query failed",
+			source: func(ctx context.Context) (OrderBacklog, error) {
+				return OrderBacklog{}, errors.New("db down")
+			},
+			expected: `
+# HELP gophermart_accrual_backlog_scrape_success Whether the last backlog query succeeded.
+# TYPE gophermart_accrual_backlog_scrape_success gauge
+gophermart_accrual_backlog_scrape_success 0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBacklogCollector(tt.source, []string{"NEW", "PROCESSING"}, time.Second)
+			c.now = func() time.Time { return now }
+
+			assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(tt.expected)))
+		})
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	// пул без MinConns не подключается до первого запроса
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost:1/gophermart?pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	expected := `
+# HELP gophermart_db_pool_acquired_conns Connections currently in use.
+# TYPE gophermart_db_pool_acquired_conns gauge
+gophermart_db_pool_acquired_conns 0
+# HELP gophermart_db_pool_max_conns Maximum size of the connection pool.
+# TYPE gophermart_db_pool_max_conns gauge
+gophermart_db_pool_max_conns 7
+`
+	c := newPoolCollector(pool.Stat)
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"gophermart_db_pool_max_conns", "gophermart_db_pool_acquired_conns"))
+	assert.Equal(t, 8, testutil.CollectAndCount(c))
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/problem"
 	"go-musthave-diploma-tpl/internal/service"
 	"net/http"
 	"strings"
@@ -9,31 +11,62 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	errMissingAuthHeader = apperr.New(apperr.CodeUnauthorized, "missing auth header")
+	errInvalidAuthHeader = apperr.New(apperr.CodeUnauthorized, "invalid auth header")
+	errInvalidToken      = apperr.New(apperr.CodeInvalidToken, "invalid token")
+	errSessionRevoked    = apperr.New(apperr.CodeSessionRevoked, "session revoked")
+)
+
 type СontextKey string
 
-const UserCtxKey СontextKey = "user_id"
+const (
+	UserCtxKey   СontextKey = "user_id"
+	FamilyCtxKey СontextKey = "family_id"
+)
+
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, familyID string, logger *zap.Logger) (bool, error)
+}
 
-func AuthMiddleware(secret string, logger *zap.Logger) func(http.Handler) http.Handler {
+// AuthMiddleware проверяет access-токен и, если в нём есть идентификатор
+// сессии, что сессия не отозвана. sessions может быть nil.
+func AuthMiddleware(keys *service.Keyring, sessions SessionChecker, logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "missing auth header", http.StatusUnauthorized)
+				problem.Write(w, r, errMissingAuthHeader)
 				return
 			}
 			parts := strings.SplitN(authHeader, " ", 2)
 			if !(len(parts) == 2 && parts[0] == "Bearer") {
-				http.Error(w, "invalid auth header", http.StatusUnauthorized)
+				problem.Write(w, r, errInvalidAuthHeader)
 				return
 			}
 
-			claims, err := service.ValidateToken(parts[1], secret)
+			claims, err := service.ValidateToken(keys, parts[1])
 			if err != nil {
 				logger.Debug("invalid token", zap.Error(err))
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				problem.Write(w, r, errInvalidToken)
 				return
 			}
+
+			if sessions != nil && claims.FamilyID != "" {
+				active, err := sessions.IsSessionActive(r.Context(), claims.FamilyID, logger)
+				if err != nil {
+					problem.Write(w, r, err)
+					return
+				}
+				if !active {
+					logger.Debug("revoked session", zap.String("family_id", claims.FamilyID))
+					problem.Write(w, r, errSessionRevoked)
+					return
+				}
+			}
+
 			ctx := context.WithValue(r.Context(), UserCtxKey, claims.UserID)
+			ctx = context.WithValue(ctx, FamilyCtxKey, claims.FamilyID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -43,3 +76,8 @@ func GetUserID(r *http.Request) (string, bool) {
 	userID, ok := r.Context().Value(UserCtxKey).(string)
 	return userID, ok
 }
+
+func GetFamilyID(r *http.Request) (string, bool) {
+	familyID, ok := r.Context().Value(FamilyCtxKey).(string)
+	return familyID, ok && familyID != ""
+}
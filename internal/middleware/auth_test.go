@@ -2,19 +2,35 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/problem"
 	"go-musthave-diploma-tpl/internal/service"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
 
+// problemCode разбирает ответ problem+json и возвращает код ошибки.
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) apperr.Code {
+	t.Helper()
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var p problem.Details
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	return p.Code
+}
+
 func TestAuthMiddleware(t *testing.T) {
-	secret := "testsecret"
+	keys, _ := service.GenerateEphemeralKeyring()
 	logger := zaptest.NewLogger(t)
 
 	// создаём тестовый хендлер, который пишет userID в ответ
@@ -27,13 +43,14 @@ func TestAuthMiddleware(t *testing.T) {
 		fmt.Fprint(w, userID)
 	})
 
-	validToken, _ := service.GenerateToken("user-123", secret)
+	validToken, _ := service.GenerateToken(keys, "user-123", "", time.Hour)
 
 	tests := []struct {
 		name           string
 		authHeader     string
 		wantStatusCode int
 		wantBody       string
+		wantCode       apperr.Code
 	}{
 		{
 			name:           "#1 valid token",
@@ -45,19 +62,19 @@ func TestAuthMiddleware(t *testing.T) {
 			name:           "#2 missing header",
 			authHeader:     "",
 			wantStatusCode: http.StatusUnauthorized,
-			wantBody:       "missing auth header\n",
+			wantCode:       apperr.CodeUnauthorized,
 		},
 		{
 			name:           "#3 invalid header format",
 			authHeader:     "Token " + validToken,
 			wantStatusCode: http.StatusUnauthorized,
-			wantBody:       "invalid auth header\n",
+			wantCode:       apperr.CodeUnauthorized,
 		},
 		{
 			name:           "#4 invalid token",
 			authHeader:     "Bearer invalidtoken",
 			wantStatusCode: http.StatusUnauthorized,
-			wantBody:       "invalid token\n",
+			wantCode:       apperr.CodeInvalidToken,
 		},
 	}
 
@@ -69,7 +86,7 @@ func TestAuthMiddleware(t *testing.T) {
 			}
 			w := httptest.NewRecorder()
 
-			middleware := AuthMiddleware(secret, logger)
+			middleware := AuthMiddleware(keys, nil, logger)
 			middleware(nextHandler).ServeHTTP(w, req)
 
 			res := w.Result()
@@ -77,8 +94,59 @@ func TestAuthMiddleware(t *testing.T) {
 
 			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
 
-			body := w.Body.String()
-			assert.Equal(t, tt.wantBody, body)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, problemCode(t, w))
+				return
+			}
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+type stubSessionChecker map[string]bool
+
+func (s stubSessionChecker) IsSessionActive(ctx context.Context, familyID string, logger *zap.Logger) (bool, error) {
+	return s[familyID], nil
+}
+
+func TestAuthMiddleware_SessionRevocation(t *testing.T) {
+	keys, _ := service.GenerateEphemeralKeyring()
+	logger := zaptest.NewLogger(t)
+	sessions := stubSessionChecker{"active": true, "revoked": false}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		familyID, _ := GetFamilyID(r)
+		fmt.Fprint(w, familyID)
+	})
+
+	tests := []struct {
+		name           string
+		familyID       string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{"#1 active session", "active", http.StatusOK, "active"},
+		{"#2 revoked session", "revoked", http.StatusUnauthorized, ""},
+		{"#3 token without session", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := service.GenerateToken(keys, "user-123", tt.familyID, time.Hour)
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			AuthMiddleware(keys, sessions, logger)(nextHandler).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatusCode, w.Code)
+			if tt.wantStatusCode != http.StatusOK {
+				assert.Equal(t, apperr.CodeSessionRevoked, problemCode(t, w))
+				return
+			}
+			assert.Equal(t, tt.wantBody, w.Body.String())
 		})
 	}
 }
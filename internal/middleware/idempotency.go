@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/problem"
+
+	"go.uber.org/zap"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLen ограничивает длину ключа, присланного клиентом.
+const maxIdempotencyKeyLen = 255
+
+// maxIdempotentBodySize ограничивает тело, которое читается целиком ради хеша.
+const maxIdempotentBodySize = 1 << 20
+
+var (
+	errIdempotencyKeyTooLong    = apperr.New(apperr.CodeIdempotencyKeyTooLong, "idempotency key too long")
+	errIdempotencyKeyMismatch   = apperr.New(apperr.CodeIdempotencyKeyMismatch, "idempotency key reused with different payload")
+	errIdempotencyKeyInProgress = apperr.New(apperr.CodeIdempotencyKeyInProgress, "request with this idempotency key is in progress")
+	errUnauthorized             = apperr.New(apperr.CodeUnauthorized, "unauthorized")
+	errUnreadableBody           = apperr.New(apperr.CodeInvalidRequest, "failed to read body")
+	errBodyTooLarge             = apperr.New(apperr.CodeBodyTooLarge, "request body too large")
+)
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, userID, endpoint, key, requestHash string, ttl time.Duration, logger *zap.Logger) (*domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, userID, endpoint, key string, statusCode int, contentType string, body []byte, logger *zap.Logger) error
+	Release(ctx context.Context, userID, endpoint, key string, logger *zap.Logger) error
+}
+
+// Idempotency сохраняет ответ на запрос с заголовком Idempotency-Key и
+// воспроизводит его для повторов с тем же ключом. Повтор ключа с другим
+// телом запроса отклоняется. Должен стоять после AuthMiddleware.
+func Idempotency(store IdempotencyStore, ttl time.Duration, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				problem.Write(w, r, errIdempotencyKeyTooLong)
+				return
+			}
+
+			userID, ok := GetUserID(r)
+			if !ok {
+				problem.Write(w, r, errUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					problem.Write(w, r, errBodyTooLarge)
+					return
+				}
+				problem.Write(w, r, errUnreadableBody)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			endpoint := r.Method + " " + r.URL.Path
+			hash := sha256.Sum256(body)
+			requestHash := hex.EncodeToString(hash[:])
+
+			rec, reserved, err := store.Reserve(r.Context(), userID, endpoint, key, requestHash, ttl, logger)
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+
+			if !reserved {
+				switch {
+				case rec.RequestHash != requestHash:
+					problem.Write(w, r, errIdempotencyKeyMismatch)
+				case rec.StatusCode == 0:
+					problem.Write(w, r, errIdempotencyKeyInProgress)
+				default:
+					logger.Debug("replaying idempotent response", zap.String("endpoint", endpoint), zap.String("key", key))
+					if rec.ContentType != "" {
+						w.Header().Set("Content-Type", rec.ContentType)
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(rec.StatusCode)
+					w.Write(rec.ResponseBody)
+				}
+				return
+			}
+
+			rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// обработчик упал: ключ освобождаем, чтобы клиент мог повторить запрос
+				_ = store.Release(context.WithoutCancel(r.Context()), userID, endpoint, key, logger)
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			ctx := context.WithoutCancel(r.Context())
+			if rw.status >= http.StatusInternalServerError {
+				_ = store.Release(ctx, userID, endpoint, key, logger)
+			} else {
+				_ = store.Complete(ctx, userID, endpoint, key, rw.status, rw.Header().Get("Content-Type"), rw.body.Bytes(), logger)
+			}
+			completed = true
+		})
+	}
+}
+
+// recordingResponseWriter пишет ответ клиенту и одновременно запоминает его.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
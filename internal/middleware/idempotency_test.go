@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*domain.IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]*domain.IdempotencyRecord{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, userID, endpoint, key, requestHash string, ttl time.Duration, logger *zap.Logger) (*domain.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := userID + endpoint + key
+	if rec, ok := s.records[id]; ok && rec.ExpiresAt.After(time.Now()) {
+		copied := *rec
+		return &copied, false, nil
+	}
+	rec := &domain.IdempotencyRecord{RequestHash: requestHash, ExpiresAt: time.Now().Add(ttl)}
+	s.records[id] = rec
+	return rec, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, userID, endpoint, key string, statusCode int, contentType string, body []byte, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.records[userID+endpoint+key]
+	rec.StatusCode = statusCode
+	rec.ContentType = contentType
+	rec.ResponseBody = body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, userID, endpoint, key string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, userID+endpoint+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	store := newMemoryIdempotencyStore()
+
+	calls := 0
+	status := http.StatusOK
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+	h := Idempotency(store, time.Hour, logger)(next)
+
+	do := func(key, userID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, userID))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("#1 first request executes handler", func(t *testing.T) {
+		rr := do("k1", "user-1", "payload")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "payload", rr.Body.String())
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("#2 retry replays stored response", func(t *testing.T) {
+		rr := do("k1", "user-1", "payload")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "payload", rr.Body.String())
+		assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("#3 reused key with different payload", func(t *testing.T) {
+		rr := do("k1", "user-1", "other")
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("#4 same key of another user is independent", func(t *testing.T) {
+		rr := do("k1", "user-2", "payload")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("#5 no key passes through", func(t *testing.T) {
+		do("", "user-1", "payload")
+		do("", "user-1", "payload")
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("#6 server error releases key", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		rr := do("k2", "user-1", "payload")
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		status = http.StatusOK
+		rr = do("k2", "user-1", "payload")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 6, calls)
+	})
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	store.records["user-1POST /api/user/orderskey"] = &domain.IdempotencyRecord{
+		RequestHash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", // sha256("")
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	h := Idempotency(store, time.Hour, zaptest.NewLogger(t))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/orders", nil)
+	req.Header.Set(IdempotencyKeyHeader, "key")
+	req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, "user-1"))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	h := Idempotency(newMemoryIdempotencyStore(), time.Hour, zaptest.NewLogger(t))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+
+	body := bytes.Repeat([]byte("1"), maxIdempotentBodySize+1)
+	req := httptest.NewRequest(http.MethodPost, "/api/user/orders", bytes.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, "key")
+	req = req.WithContext(context.WithValue(req.Context(), UserCtxKey, "user-1"))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+}
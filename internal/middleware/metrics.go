@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute используется для запросов, не попавших ни в один маршрут,
+// чтобы произвольные URL не раздували кардинальность метрик.
+const unmatchedRoute = "unmatched"
+
+// Metrics записывает длительность запроса с меткой шаблона маршрута chi и статуса.
+func Metrics() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				// шаблон маршрута известен только после того, как chi выполнил роутинг
+				route := unmatchedRoute
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					if pattern := rctx.RoutePattern(); pattern != "" {
+						route = pattern
+					}
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				metrics.HTTPRequestDuration.
+					WithLabelValues(r.Method, route, strconv.Itoa(status)).
+					Observe(time.Since(start).Seconds())
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleCount возвращает число наблюдений гистограммы запросов для набора меток.
+func sampleCount(t *testing.T, method, route, status string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	obs := metrics.HTTPRequestDuration.WithLabelValues(method, route, status)
+	require.NoError(t, obs.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics())
+	r.Get("/api/orders/{number}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		route  string
+		status string
+	}{
+		{
+			name:   "#1 route pattern instead of raw path",
+			url:    "/api/orders/12345678903",
+			route:  "/api/orders/{number}",
+			status: "202",
+		},
+		{
+			name:   "#2 unknown path goes to a single series",
+			url:    "/random/path",
+			route:  unmatchedRoute,
+			status: "404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := sampleCount(t, http.MethodGet, tt.route, tt.status)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, before+1, sampleCount(t, http.MethodGet, tt.route, tt.status))
+		})
+	}
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_locked_until;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS locked_by;
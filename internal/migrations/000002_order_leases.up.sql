@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    ADD COLUMN locked_by TEXT,
+    ADD COLUMN locked_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_locked_until ON orders(locked_until);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_next_poll_at;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS next_poll_at,
+    DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,7 @@
+ALTER TABLE orders
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN next_poll_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN last_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_orders_next_poll_at ON orders(next_poll_at)
+    WHERE status IN ('NEW', 'PROCESSING');
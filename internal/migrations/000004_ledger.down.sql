@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_balances;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_transactions;
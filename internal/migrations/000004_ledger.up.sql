@@ -0,0 +1,60 @@
+CREATE TABLE ledger_transactions (
+                                     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                     kind TEXT NOT NULL,
+                                     reference TEXT NOT NULL,
+                                     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                     UNIQUE(kind, reference)
+);
+
+CREATE TABLE ledger_entries (
+                                id BIGSERIAL PRIMARY KEY,
+                                transaction_id UUID NOT NULL REFERENCES ledger_transactions(id) ON DELETE CASCADE,
+                                account TEXT NOT NULL,
+                                user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                amount DECIMAL(12,2) NOT NULL,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user_account ON ledger_entries(user_id, account);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction ON ledger_entries(transaction_id);
+
+CREATE TABLE user_balances (
+                               user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+                               current DECIMAL(12,2) NOT NULL DEFAULT 0,
+                               withdrawn DECIMAL(12,2) NOT NULL DEFAULT 0,
+                               updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Переносим в журнал уже начисленные баллы и списания.
+WITH tx AS (
+    INSERT INTO ledger_transactions (kind, reference, created_at)
+        SELECT 'accrual', id::text, COALESCE(uploaded_at, NOW())
+        FROM orders
+        WHERE status = 'PROCESSED' AND accrual > 0
+        RETURNING id, reference, created_at
+)
+INSERT INTO ledger_entries (transaction_id, account, user_id, amount, created_at)
+SELECT tx.id, legs.account, o.user_id, legs.sign * o.accrual, tx.created_at
+FROM tx
+         JOIN orders o ON o.id::text = tx.reference
+         CROSS JOIN (VALUES ('accrual_source', -1), ('user_points', 1)) AS legs(account, sign);
+
+WITH tx AS (
+    INSERT INTO ledger_transactions (kind, reference, created_at)
+        SELECT 'withdrawal', id::text, COALESCE(processed_at, NOW())
+        FROM withdrawals
+        RETURNING id, reference, created_at
+)
+INSERT INTO ledger_entries (transaction_id, account, user_id, amount, created_at)
+SELECT tx.id, legs.account, w.user_id, legs.sign * w.sum, tx.created_at
+FROM tx
+         JOIN withdrawals w ON w.id::text = tx.reference
+         CROSS JOIN (VALUES ('user_points', -1), ('withdrawal_sink', 1)) AS legs(account, sign);
+
+INSERT INTO user_balances (user_id, current, withdrawn)
+SELECT u.id,
+       COALESCE((SELECT SUM(e.amount) FROM ledger_entries e
+                 WHERE e.user_id = u.id AND e.account = 'user_points'), 0),
+       COALESCE((SELECT SUM(e.amount) FROM ledger_entries e
+                 WHERE e.user_id = u.id AND e.account = 'withdrawal_sink'), 0)
+FROM users u;
@@ -0,0 +1,14 @@
+CREATE TABLE idempotency_keys (
+                                  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                  endpoint TEXT NOT NULL,
+                                  key TEXT NOT NULL,
+                                  request_hash TEXT NOT NULL,
+                                  status_code INT,
+                                  content_type TEXT,
+                                  response_body BYTEA,
+                                  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                  expires_at TIMESTAMPTZ NOT NULL,
+                                  PRIMARY KEY (user_id, endpoint, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,13 @@
+CREATE TABLE sessions (
+                          id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                          user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                          family_id UUID NOT NULL,
+                          token_hash TEXT NOT NULL UNIQUE,
+                          created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                          expires_at TIMESTAMPTZ NOT NULL,
+                          rotated_at TIMESTAMPTZ,
+                          revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions(family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
@@ -0,0 +1,11 @@
+CREATE TABLE order_events (
+                              id BIGSERIAL PRIMARY KEY,
+                              order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+                              user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                              number TEXT NOT NULL,
+                              status TEXT NOT NULL,
+                              accrual DECIMAL(12,2),
+                              created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_user_id ON order_events(user_id, id);
@@ -0,0 +1,13 @@
+CREATE TABLE order_status_history (
+                                      id BIGSERIAL PRIMARY KEY,
+                                      order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+                                      old_status TEXT NOT NULL,
+                                      new_status TEXT NOT NULL,
+                                      accrual DECIMAL(12,2),
+                                      source TEXT NOT NULL,
+                                      reason TEXT,
+                                      accrual_response JSONB,
+                                      changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id, id);
@@ -0,0 +1,17 @@
+CREATE TABLE outbox (
+                        id BIGSERIAL PRIMARY KEY,
+                        event_id UUID NOT NULL UNIQUE,
+                        event_type TEXT NOT NULL,
+                        schema_version INT NOT NULL,
+                        user_id UUID NOT NULL,
+                        data JSONB NOT NULL,
+                        occurred_at TIMESTAMPTZ NOT NULL,
+                        attempts INT NOT NULL DEFAULT 0,
+                        next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                        locked_by TEXT,
+                        locked_until TIMESTAMPTZ,
+                        last_error TEXT,
+                        published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(next_attempt_at, id) WHERE published_at IS NULL;
@@ -0,0 +1,30 @@
+CREATE TABLE webhook_subscriptions (
+                                       id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                                       user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+                                       url TEXT NOT NULL,
+                                       secret TEXT NOT NULL,
+                                       event_types TEXT[] NOT NULL,
+                                       created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_user_id ON webhook_subscriptions(user_id);
+
+CREATE TABLE webhook_deliveries (
+                                    id BIGSERIAL PRIMARY KEY,
+                                    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+                                    event_id UUID NOT NULL,
+                                    event_type TEXT NOT NULL,
+                                    payload JSONB NOT NULL,
+                                    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+                                    attempts INT NOT NULL DEFAULT 0,
+                                    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                    locked_by TEXT,
+                                    locked_until TIMESTAMPTZ,
+                                    response_status INT,
+                                    last_error TEXT,
+                                    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                                    delivered_at TIMESTAMPTZ,
+                                    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at, id) WHERE status = 'pending';
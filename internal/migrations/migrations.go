@@ -0,0 +1,33 @@
+// Package migrations встраивает SQL-миграции в бинарник.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Latest возвращает номер последней встроенной миграции —
+// версию схемы, которую ожидает этот бинарник.
+func Latest() (uint, error) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, f := range files {
+		prefix, _, _ := strings.Cut(f, "_")
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: invalid version: %w", f, err)
+		}
+		latest = max(latest, uint(v))
+	}
+	return latest, nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS_UpAndDownPairs(t *testing.T) {
+	files, err := fs.Glob(FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	names := map[string]bool{}
+	for _, f := range files {
+		names[f] = true
+	}
+
+	for _, f := range files {
+		if up, ok := strings.CutSuffix(f, ".up.sql"); ok {
+			assert.True(t, names[up+".down.sql"], "missing down migration for %s", f)
+		}
+		if down, ok := strings.CutSuffix(f, ".down.sql"); ok {
+			assert.True(t, names[down+".up.sql"], "missing up migration for %s", f)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	require.NoError(t, err)
+
+	latest, err := Latest()
+	require.NoError(t, err)
+	assert.EqualValues(t, len(files), latest)
+}
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS order_events;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS user_balances;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_transactions;
+DROP TABLE IF EXISTS withdrawals;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,120 @@
+-- Схема SQLite повторяет схему PostgreSQL на момент появления бэкенда.
+-- UUID генерируются приложением, суммы хранятся текстом DECIMAL(12,2),
+-- время — целым числом микросекунд UTC, чтобы сравнение и сортировка
+-- совпадали с timestamptz.
+
+CREATE TABLE users (
+    id            TEXT PRIMARY KEY,
+    login         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at    INTEGER NOT NULL
+);
+
+CREATE TABLE orders (
+    id           TEXT PRIMARY KEY,
+    number       TEXT NOT NULL UNIQUE,
+    user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status       TEXT NOT NULL DEFAULT 'NEW'
+        CHECK (status IN ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED')),
+    accrual      TEXT,
+    uploaded_at  INTEGER NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    next_poll_at INTEGER NOT NULL,
+    last_error   TEXT,
+    locked_by    TEXT,
+    locked_until INTEGER
+);
+
+CREATE INDEX idx_orders_user_uploaded ON orders(user_id, uploaded_at DESC, id DESC);
+CREATE INDEX idx_orders_next_poll_at ON orders(next_poll_at) WHERE status IN ('NEW', 'PROCESSING');
+
+CREATE TABLE withdrawals (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_number TEXT NOT NULL,
+    sum          TEXT NOT NULL,
+    processed_at INTEGER NOT NULL,
+    UNIQUE (user_id, order_number)
+);
+
+CREATE INDEX idx_withdrawals_user_processed ON withdrawals(user_id, processed_at DESC, id DESC);
+
+CREATE TABLE ledger_transactions (
+    id         TEXT PRIMARY KEY,
+    kind       TEXT NOT NULL,
+    reference  TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (kind, reference)
+);
+
+CREATE TABLE ledger_entries (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_id TEXT NOT NULL REFERENCES ledger_transactions(id) ON DELETE CASCADE,
+    account        TEXT NOT NULL,
+    user_id        TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount         TEXT NOT NULL,
+    created_at     INTEGER NOT NULL
+);
+
+CREATE INDEX idx_ledger_entries_user_account ON ledger_entries(user_id, account);
+
+CREATE TABLE user_balances (
+    user_id    TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    current    TEXT NOT NULL DEFAULT '0.00',
+    withdrawn  TEXT NOT NULL DEFAULT '0.00',
+    updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE idempotency_keys (
+    user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint      TEXT NOT NULL,
+    key           TEXT NOT NULL,
+    request_hash  TEXT NOT NULL,
+    status_code   INTEGER,
+    content_type  TEXT,
+    response_body BLOB,
+    created_at    INTEGER NOT NULL,
+    expires_at    INTEGER NOT NULL,
+    PRIMARY KEY (user_id, endpoint, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+CREATE TABLE sessions (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id  TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    rotated_at INTEGER,
+    revoked_at INTEGER
+);
+
+CREATE INDEX idx_sessions_family_id ON sessions(family_id);
+
+CREATE TABLE order_events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id   TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    number     TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    accrual    TEXT,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX idx_order_events_user_id ON order_events(user_id, id);
+
+CREATE TABLE order_status_history (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id         TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    old_status       TEXT NOT NULL,
+    new_status       TEXT NOT NULL,
+    accrual          TEXT,
+    source           TEXT NOT NULL,
+    reason           TEXT,
+    accrual_response BLOB,
+    changed_at       INTEGER NOT NULL
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id, id);
@@ -0,0 +1,17 @@
+CREATE TABLE outbox (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id        TEXT NOT NULL UNIQUE,
+    event_type      TEXT NOT NULL,
+    schema_version  INTEGER NOT NULL,
+    user_id         TEXT NOT NULL,
+    data            TEXT NOT NULL,
+    occurred_at     INTEGER NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL,
+    locked_by       TEXT,
+    locked_until    INTEGER,
+    last_error      TEXT,
+    published_at    INTEGER
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at, id) WHERE published_at IS NULL;
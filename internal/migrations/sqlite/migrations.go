@@ -0,0 +1,8 @@
+// Package sqlite встраивает миграции схемы для бэкенда SQLite.
+// Версии независимы от миграций PostgreSQL.
+package sqlite
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
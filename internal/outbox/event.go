@@ -0,0 +1,127 @@
+// Package outbox описывает доменные события, которые репозитории записывают
+// в таблицу outbox в одной транзакции с изменением, и relay, доставляющий
+// их во внешние приёмники с гарантией at-least-once.
+package outbox
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Типы событий. Тип вместе с версией схемы определяет формат Data.
+const (
+	TypeUserRegistered    = "user.registered"
+	TypeOrderProcessing   = "order.processing"
+	TypeOrderProcessed    = "order.processed"
+	TypeOrderInvalid      = "order.invalid"
+	TypeWithdrawalCreated = "withdrawal.created"
+)
+
+// SchemaVersion — версия схемы, с которой записываются новые события.
+// Несовместимое изменение Data требует новой версии и нового файла в schemas/.
+const SchemaVersion = 1
+
+// Schemas — JSON Schema для Data каждого типа события: <type>.v<version>.json.
+//
+//go:embed schemas/*.json
+var Schemas embed.FS
+
+// Event — конверт доменного события. Доставка at-least-once,
+// поэтому получатели отбрасывают повторы по ID.
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	UserID        string          `json:"user_id"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// MarshalJSON добавляет к конверту идентификатор схемы Data.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type envelope Event
+	return json.Marshal(struct {
+		envelope
+		Schema string `json:"schema"`
+	}{envelope(e), SchemaID(e.Type, e.SchemaVersion)})
+}
+
+// SchemaID возвращает $id схемы события типа eventType версии version.
+func SchemaID(eventType string, version int) string {
+	return fmt.Sprintf("urn:gophermart:event:%s:v%d", eventType, version)
+}
+
+// SchemaFile возвращает путь к схеме события в Schemas.
+func SchemaFile(eventType string, version int) string {
+	return fmt.Sprintf("schemas/%s.v%d.json", eventType, version)
+}
+
+// Message — событие из outbox с номером строки и числом попыток доставки,
+// включая текущую.
+type Message struct {
+	Seq      int64
+	Attempts int
+	Event    Event
+}
+
+// UserRegistered — данные события user.registered.
+type UserRegistered struct {
+	Login string `json:"login"`
+}
+
+// OrderStatusChanged — данные событий order.processing, order.processed и order.invalid.
+type OrderStatusChanged struct {
+	OrderID        string           `json:"order_id"`
+	Number         string           `json:"number"`
+	PreviousStatus string           `json:"previous_status"`
+	Status         string           `json:"status"`
+	Accrual        *decimal.Decimal `json:"accrual,omitempty"`
+	Reason         string           `json:"reason,omitempty"`
+}
+
+// WithdrawalCreated — данные события withdrawal.created.
+type WithdrawalCreated struct {
+	WithdrawalID string          `json:"withdrawal_id"`
+	Order        string          `json:"order"`
+	Sum          decimal.Decimal `json:"sum"`
+	ProcessedAt  time.Time       `json:"processed_at"`
+}
+
+// OrderEventType возвращает тип события перехода заказа в status.
+func OrderEventType(status string) string {
+	return "order." + strings.ToLower(status)
+}
+
+func NewUserRegistered(userID string, data UserRegistered) Event {
+	return newEvent(TypeUserRegistered, userID, data)
+}
+
+func NewOrderStatusChanged(userID string, data OrderStatusChanged) Event {
+	return newEvent(OrderEventType(data.Status), userID, data)
+}
+
+func NewWithdrawalCreated(userID string, data WithdrawalCreated) Event {
+	return newEvent(TypeWithdrawalCreated, userID, data)
+}
+
+func newEvent(eventType, userID string, data any) Event {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		// данные событий — структуры из этого пакета, их сериализация не падает
+		panic(fmt.Sprintf("outbox: marshal %s: %v", eventType, err))
+	}
+	return Event{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		SchemaVersion: SchemaVersion,
+		OccurredAt:    time.Now().UTC().Truncate(time.Microsecond),
+		UserID:        userID,
+		Data:          raw,
+	}
+}
@@ -0,0 +1,119 @@
+package outbox
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schema — подмножество JSON Schema, которым описаны данные событий.
+type schema struct {
+	ID                   string                    `json:"$id"`
+	Title                string                    `json:"title"`
+	Properties           map[string]map[string]any `json:"properties"`
+	Required             []string                  `json:"required"`
+	AdditionalProperties bool                      `json:"additionalProperties"`
+}
+
+func TestEventsMatchSchemas(t *testing.T) {
+	accrual := decimal.RequireFromString("120.5")
+	tests := []struct {
+		name string
+		ev   Event
+		want string
+	}{
+		{
+			name: "#1 регистрация пользователя",
+			ev:   NewUserRegistered("user-1", UserRegistered{Login: "alice"}),
+			want: TypeUserRegistered,
+		},
+		{
+			name: "#2 заказ взят в обработку",
+			ev: NewOrderStatusChanged("user-1", OrderStatusChanged{
+				OrderID: "order-1", Number: "12345678903", PreviousStatus: "NEW", Status: "PROCESSING",
+			}),
+			want: TypeOrderProcessing,
+		},
+		{
+			name: "#3 заказ рассчитан",
+			ev: NewOrderStatusChanged("user-1", OrderStatusChanged{
+				OrderID: "order-1", Number: "12345678903", PreviousStatus: "PROCESSING", Status: "PROCESSED", Accrual: &accrual,
+			}),
+			want: TypeOrderProcessed,
+		},
+		{
+			name: "#4 опрос заказа прекращён",
+			ev: NewOrderStatusChanged("user-1", OrderStatusChanged{
+				OrderID: "order-1", Number: "12345678903", PreviousStatus: "NEW", Status: "INVALID", Reason: "too old",
+			}),
+			want: TypeOrderInvalid,
+		},
+		{
+			name: "#5 списание",
+			ev: NewWithdrawalCreated("user-1", WithdrawalCreated{
+				WithdrawalID: "withdrawal-1", Order: "2377225624", Sum: decimal.RequireFromString("40.50"), ProcessedAt: time.Now(),
+			}),
+			want: TypeWithdrawalCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.ev.Type)
+			assert.Equal(t, SchemaVersion, tt.ev.SchemaVersion)
+			assert.NotEmpty(t, tt.ev.ID)
+
+			raw, err := Schemas.ReadFile(SchemaFile(tt.ev.Type, tt.ev.SchemaVersion))
+			require.NoError(t, err)
+			var sc schema
+			require.NoError(t, json.Unmarshal(raw, &sc))
+			assert.Equal(t, tt.ev.Type, sc.Title)
+
+			body, err := json.Marshal(tt.ev)
+			require.NoError(t, err)
+			var envelope struct {
+				Schema string         `json:"schema"`
+				Data   map[string]any `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(body, &envelope))
+			assert.Equal(t, sc.ID, envelope.Schema)
+
+			for _, key := range sc.Required {
+				assert.Contains(t, envelope.Data, key)
+			}
+			for key, value := range envelope.Data {
+				prop, ok := sc.Properties[key]
+				if !assert.True(t, ok || sc.AdditionalProperties, "unexpected property %q", key) {
+					continue
+				}
+				if c, ok := prop["const"]; ok {
+					assert.Equal(t, c, value, key)
+				}
+				if enum, ok := prop["enum"]; ok {
+					assert.Contains(t, enum, value, key)
+				}
+				if prop["type"] == "string" {
+					assert.IsType(t, "", value, key)
+				}
+			}
+		})
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	ev := NewUserRegistered("user-1", UserRegistered{Login: "alice"})
+
+	body, err := json.Marshal(ev)
+	require.NoError(t, err)
+
+	var got Event
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, ev.ID, got.ID)
+	assert.Equal(t, ev.Type, got.Type)
+	assert.True(t, ev.OccurredAt.Equal(got.OccurredAt))
+	assert.JSONEq(t, string(ev.Data), string(got.Data))
+}
@@ -0,0 +1,46 @@
+// Package outboxtest содержит локальную замену брокера сообщений
+// для тестов и запуска без Kafka/NATS.
+package outboxtest
+
+import (
+	"context"
+	"sync"
+)
+
+// Message — сообщение, принятое Broker.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Broker реализует outbox.Producer в памяти. Пока Fail возвращает ошибку,
+// сообщения не принимаются — так проверяются повторы доставки.
+type Broker struct {
+	mu       sync.Mutex
+	messages []Message
+
+	Fail func(topic string, key []byte) error
+}
+
+func (b *Broker) Produce(ctx context.Context, topic string, key, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Fail != nil {
+		if err := b.Fail(topic, key); err != nil {
+			return err
+		}
+	}
+	b.messages = append(b.messages, Message{Topic: topic, Key: key, Value: value})
+	return nil
+}
+
+// Messages возвращает копию принятых сообщений в порядке записи.
+func (b *Broker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
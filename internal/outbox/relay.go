@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// dbTimeout ограничивает отдельные обращения relay к базе.
+const dbTimeout = 2 * time.Second
+
+// Store — таблица outbox с точки зрения relay.
+type Store interface {
+	ClaimOutbox(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]Message, error)
+	MarkOutboxPublished(ctx context.Context, seq int64, logger *zap.Logger) error
+	RetryOutbox(ctx context.Context, seq int64, nextAttemptAt time.Time, lastErr string, logger *zap.Logger) error
+}
+
+// Relay переносит события из outbox в Sink. Событие помечается
+// опубликованным только после ответа приёмника, поэтому при сбое между
+// ними оно будет доставлено повторно (at-least-once). Несколько реплик
+// делят очередь через аренду строк, как воркер начислений — заказы.
+type Relay struct {
+	Store  Store
+	Sink   Sink
+	Logger *zap.Logger
+
+	workerID     string
+	batchSize    int
+	pollInterval time.Duration
+	lease        time.Duration
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+}
+
+func NewRelay(store Store, sink Sink, cfg *config.Config, logger *zap.Logger) *Relay {
+	return &Relay{
+		Store:        store,
+		Sink:         sink,
+		Logger:       logger,
+		workerID:     cfg.AccrualWorkerID,
+		batchSize:    cfg.OutboxBatchSize,
+		pollInterval: cfg.OutboxPollInterval,
+		lease:        cfg.OutboxLease,
+		backoffBase:  cfg.OutboxBackoffBase,
+		backoffMax:   cfg.OutboxBackoffMax,
+	}
+}
+
+// Run доставляет события каждые pollInterval до отмены ctx.
+// Пока пачки приходят полными, следующая берётся без ожидания.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.Logger.Info("outbox relay stopped", zap.Error(ctx.Err()))
+			return
+		case <-ticker.C:
+			for r.Flush(ctx) == r.batchSize && ctx.Err() == nil {
+			}
+		}
+	}
+}
+
+// Flush берёт в аренду пачку событий и доставляет их по порядку.
+// Если событие пользователя не доставлено, его последующие события
+// в пачке откладываются вместе с ним, чтобы не обогнать его.
+// Возвращает число взятых событий.
+func (r *Relay) Flush(ctx context.Context) int {
+	dbCtx, cancel := context.WithTimeout(ctx, dbTimeout)
+	msgs, err := r.Store.ClaimOutbox(dbCtx, r.workerID, r.batchSize, r.lease, r.Logger)
+	cancel()
+	if err != nil {
+		r.Logger.Error("failed to claim outbox events", zap.Error(err))
+		return 0
+	}
+
+	deferred := map[string]time.Time{}
+	for _, msg := range msgs {
+		if ctx.Err() != nil {
+			// аренда истечёт, и события возьмёт следующий цикл
+			break
+		}
+		log := r.Logger.With(
+			zap.Int64("seq", msg.Seq),
+			zap.String("event_id", msg.Event.ID),
+			zap.String("type", msg.Event.Type),
+		)
+
+		if next, ok := deferred[msg.Event.UserID]; ok {
+			r.retry(ctx, msg, next, "waiting for an earlier event of the user", log)
+			continue
+		}
+
+		if err := r.Sink.Publish(ctx, msg.Event); err != nil {
+			metrics.OutboxPublishErrors.Inc()
+			next := time.Now().Add(Backoff(msg.Attempts-1, r.backoffBase, r.backoffMax))
+			deferred[msg.Event.UserID] = next
+			log.Warn("failed to publish outbox event",
+				zap.Int("attempts", msg.Attempts),
+				zap.Time("next_attempt_at", next),
+				zap.Error(err),
+			)
+			r.retry(ctx, msg, next, err.Error(), log)
+			continue
+		}
+
+		metrics.OutboxPublished.WithLabelValues(msg.Event.Type).Inc()
+		dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dbTimeout)
+		if err := r.Store.MarkOutboxPublished(dbCtx, msg.Seq, log); err != nil {
+			// событие уйдёт повторно после истечения аренды
+			log.Error("failed to mark outbox event published", zap.Error(err))
+		}
+		cancel()
+	}
+	return len(msgs)
+}
+
+func (r *Relay) retry(ctx context.Context, msg Message, next time.Time, reason string, log *zap.Logger) {
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dbTimeout)
+	defer cancel()
+	if err := r.Store.RetryOutbox(dbCtx, msg.Seq, next, reason, log); err != nil {
+		log.Error("failed to schedule outbox retry", zap.Error(err))
+	}
+}
+
+// Backoff возвращает задержку base*2^attempt, ограниченную ceiling,
+// со случайным разбросом в пределах её второй половины.
+func Backoff(attempt int, base, ceiling time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 0; i < attempt && d < ceiling; i++ {
+		d *= 2
+	}
+	if ceiling > 0 && d > ceiling {
+		d = ceiling
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
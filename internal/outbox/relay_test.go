@@ -0,0 +1,189 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+	"go-musthave-diploma-tpl/internal/outbox/outboxtest"
+	"go-musthave-diploma-tpl/internal/repository/memory"
+	"go-musthave-diploma-tpl/internal/storage"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+var relayConfig = &config.Config{
+	AccrualWorkerID:    "relay-1",
+	OutboxBatchSize:    10,
+	OutboxPollInterval: 10 * time.Millisecond,
+	OutboxLease:        time.Minute,
+	OutboxBackoffBase:  time.Second,
+	OutboxBackoffMax:   time.Minute,
+}
+
+type retry struct {
+	seq  int64
+	next time.Time
+}
+
+// fakeStore отдаёт заранее заданные события и записывает результаты доставки.
+type fakeStore struct {
+	mu        sync.Mutex
+	pending   []outbox.Message
+	published []int64
+	retried   []retry
+}
+
+func (s *fakeStore) ClaimOutbox(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]outbox.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := s.pending
+	s.pending = nil
+	return msgs, nil
+}
+
+func (s *fakeStore) MarkOutboxPublished(ctx context.Context, seq int64, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published = append(s.published, seq)
+	return nil
+}
+
+func (s *fakeStore) RetryOutbox(ctx context.Context, seq int64, nextAttemptAt time.Time, lastErr string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retried = append(s.retried, retry{seq, nextAttemptAt})
+	return nil
+}
+
+// sinkFunc превращает функцию в outbox.Sink.
+type sinkFunc func(ev outbox.Event) error
+
+func (f sinkFunc) Publish(_ context.Context, ev outbox.Event) error {
+	return f(ev)
+}
+
+func message(seq int64, userID string) outbox.Message {
+	return outbox.Message{
+		Seq:      seq,
+		Attempts: 1,
+		Event:    outbox.NewUserRegistered(userID, outbox.UserRegistered{Login: userID}),
+	}
+}
+
+func TestRelayFlush(t *testing.T) {
+	tests := []struct {
+		name          string
+		failUser      string
+		wantPublished []int64
+		wantRetried   []int64
+	}{
+		{
+			name:          "#1 доставленные события помечаются опубликованными",
+			wantPublished: []int64{1, 2, 3},
+		},
+		{
+			name:          "#2 ошибка приёмника откладывает событие и следующие события того же пользователя",
+			failUser:      "alice",
+			wantPublished: []int64{2},
+			wantRetried:   []int64{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{pending: []outbox.Message{
+				message(1, "alice"),
+				message(2, "bob"),
+				message(3, "alice"),
+			}}
+			sink := sinkFunc(func(ev outbox.Event) error {
+				if ev.UserID == tt.failUser {
+					return errors.New("sink is down")
+				}
+				return nil
+			})
+
+			relay := outbox.NewRelay(store, sink, relayConfig, zaptest.NewLogger(t))
+			assert.Equal(t, 3, relay.Flush(context.Background()))
+
+			assert.Equal(t, tt.wantPublished, store.published)
+			var retried []int64
+			for _, r := range store.retried {
+				retried = append(retried, r.seq)
+				assert.True(t, r.next.After(time.Now()), "повтор откладывается")
+			}
+			assert.Equal(t, tt.wantRetried, retried)
+			if len(store.retried) == 2 {
+				assert.Equal(t, store.retried[0].next, store.retried[1].next,
+					"следующее событие пользователя не обгоняет отложенное")
+			}
+		})
+	}
+}
+
+func TestRelayDeliversFromStore(t *testing.T) {
+	s := storage.NewMemory(memory.New())
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	userID, err := s.Users.CreateUser(ctx, "alice", "hash", logger)
+	require.NoError(t, err)
+	require.NoError(t, s.Ledger.Post(ctx, domain.Posting{
+		Kind:      domain.LedgerKindAccrual,
+		Reference: "order-1",
+		UserID:    userID,
+		From:      domain.AccountAccrualSource,
+		To:        domain.AccountUserPoints,
+		Amount:    decimal.NewFromInt(10),
+	}, logger))
+	require.NoError(t, s.Withdrawals.Withdraw(ctx, userID, "2377225624", decimal.NewFromInt(3), logger))
+
+	broker := &outboxtest.Broker{}
+	calls := 0
+	broker.Fail = func(string, []byte) error {
+		calls++
+		if calls == 1 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}
+	cfg := *relayConfig
+	cfg.OutboxBackoffBase = time.Microsecond
+	cfg.OutboxBackoffMax = time.Microsecond
+	relay := outbox.NewRelay(s.Outbox, outbox.NewBrokerSink(broker, "events"), &cfg, logger)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(runCtx)
+	}()
+	require.Eventually(t, func() bool { return len(broker.Messages()) == 2 }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	var types []string
+	for _, m := range broker.Messages() {
+		var ev outbox.Event
+		require.NoError(t, json.Unmarshal(m.Value, &ev))
+		assert.Equal(t, userID, string(m.Key))
+		types = append(types, ev.Type)
+	}
+	// первая попытка не удалась, но порядок событий пользователя сохранён
+	assert.Equal(t, []string{outbox.TypeUserRegistered, outbox.TypeWithdrawalCreated}, types)
+
+	msgs, err := s.Outbox.ClaimOutbox(ctx, "relay-2", 10, time.Minute, logger)
+	require.NoError(t, err)
+	assert.Empty(t, msgs, "доставленные события не выдаются повторно")
+}
@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink — внешний приёмник событий. Ошибка Publish означает, что событие
+// не принято, и relay повторит доставку позже.
+type Sink interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+// Fanout доставляет событие во все приёмники по очереди. Ошибка хотя бы
+// одного приводит к повтору для всех, поэтому приёмники получают дубликаты.
+type Fanout []Sink
+
+func (f Fanout) Publish(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, s := range f {
+		if err := s.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close закрывает приёмники, которые держат ресурсы, например файлы.
+func (f Fanout) Close() error {
+	var errs []error
+	for _, s := range f {
+		if c, ok := s.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseSinks собирает приёмники из списка через запятую:
+// stdout, file:<путь> (NDJSON) или http(s)://<url> (вебхук).
+func ParseSinks(spec string, webhookTimeout time.Duration) (Fanout, error) {
+	var sinks Fanout
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		switch {
+		case s == "":
+			continue
+		case s == "stdout":
+			sinks = append(sinks, NewWriterSink(os.Stdout))
+		case strings.HasPrefix(s, "file:"):
+			fs, err := OpenFileSink(strings.TrimPrefix(s, "file:"))
+			if err != nil {
+				sinks.Close()
+				return nil, err
+			}
+			sinks = append(sinks, fs)
+		case strings.HasPrefix(s, "http://"), strings.HasPrefix(s, "https://"):
+			sinks = append(sinks, NewWebhookSink(s, webhookTimeout))
+		default:
+			sinks.Close()
+			return nil, fmt.Errorf("unknown outbox sink %q", s)
+		}
+	}
+	return sinks, nil
+}
+
+// WriterSink пишет события как NDJSON: один JSON-конверт на строку.
+type WriterSink struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// OpenFileSink открывает файл path на дозапись. Каждое событие
+// сбрасывается на диск до подтверждения доставки.
+func OpenFileSink(path string) (*WriterSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{w: f, file: f}, nil
+}
+
+func (s *WriterSink) Publish(_ context.Context, ev Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return err
+	}
+	if s.file != nil {
+		return s.file.Sync()
+	}
+	return nil
+}
+
+func (s *WriterSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// WebhookSink отправляет конверт события POST-запросом на url.
+// Доставка подтверждается любым ответом 2xx.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", ev.ID)
+	req.Header.Set("X-Event-Type", ev.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// Producer — клиент брокера сообщений: Kafka, NATS и т.п. Produce
+// возвращается, когда брокер подтвердил запись.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// BrokerSink публикует конверты в topic с ключом user_id, чтобы события
+// одного пользователя попадали в одну партицию.
+type BrokerSink struct {
+	producer Producer
+	topic    string
+}
+
+func NewBrokerSink(producer Producer, topic string) *BrokerSink {
+	return &BrokerSink{producer: producer, topic: topic}
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, ev Event) error {
+	value, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.producer.Produce(ctx, s.topic, []byte(ev.UserID), value)
+}
@@ -0,0 +1,138 @@
+package outbox_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+	"go-musthave-diploma-tpl/internal/outbox/outboxtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "#1 2xx подтверждает доставку", status: http.StatusAccepted},
+		{name: "#2 5xx — повтор", status: http.StatusBadGateway, wantErr: true},
+		{name: "#3 4xx — тоже повтор", status: http.StatusBadRequest, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := outbox.NewUserRegistered("user-1", outbox.UserRegistered{Login: "alice"})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, ev.ID, r.Header.Get("X-Event-ID"))
+				assert.Equal(t, outbox.TypeUserRegistered, r.Header.Get("X-Event-Type"))
+
+				var got outbox.Event
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				assert.Equal(t, ev.ID, got.ID)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := outbox.NewWebhookSink(srv.URL, time.Second).Publish(context.Background(), ev)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFileSinkAppendsNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	first := outbox.NewUserRegistered("user-1", outbox.UserRegistered{Login: "alice"})
+	second := outbox.NewUserRegistered("user-2", outbox.UserRegistered{Login: "bob"})
+
+	for _, ev := range []outbox.Event{first, second} {
+		// файл переоткрывается: события дописываются, а не перезаписываются
+		sink, err := outbox.OpenFileSink(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Publish(context.Background(), ev))
+		require.NoError(t, sink.Close())
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var ids []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var line struct {
+			ID     string `json:"id"`
+			Schema string `json:"schema"`
+		}
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &line))
+		assert.Equal(t, outbox.SchemaID(outbox.TypeUserRegistered, 1), line.Schema)
+		ids = append(ids, line.ID)
+	}
+	require.NoError(t, sc.Err())
+	assert.Equal(t, []string{first.ID, second.ID}, ids)
+}
+
+func TestBrokerSink(t *testing.T) {
+	broker := &outboxtest.Broker{}
+	sink := outbox.NewBrokerSink(broker, "gophermart.events")
+	ev := outbox.NewUserRegistered("user-1", outbox.UserRegistered{Login: "alice"})
+
+	broker.Fail = func(string, []byte) error { return errors.New("broker unavailable") }
+	require.Error(t, sink.Publish(context.Background(), ev))
+	assert.Empty(t, broker.Messages())
+
+	broker.Fail = nil
+	require.NoError(t, sink.Publish(context.Background(), ev))
+	msgs := broker.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "gophermart.events", msgs[0].Topic)
+	assert.Equal(t, []byte("user-1"), msgs[0].Key)
+
+	var got outbox.Event
+	require.NoError(t, json.Unmarshal(msgs[0].Value, &got))
+	assert.Equal(t, ev.ID, got.ID)
+}
+
+func TestParseSinks(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{name: "#1 пусто — внешних приёмников нет", spec: "", want: 0},
+		{name: "#2 все виды приёмников", spec: "stdout, file:" + filepath.Join(dir, "e.ndjson") + ",https://hooks.example.com/gophermart", want: 3},
+		{name: "#3 неизвестный приёмник", spec: "stdout,kafka://localhost:9092", wantErr: true},
+		{name: "#4 файл в несуществующем каталоге", spec: "file:" + filepath.Join(dir, "missing", "e.ndjson"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks, err := outbox.ParseSinks(tt.spec, time.Second)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, sinks, tt.want)
+			assert.NoError(t, sinks.Close())
+		})
+	}
+}
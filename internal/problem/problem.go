@@ -0,0 +1,180 @@
+// Package problem отдаёт ошибки в формате RFC 7807 (application/problem+json).
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const ContentType = "application/problem+json"
+
+const typePrefix = "urn:gophermart:problem:"
+
+// Details — тело ответа об ошибке.
+type Details struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      apperr.Code `json:"code"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+var statuses = map[apperr.Code]int{
+	apperr.CodeInvalidRequest:           http.StatusBadRequest,
+	apperr.CodeInvalidQuery:             http.StatusBadRequest,
+	apperr.CodeInvalidCursor:            http.StatusBadRequest,
+	apperr.CodeBodyTooLarge:             http.StatusRequestEntityTooLarge,
+	apperr.CodeCredentialsRequired:      http.StatusBadRequest,
+	apperr.CodePasswordTooShort:         http.StatusBadRequest,
+	apperr.CodePasswordEqualsLogin:      http.StatusBadRequest,
+	apperr.CodeLoginTaken:               http.StatusConflict,
+	apperr.CodeInvalidCredentials:       http.StatusUnauthorized,
+	apperr.CodeUnauthorized:             http.StatusUnauthorized,
+	apperr.CodeInvalidToken:             http.StatusUnauthorized,
+	apperr.CodeSessionRevoked:           http.StatusUnauthorized,
+	apperr.CodeInvalidRefreshToken:      http.StatusUnauthorized,
+	apperr.CodeOrderNumberRequired:      http.StatusBadRequest,
+	apperr.CodeInvalidOrderNumber:       http.StatusUnprocessableEntity,
+	apperr.CodeOrderOwnedByOtherUser:    http.StatusConflict,
+	apperr.CodeOrderAlreadyUploaded:     http.StatusOK, // повторная загрузка своего заказа не ошибка
+	apperr.CodeOrderBatchTooLarge:       http.StatusRequestEntityTooLarge,
+	apperr.CodeOrderNotFound:            http.StatusNotFound,
+	apperr.CodeNotEnoughFunds:           http.StatusPaymentRequired,
+	apperr.CodeInvalidWithdrawalSum:     http.StatusUnprocessableEntity,
+	apperr.CodeInvalidWebhookURL:        http.StatusUnprocessableEntity,
+	apperr.CodeWebhookURLNotAllowed:     http.StatusUnprocessableEntity,
+	apperr.CodeInvalidWebhookEventType:  http.StatusUnprocessableEntity,
+	apperr.CodeInvalidWebhookSecret:     http.StatusUnprocessableEntity,
+	apperr.CodeWebhookNotFound:          http.StatusNotFound,
+	apperr.CodeWebhookDeliveryNotFound:  http.StatusNotFound,
+	apperr.CodeIdempotencyKeyTooLong:    http.StatusBadRequest,
+	apperr.CodeIdempotencyKeyMismatch:   http.StatusUnprocessableEntity,
+	apperr.CodeIdempotencyKeyInProgress: http.StatusConflict,
+	apperr.CodeInternal:                 http.StatusInternalServerError,
+}
+
+const (
+	langEN = "en"
+	langRU = "ru"
+)
+
+var titles = map[string]map[apperr.Code]string{
+	langEN: {
+		apperr.CodeInvalidRequest:           "Malformed request",
+		apperr.CodeInvalidQuery:             "Invalid query parameter",
+		apperr.CodeInvalidCursor:            "Invalid or expired page cursor",
+		apperr.CodeBodyTooLarge:             "Request body is too large",
+		apperr.CodeCredentialsRequired:      "Login and password are required",
+		apperr.CodePasswordTooShort:         "Password is too short",
+		apperr.CodePasswordEqualsLogin:      "Password must differ from login",
+		apperr.CodeLoginTaken:               "Login is already taken",
+		apperr.CodeInvalidCredentials:       "Invalid login or password",
+		apperr.CodeUnauthorized:             "Authentication required",
+		apperr.CodeInvalidToken:             "Invalid access token",
+		apperr.CodeSessionRevoked:           "Session has been revoked",
+		apperr.CodeInvalidRefreshToken:      "Invalid refresh token",
+		apperr.CodeOrderNumberRequired:      "Order number is required",
+		apperr.CodeInvalidOrderNumber:       "Invalid order number",
+		apperr.CodeOrderOwnedByOtherUser:    "Order was uploaded by another user",
+		apperr.CodeOrderAlreadyUploaded:     "Order has already been uploaded",
+		apperr.CodeOrderBatchTooLarge:       "Too many order numbers in one batch",
+		apperr.CodeOrderNotFound:            "Order not found",
+		apperr.CodeNotEnoughFunds:           "Not enough points",
+		apperr.CodeInvalidWithdrawalSum:     "Withdrawal sum must be positive with at most two decimal places",
+		apperr.CodeInvalidWebhookURL:        "Webhook URL must be an absolute http or https URL",
+		apperr.CodeWebhookURLNotAllowed:     "Webhook URL must point to a public address",
+		apperr.CodeInvalidWebhookEventType:  "Unknown or unsupported webhook event type",
+		apperr.CodeInvalidWebhookSecret:     "Webhook secret is too short",
+		apperr.CodeWebhookNotFound:          "Webhook subscription not found",
+		apperr.CodeWebhookDeliveryNotFound:  "Webhook delivery not found",
+		apperr.CodeIdempotencyKeyTooLong:    "Idempotency key is too long",
+		apperr.CodeIdempotencyKeyMismatch:   "Idempotency key reused with a different payload",
+		apperr.CodeIdempotencyKeyInProgress: "Request with this idempotency key is in progress",
+		apperr.CodeInternal:                 "Internal server error",
+	},
+	langRU: {
+		apperr.CodeInvalidRequest:           "Некорректный запрос",
+		apperr.CodeInvalidQuery:             "Некорректный параметр запроса",
+		apperr.CodeInvalidCursor:            "Некорректный курсор страницы",
+		apperr.CodeBodyTooLarge:             "Слишком большое тело запроса",
+		apperr.CodeCredentialsRequired:      "Нужно указать логин и пароль",
+		apperr.CodePasswordTooShort:         "Слишком короткий пароль",
+		apperr.CodePasswordEqualsLogin:      "Пароль не должен совпадать с логином",
+		apperr.CodeLoginTaken:               "Логин уже занят",
+		apperr.CodeInvalidCredentials:       "Неверный логин или пароль",
+		apperr.CodeUnauthorized:             "Требуется авторизация",
+		apperr.CodeInvalidToken:             "Недействительный токен доступа",
+		apperr.CodeSessionRevoked:           "Сессия отозвана",
+		apperr.CodeInvalidRefreshToken:      "Недействительный refresh-токен",
+		apperr.CodeOrderNumberRequired:      "Нужно указать номер заказа",
+		apperr.CodeInvalidOrderNumber:       "Неверный номер заказа",
+		apperr.CodeOrderOwnedByOtherUser:    "Заказ загружен другим пользователем",
+		apperr.CodeOrderAlreadyUploaded:     "Заказ уже загружен",
+		apperr.CodeOrderBatchTooLarge:       "Слишком много номеров заказов в одной пачке",
+		apperr.CodeOrderNotFound:            "Заказ не найден",
+		apperr.CodeNotEnoughFunds:           "Недостаточно баллов",
+		apperr.CodeInvalidWithdrawalSum:     "Сумма списания должна быть положительной и не точнее копеек",
+		apperr.CodeInvalidWebhookURL:        "URL вебхука должен быть абсолютным http- или https-адресом",
+		apperr.CodeWebhookURLNotAllowed:     "URL вебхука должен вести на публичный адрес",
+		apperr.CodeInvalidWebhookEventType:  "Неизвестный или неподдерживаемый тип события вебхука",
+		apperr.CodeInvalidWebhookSecret:     "Слишком короткий секрет вебхука",
+		apperr.CodeWebhookNotFound:          "Подписка на вебхук не найдена",
+		apperr.CodeWebhookDeliveryNotFound:  "Доставка вебхука не найдена",
+		apperr.CodeIdempotencyKeyTooLong:    "Слишком длинный ключ идемпотентности",
+		apperr.CodeIdempotencyKeyMismatch:   "Ключ идемпотентности использован с другим запросом",
+		apperr.CodeIdempotencyKeyInProgress: "Запрос с этим ключом идемпотентности ещё выполняется",
+		apperr.CodeInternal:                 "Внутренняя ошибка сервера",
+	},
+}
+
+// Status возвращает HTTP-статус для кода ошибки.
+func Status(code apperr.Code) int {
+	if s, ok := statuses[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Write отдаёт err как problem+json. Ошибки без кода считаются внутренними,
+// и их текст клиенту не показывается.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	code := apperr.CodeOf(err)
+	status := Status(code)
+
+	p := Details{
+		Type:      typePrefix + string(code),
+		Title:     titles[language(r)][code],
+		Status:    status,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+	if code != apperr.CodeInternal {
+		p.Detail = err.Error()
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// language выбирает язык заголовка по Accept-Language; по умолчанию английский.
+// Веса q не учитываются: берётся первый поддерживаемый язык из списка.
+func language(r *http.Request) string {
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(tag, "-")
+		primary = strings.ToLower(primary)
+		if _, ok := titles[primary]; ok {
+			return primary
+		}
+	}
+	return langEN
+}
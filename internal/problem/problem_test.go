@@ -0,0 +1,139 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	errNotEnoughFunds := apperr.New(apperr.CodeNotEnoughFunds, "not enough funds")
+
+	tests := []struct {
+		name           string
+		err            error
+		acceptLanguage string
+		want           Details
+	}{
+		{
+			name: "#1 domain error",
+			err:  errNotEnoughFunds,
+			want: Details{
+				Type:     "urn:gophermart:problem:not_enough_funds",
+				Title:    "Not enough points",
+				Status:   http.StatusPaymentRequired,
+				Detail:   "not enough funds",
+				Instance: "/api/user/balance/withdraw",
+				Code:     apperr.CodeNotEnoughFunds,
+			},
+		},
+		{
+			name:           "#2 wrapped error with russian title",
+			err:            fmt.Errorf("withdraw: %w", errNotEnoughFunds),
+			acceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8",
+			want: Details{
+				Type:     "urn:gophermart:problem:not_enough_funds",
+				Title:    "Недостаточно баллов",
+				Status:   http.StatusPaymentRequired,
+				Detail:   "withdraw: not enough funds",
+				Instance: "/api/user/balance/withdraw",
+				Code:     apperr.CodeNotEnoughFunds,
+			},
+		},
+		{
+			name:           "#3 unknown error does not leak details",
+			err:            errors.New("pq: connection refused"),
+			acceptLanguage: "de-DE",
+			want: Details{
+				Type:     "urn:gophermart:problem:internal_error",
+				Title:    "Internal server error",
+				Status:   http.StatusInternalServerError,
+				Instance: "/api/user/balance/withdraw",
+				Code:     apperr.CodeInternal,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+
+			middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Write(w, r, tt.err)
+			})).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want.Status, w.Code)
+			assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+			var got Details
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.NotEmpty(t, got.RequestID)
+			got.RequestID = ""
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Каждому коду нужен статус и заголовок на всех языках, иначе клиент получит 500 или пустой title.
+func TestCodesAreComplete(t *testing.T) {
+	codes := declaredCodes(t)
+	require.NotEmpty(t, codes)
+
+	for _, code := range codes {
+		assert.Contains(t, statuses, code, "missing status for %s", code)
+	}
+	assert.Len(t, statuses, len(codes), "statuses contain undeclared codes")
+	for lang, byCode := range titles {
+		for _, code := range codes {
+			assert.NotEmpty(t, byCode[code], "missing %s title for %s", lang, code)
+		}
+		assert.Len(t, byCode, len(codes), "titles[%s] contain undeclared codes", lang)
+	}
+}
+
+// declaredCodes собирает все константы типа apperr.Code из исходника
+// пакета apperr, чтобы новый код не мог обойти проверку.
+func declaredCodes(t *testing.T) []apperr.Code {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "../apperr/apperr.go", nil, 0)
+	require.NoError(t, err)
+
+	var codes []apperr.Code
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if typ, ok := vs.Type.(*ast.Ident); !ok || typ.Name != "Code" {
+				continue
+			}
+			for _, v := range vs.Values {
+				lit, ok := v.(*ast.BasicLit)
+				require.True(t, ok && lit.Kind == token.STRING, "code must be a string literal")
+				value, err := strconv.Unquote(lit.Value)
+				require.NoError(t, err)
+				codes = append(codes, apperr.Code(value))
+			}
+		}
+	}
+	return codes
+}
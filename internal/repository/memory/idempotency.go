@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+type idempotencyKey struct {
+	userID, endpoint, key string
+}
+
+// Reserve занимает ключ для нового запроса. Если ключ уже занят и не истёк,
+// возвращается копия существующей записи, а reserved == false.
+func (s *Store) Reserve(ctx context.Context, userID, endpoint, key, requestHash string, ttl time.Duration, logger *zap.Logger) (*domain.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	k := idempotencyKey{userID, endpoint, key}
+	if rec, ok := s.idempotency[k]; ok && !rec.ExpiresAt.Before(now) {
+		res := *rec
+		return &res, false, nil
+	}
+
+	rec := &domain.IdempotencyRecord{RequestHash: requestHash, ExpiresAt: now.Add(ttl)}
+	s.idempotency[k] = rec
+	res := *rec
+	return &res, true, nil
+}
+
+// Complete сохраняет ответ, который будет воспроизводиться для повторов.
+func (s *Store) Complete(ctx context.Context, userID, endpoint, key string, statusCode int, contentType string, body []byte, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.idempotency[idempotencyKey{userID, endpoint, key}]; ok {
+		rec.StatusCode, rec.ContentType = statusCode, contentType
+		rec.ResponseBody = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+// Release освобождает ключ, чтобы клиент мог повторить неудавшийся запрос.
+func (s *Store) Release(ctx context.Context, userID, endpoint, key string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotency, idempotencyKey{userID, endpoint, key})
+	return nil
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком хранения.
+func (s *Store) DeleteExpired(ctx context.Context, logger *zap.Logger) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var n int64
+	for k, rec := range s.idempotency {
+		if rec.ExpiresAt.Before(now) {
+			delete(s.idempotency, k)
+			n++
+		}
+	}
+	return n, nil
+}
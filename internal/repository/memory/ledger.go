@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type postingKey struct {
+	kind, reference string
+}
+
+type withdrawalKey struct {
+	userID, orderNumber string
+}
+
+// Post проводит posting.
+func (s *Store) Post(ctx context.Context, p domain.Posting, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.post(p); err != nil {
+		logger.Warn("ledger posting rejected",
+			zap.String("kind", p.Kind),
+			zap.String("reference", p.Reference),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+func (s *Store) GetBalance(ctx context.Context, userID string, logger *zap.Logger) (current, withdrawn decimal.Decimal, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.balances[userID]
+	if !ok {
+		return decimal.Zero, decimal.Zero, nil
+	}
+	return b.current, b.withdrawn, nil
+}
+
+// ListEntries возвращает все записи журнала пользователя в порядке проводки.
+func (s *Store) ListEntries(ctx context.Context, userID string, logger *zap.Logger) ([]domain.LedgerEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []domain.LedgerEntry
+	for _, e := range s.entries {
+		if e.UserID == userID {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// Reconcile сверяет балансы с журналом и возвращает пользователей с расхождением.
+func (s *Store) Reconcile(ctx context.Context, logger *zap.Logger) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sums := map[string]*balance{}
+	for _, e := range s.entries {
+		b, ok := sums[e.UserID]
+		if !ok {
+			b = &balance{}
+			sums[e.UserID] = b
+		}
+		switch e.Account {
+		case domain.AccountUserPoints:
+			b.current = b.current.Add(e.Amount)
+		case domain.AccountWithdrawalSink:
+			b.withdrawn = b.withdrawn.Add(e.Amount)
+		}
+	}
+
+	var mismatched []string
+	for userID, b := range s.balances {
+		l, ok := sums[userID]
+		if !ok {
+			l = &balance{}
+		}
+		if !b.current.Equal(l.current) || !b.withdrawn.Equal(l.withdrawn) {
+			mismatched = append(mismatched, userID)
+		}
+	}
+	if len(mismatched) > 0 {
+		logger.Warn("ledger balance mismatch", zap.Strings("users", mismatched))
+	}
+	return mismatched, nil
+}
+
+// post записывает транзакцию, две её записи и обновляет баланс; вызывается под s.mu.
+func (s *Store) post(p domain.Posting) error {
+	if !p.Amount.IsPositive() || p.From == p.To || p.UserID == "" {
+		return domain.ErrInvalidPosting
+	}
+	key := postingKey{p.Kind, p.Reference}
+	if _, ok := s.transactions[key]; ok {
+		return domain.ErrPostingExists
+	}
+
+	txID := newID()
+	s.transactions[key] = txID
+	amount := numeric(p.Amount)
+	now := s.timestamp()
+	for _, leg := range []struct {
+		account string
+		amount  decimal.Decimal
+	}{{p.From, amount.Neg()}, {p.To, amount}} {
+		s.entries = append(s.entries, domain.LedgerEntry{
+			ID:            int64(len(s.entries) + 1),
+			TransactionID: txID,
+			Kind:          p.Kind,
+			Reference:     p.Reference,
+			Account:       leg.account,
+			UserID:        p.UserID,
+			Amount:        leg.amount,
+			CreatedAt:     now,
+		})
+	}
+
+	b, ok := s.balances[p.UserID]
+	if !ok {
+		b = &balance{}
+		s.balances[p.UserID] = b
+	}
+	switch p.To {
+	case domain.AccountUserPoints:
+		b.current = b.current.Add(amount)
+	case domain.AccountWithdrawalSink:
+		b.withdrawn = b.withdrawn.Add(amount)
+	}
+	switch p.From {
+	case domain.AccountUserPoints:
+		b.current = b.current.Sub(amount)
+	case domain.AccountWithdrawalSink:
+		b.withdrawn = b.withdrawn.Sub(amount)
+	}
+	return nil
+}
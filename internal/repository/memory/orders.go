@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func (s *Store) CreateOrder(ctx context.Context, userID, number string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ordersByNumber[number]; ok {
+		logger.Warn("order already exists", zap.String("number", number))
+		return domain.ErrOrderExists
+	}
+	s.insertOrder(userID, number)
+	logger.Info("order created", zap.String("number", number))
+	return nil
+}
+
+// CreateOrders загружает пачку номеров. Для каждого номера возвращается
+// nil, ErrOrderUploadedByUser или ErrOrderExists.
+func (s *Store) CreateOrders(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make(map[string]error, len(numbers))
+	for _, number := range numbers {
+		if _, seen := results[number]; seen {
+			continue
+		}
+		id, ok := s.ordersByNumber[number]
+		switch {
+		case !ok:
+			s.insertOrder(userID, number)
+			results[number] = nil
+		case s.orders[id].UserID == userID:
+			results[number] = domain.ErrOrderUploadedByUser
+		default:
+			results[number] = domain.ErrOrderExists
+		}
+	}
+	logger.Info("order batch uploaded", zap.Int("numbers", len(numbers)))
+	return results, nil
+}
+
+func (s *Store) insertOrder(userID, number string) {
+	now := s.timestamp()
+	o := &order{
+		Order: domain.Order{
+			ID:         newID(),
+			Number:     number,
+			UserID:     userID,
+			Status:     domain.OrderStatusNew,
+			UploadedAt: now,
+		},
+		nextPollAt: now,
+	}
+	s.orders[o.ID] = o
+	s.ordersByNumber[number] = o.ID
+}
+
+func (s *Store) GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error) {
+	return s.ListOrders(ctx, userID, domain.ListQuery{}, logger)
+}
+
+// ListOrders возвращает заказы пользователя с фильтрами и keyset-пагинацией по (uploaded_at, id).
+func (s *Store) ListOrders(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var orders []domain.Order
+	for _, o := range s.orders {
+		if o.UserID != userID || len(q.Statuses) > 0 && !containsStatus(q.Statuses, o.Status) {
+			continue
+		}
+		orders = append(orders, publicOrder(o))
+	}
+	return page(orders, q, func(o domain.Order) (time.Time, string) { return o.UploadedAt, o.ID }), nil
+}
+
+// GetOrderByNumber возвращает заказ пользователя по номеру; чужой заказ даёт ErrOrderNotFound.
+func (s *Store) GetOrderByNumber(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.ordersByNumber[number]
+	if !ok || s.orders[id].UserID != userID {
+		return domain.Order{}, domain.ErrOrderNotFound
+	}
+	return publicOrder(s.orders[id]), nil
+}
+
+func (s *Store) ListStatusHistory(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]domain.StatusHistoryEntry(nil), s.history[orderID]...), nil
+}
+
+// ListOrderEvents возвращает события пользователя с ID больше afterID по возрастанию ID.
+func (s *Store) ListOrderEvents(ctx context.Context, userID string, afterID int64, logger *zap.Logger) ([]domain.OrderEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []domain.OrderEvent
+	for _, ev := range s.events {
+		if ev.UserID == userID && ev.ID > afterID {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+// ClaimOrdersForProcessing берёт в аренду до limit необработанных заказов,
+// пропуская заказы с действующей арендой другого воркера.
+func (s *Store) ClaimOrdersForProcessing(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var ready []*order
+	for _, o := range s.orders {
+		if o.Status.Terminal() || o.nextPollAt.After(now) {
+			continue
+		}
+		if !o.lockedUntil.IsZero() && !o.lockedUntil.Before(now) {
+			continue
+		}
+		ready = append(ready, o)
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if !ready[i].nextPollAt.Equal(ready[j].nextPollAt) {
+			return ready[i].nextPollAt.Before(ready[j].nextPollAt)
+		}
+		return ready[i].UploadedAt.Before(ready[j].UploadedAt)
+	})
+	if len(ready) > limit {
+		ready = ready[:limit]
+	}
+
+	orders := make([]domain.Order, 0, len(ready))
+	for _, o := range ready {
+		o.lockedBy, o.lockedUntil = workerID, now.Add(lease)
+		orders = append(orders, o.Order)
+	}
+	return orders, nil
+}
+
+// ReleaseOrder снимает аренду, если она всё ещё принадлежит workerID.
+func (s *Store) ReleaseOrder(ctx context.Context, orderID, workerID string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o, ok := s.orders[orderID]; ok && o.lockedBy == workerID {
+		o.lockedBy, o.lockedUntil = "", time.Time{}
+	}
+	return nil
+}
+
+// ReleaseWorkerOrders снимает все аренды воркера.
+func (s *Store) ReleaseWorkerOrders(ctx context.Context, workerID string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range s.orders {
+		if o.lockedBy == workerID {
+			o.lockedBy, o.lockedUntil = "", time.Time{}
+		}
+	}
+	return nil
+}
+
+// ScheduleRetry откладывает следующий опрос заказа и увеличивает счётчик попыток.
+func (s *Store) ScheduleRetry(ctx context.Context, orderID string, nextPollAt time.Time, lastErr string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if o, ok := s.orders[orderID]; ok {
+		o.Attempts++
+		o.nextPollAt, o.lastError = nextPollAt, lastErr
+	}
+	return nil
+}
+
+// MarkOrderFailed переводит необработанный заказ в INVALID с сохранением причины.
+func (s *Store) MarkOrderFailed(ctx context.Context, orderID, lastErr string, logger *zap.Logger) error {
+	s.mu.Lock()
+	o, ok := s.orders[orderID]
+	if !ok || o.Status.Terminal() {
+		s.mu.Unlock()
+		return nil
+	}
+	change := domain.StatusChange{From: o.Status, Status: domain.OrderStatusInvalid, Source: domain.StatusSourceWorker, Reason: lastErr}
+	o.Status, o.lastError = domain.OrderStatusInvalid, lastErr
+	ev := s.recordChange(o, change)
+	s.mu.Unlock()
+
+	s.publish(ev)
+	logger.Warn("order polling gave up", zap.String("order_id", orderID), zap.String("reason", lastErr))
+	return nil
+}
+
+// UpdateOrderStatus переводит заказ из change.From в change.Status и проводит
+// начисление рассчитанного заказа. Если статус уже не change.From,
+// возвращается ErrStatusConflict.
+func (s *Store) UpdateOrderStatus(ctx context.Context, orderID string, change domain.StatusChange, logger *zap.Logger) error {
+	if !change.From.CanTransitionTo(change.Status) {
+		logger.Warn("order status transition not allowed",
+			zap.String("order_id", orderID),
+			zap.String("from", string(change.From)),
+			zap.String("to", string(change.Status)),
+		)
+		return domain.ErrTransitionNotAllowed
+	}
+
+	s.mu.Lock()
+	o, ok := s.orders[orderID]
+	if !ok || o.Status != change.From {
+		s.mu.Unlock()
+		logger.Warn("order status changed concurrently",
+			zap.String("order_id", orderID),
+			zap.String("expected", string(change.From)),
+			zap.String("to", string(change.Status)),
+		)
+		return domain.ErrStatusConflict
+	}
+
+	o.Status = change.Status
+	if change.Accrual != nil {
+		o.Accrual = decimal.NullDecimal{Decimal: numeric(*change.Accrual), Valid: true}
+	}
+	ev := s.recordChange(o, change)
+
+	if change.Status == domain.OrderStatusProcessed && change.Accrual != nil && change.Accrual.IsPositive() {
+		err := s.post(domain.Posting{
+			Kind:      domain.LedgerKindAccrual,
+			Reference: orderID,
+			UserID:    o.UserID,
+			From:      domain.AccountAccrualSource,
+			To:        domain.AccountUserPoints,
+			Amount:    *change.Accrual,
+		})
+		if err != nil && !errors.Is(err, domain.ErrPostingExists) {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Unlock()
+
+	s.publish(ev)
+	return nil
+}
+
+// GetBacklog возвращает число необработанных заказов по статусам и время загрузки самого старого.
+func (s *Store) GetBacklog(ctx context.Context, logger *zap.Logger) (map[string]int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	var oldest time.Time
+	for _, o := range s.orders {
+		if o.Status.Terminal() {
+			continue
+		}
+		counts[string(o.Status)]++
+		if oldest.IsZero() || o.UploadedAt.Before(oldest) {
+			oldest = o.UploadedAt
+		}
+	}
+	return counts, oldest, nil
+}
+
+// recordChange пишет переход в историю, поток событий и outbox; вызывается под s.mu.
+func (s *Store) recordChange(o *order, change domain.StatusChange) domain.OrderEvent {
+	now := s.timestamp()
+
+	entry := domain.StatusHistoryEntry{
+		OldStatus: change.From,
+		NewStatus: change.Status,
+		Source:    change.Source,
+		Response:  change.Response,
+		ChangedAt: now,
+	}
+	s.historySeq++
+	entry.ID = s.historySeq
+	if change.Accrual != nil {
+		entry.Accrual = decimal.NullDecimal{Decimal: numeric(*change.Accrual), Valid: true}
+	}
+	if change.Reason != "" {
+		entry.Reason.String, entry.Reason.Valid = change.Reason, true
+	}
+	s.history[o.ID] = append(s.history[o.ID], entry)
+
+	ev := domain.OrderEvent{
+		ID:        int64(len(s.events) + 1),
+		OrderID:   o.ID,
+		UserID:    o.UserID,
+		Number:    o.Number,
+		Status:    o.Status,
+		Accrual:   o.Accrual,
+		CreatedAt: now,
+	}
+	s.events = append(s.events, ev)
+	s.addOutbox(domain.OrderOutboxEvent(ev, change))
+	return ev
+}
+
+// publicOrder возвращает заказ в том виде, в каком его отдаёт список (без счётчика попыток).
+func publicOrder(o *order) domain.Order {
+	res := o.Order
+	res.Attempts = 0
+	return res
+}
+
+func containsStatus(statuses []domain.OrderStatus, s domain.OrderStatus) bool {
+	for _, st := range statuses {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
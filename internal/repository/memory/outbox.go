@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+type outboxRow struct {
+	outbox.Message
+	nextAttemptAt time.Time
+	lockedBy      string
+	lockedUntil   time.Time
+	lastError     string
+}
+
+// addOutbox ставит событие в очередь outbox; вызывается под s.mu
+// вместе с изменением, которое событие описывает.
+func (s *Store) addOutbox(ev outbox.Event) {
+	s.outboxSeq++
+	s.outbox = append(s.outbox, &outboxRow{
+		Message:       outbox.Message{Seq: s.outboxSeq, Event: ev},
+		nextAttemptAt: ev.OccurredAt,
+	})
+}
+
+// ClaimOutbox берёт в аренду до limit неопубликованных событий в порядке записи.
+func (s *Store) ClaimOutbox(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]outbox.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	var res []outbox.Message
+	for _, row := range s.outbox {
+		if len(res) == limit {
+			break
+		}
+		if row.nextAttemptAt.After(now) || row.lockedUntil.After(now) {
+			continue
+		}
+		row.lockedBy, row.lockedUntil = workerID, now.Add(lease)
+		row.Attempts++
+		res = append(res, row.Message)
+	}
+	return res, nil
+}
+
+// MarkOutboxPublished убирает доставленное событие из очереди:
+// в памяти опубликованные события не хранятся.
+func (s *Store) MarkOutboxPublished(ctx context.Context, seq int64, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, row := range s.outbox {
+		if row.Seq == seq {
+			s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// RetryOutbox снимает аренду и откладывает следующую доставку до nextAttemptAt.
+func (s *Store) RetryOutbox(ctx context.Context, seq int64, nextAttemptAt time.Time, lastErr string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, row := range s.outbox {
+		if row.Seq == seq {
+			row.nextAttemptAt, row.lastError = nextAttemptAt, lastErr
+			row.lockedBy, row.lockedUntil = "", time.Time{}
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+type session struct {
+	domain.Session
+	rotated bool
+	revoked bool
+}
+
+// CreateSession открывает новое семейство сессий для пользователя.
+func (s *Store) CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time, logger *zap.Logger) (*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := &session{Session: domain.Session{ID: newID(), UserID: userID, FamilyID: newID(), ExpiresAt: expiresAt}}
+	s.sessions[tokenHash] = sess
+	logger.Info("session created", zap.String("user_id", userID), zap.String("family_id", sess.FamilyID))
+	res := sess.Session
+	return &res, nil
+}
+
+// RotateSession обменивает refresh-токен на новый; повторное предъявление
+// обменянного токена отзывает всё семейство.
+func (s *Store) RotateSession(ctx context.Context, oldHash, newHash string, newExpiresAt time.Time, logger *zap.Logger) (*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.sessions[oldHash]
+	switch {
+	case !ok:
+		return nil, domain.ErrSessionNotFound
+	case old.revoked:
+		return nil, domain.ErrSessionRevoked
+	case old.rotated:
+		s.revokeFamily(old.FamilyID)
+		logger.Warn("refresh token reuse detected, session family revoked",
+			zap.String("user_id", old.UserID),
+			zap.String("family_id", old.FamilyID),
+		)
+		return nil, domain.ErrRefreshTokenReused
+	case !old.ExpiresAt.After(s.now()):
+		return nil, domain.ErrSessionExpired
+	}
+
+	old.rotated = true
+	next := &session{Session: domain.Session{ID: newID(), UserID: old.UserID, FamilyID: old.FamilyID, ExpiresAt: newExpiresAt}}
+	s.sessions[newHash] = next
+	res := next.Session
+	return &res, nil
+}
+
+// RevokeFamily отзывает все сессии семейства.
+func (s *Store) RevokeFamily(ctx context.Context, familyID string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeFamily(familyID)
+	logger.Info("session family revoked", zap.String("family_id", familyID))
+	return nil
+}
+
+// IsSessionActive сообщает, есть ли в семействе неотозванная и неистёкшая сессия.
+func (s *Store) IsSessionActive(ctx context.Context, familyID string, logger *zap.Logger) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, sess := range s.sessions {
+		if sess.FamilyID == familyID && !sess.revoked && sess.ExpiresAt.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) revokeFamily(familyID string) {
+	for _, sess := range s.sessions {
+		if sess.FamilyID == familyID {
+			sess.revoked = true
+		}
+	}
+}
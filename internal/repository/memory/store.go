@@ -0,0 +1,172 @@
+// Package memory — хранилище в памяти процесса с той же семантикой
+// уникальности и балансов, что и PostgreSQL. Подходит для демо и тестов,
+// данные теряются при перезапуске.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Store реализует все репозитории сразу; один мьютекс играет роль транзакции.
+type Store struct {
+	now func() time.Time
+
+	mu             sync.Mutex
+	users          map[string]*domain.User
+	usersByLogin   map[string]string
+	sessions       map[string]*session // по хешу токена
+	orders         map[string]*order
+	ordersByNumber map[string]string
+	history        map[string][]domain.StatusHistoryEntry
+	historySeq     int64
+	events         []domain.OrderEvent
+	withdrawals    []domain.Withdrawal
+	withdrawn      map[withdrawalKey]bool
+	transactions   map[postingKey]string
+	entries        []domain.LedgerEntry
+	balances       map[string]*balance
+	idempotency    map[idempotencyKey]*domain.IdempotencyRecord
+	outbox         []*outboxRow
+	outboxSeq      int64
+	webhooks       []*domain.WebhookSubscription
+	deliveries     []*webhookDelivery
+	deliverySeq    int64
+
+	listenersMu sync.Mutex
+	listeners   map[int]func(domain.OrderEvent)
+	listenerSeq int
+}
+
+type order struct {
+	domain.Order
+	nextPollAt  time.Time
+	lockedBy    string
+	lockedUntil time.Time
+	lastError   string
+}
+
+type balance struct {
+	current, withdrawn decimal.Decimal
+}
+
+func New() *Store {
+	return &Store{
+		now:            time.Now,
+		users:          map[string]*domain.User{},
+		usersByLogin:   map[string]string{},
+		sessions:       map[string]*session{},
+		orders:         map[string]*order{},
+		ordersByNumber: map[string]string{},
+		history:        map[string][]domain.StatusHistoryEntry{},
+		withdrawn:      map[withdrawalKey]bool{},
+		transactions:   map[postingKey]string{},
+		balances:       map[string]*balance{},
+		idempotency:    map[idempotencyKey]*domain.IdempotencyRecord{},
+		listeners:      map[int]func(domain.OrderEvent){},
+	}
+}
+
+// Listen передаёт в publish события заказов, записанные после подписки,
+// пока не отменён ctx. Так Store служит источником событий для SSE вместо LISTEN/NOTIFY.
+func (s *Store) Listen(ctx context.Context, publish func(domain.OrderEvent)) error {
+	s.listenersMu.Lock()
+	s.listenerSeq++
+	id := s.listenerSeq
+	s.listeners[id] = publish
+	s.listenersMu.Unlock()
+
+	<-ctx.Done()
+
+	s.listenersMu.Lock()
+	delete(s.listeners, id)
+	s.listenersMu.Unlock()
+	return nil
+}
+
+// publish рассылает события слушателям. Вызывается после снятия s.mu,
+// как NOTIFY после коммита.
+func (s *Store) publish(events ...domain.OrderEvent) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	for _, ev := range events {
+		for _, fn := range s.listeners {
+			fn(ev)
+		}
+	}
+}
+
+// timestamp возвращает текущее время с точностью timestamptz.
+func (s *Store) timestamp() time.Time {
+	return s.now().Truncate(time.Microsecond)
+}
+
+func newID() string {
+	return uuid.NewString()
+}
+
+// numeric приводит сумму к DECIMAL(12,2).
+func numeric(d decimal.Decimal) decimal.Decimal {
+	return d.Round(2)
+}
+
+// page применяет к отсортированным по ключу записям фильтр по времени,
+// keyset-курсор, направление и лимит из q, как ListQuery в PostgreSQL.
+func page[T any](items []T, q domain.ListQuery, key func(T) (time.Time, string)) []T {
+	less := func(a, b T) bool {
+		at, aid := key(a)
+		bt, bid := key(b)
+		if !at.Equal(bt) {
+			return at.Before(bt)
+		}
+		return aid < bid
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if q.Ascending {
+			return less(items[i], items[j])
+		}
+		return less(items[j], items[i])
+	})
+
+	res := make([]T, 0, len(items))
+	for _, it := range items {
+		at, id := key(it)
+		if !q.From.IsZero() && at.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !at.Before(q.To) {
+			continue
+		}
+		if q.After != nil {
+			cmp := at.Compare(q.After.At)
+			if cmp == 0 {
+				cmp = compareStrings(id, q.After.ID)
+			}
+			if q.Ascending && cmp <= 0 || !q.Ascending && cmp >= 0 {
+				continue
+			}
+		}
+		res = append(res, it)
+		if q.Limit > 0 && len(res) == q.Limit {
+			break
+		}
+	}
+	return res
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
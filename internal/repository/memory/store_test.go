@@ -0,0 +1,61 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/repository/memory"
+	"go-musthave-diploma-tpl/internal/storage"
+	"go-musthave-diploma-tpl/internal/storage/storagetest"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storage.Storage {
+		return storage.NewMemory(memory.New())
+	})
+}
+
+func TestStoreListenPublishesAfterChange(t *testing.T) {
+	store := memory.New()
+	s := storage.NewMemory(store)
+	logger := zaptest.NewLogger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan domain.OrderEvent, 1)
+	go store.Listen(ctx, func(ev domain.OrderEvent) { got <- ev })
+
+	userID, err := s.Users.CreateUser(ctx, "user", "hash", logger)
+	require.NoError(t, err)
+
+	// слушатель регистрируется асинхронно: рассчитываем новые заказы, пока событие не дойдёт
+	points := decimal.NewFromInt(5)
+	n := 0
+	require.Eventually(t, func() bool {
+		n++
+		number := fmt.Sprintf("order-%d", n)
+		require.NoError(t, s.Orders.CreateOrder(ctx, userID, number, logger))
+		o, err := s.Orders.GetOrderByNumber(ctx, userID, number, logger)
+		require.NoError(t, err)
+		require.NoError(t, s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+			From: domain.OrderStatusNew, Status: domain.OrderStatusProcessed, Accrual: &points,
+		}, logger))
+
+		select {
+		case ev := <-got:
+			assert.Equal(t, number, ev.Number)
+			assert.Equal(t, domain.OrderStatusProcessed, ev.Status)
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+func (s *Store) CreateUser(ctx context.Context, login, passwordHash string, logger *zap.Logger) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.usersByLogin[login]; ok {
+		logger.Warn("user already exists", zap.String("login", login))
+		return "", domain.ErrUserExists
+	}
+
+	u := &domain.User{ID: newID(), Login: login, PasswordHash: passwordHash}
+	s.users[u.ID] = u
+	s.usersByLogin[login] = u.ID
+	s.addOutbox(outbox.NewUserRegistered(u.ID, outbox.UserRegistered{Login: login}))
+	logger.Info("user created", zap.String("id", u.ID))
+	return u.ID, nil
+}
+
+func (s *Store) GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*domain.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.usersByLogin[login]
+	if !ok {
+		logger.Warn("failed to find user by login", zap.String("login", login))
+		return nil, domain.ErrUserNotFound
+	}
+	u := *s.users[id]
+	return &u, nil
+}
@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+type webhookDelivery struct {
+	domain.WebhookDelivery
+	lockedBy    string
+	lockedUntil time.Time
+}
+
+// webhook ищет подписку владельца userID; вызывается под s.mu.
+func (s *Store) webhook(userID, id string) (*domain.WebhookSubscription, int) {
+	for i, sub := range s.webhooks {
+		if sub.ID == id && sub.UserID == userID {
+			return sub, i
+		}
+	}
+	return nil, -1
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, sub domain.WebhookSubscription, logger *zap.Logger) (domain.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub.ID, sub.CreatedAt = newID(), s.timestamp()
+	sub.EventTypes = slices.Clone(sub.EventTypes)
+	stored := sub
+	s.webhooks = append(s.webhooks, &stored)
+	return sub, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, userID string, logger *zap.Logger) ([]domain.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []domain.WebhookSubscription
+	for _, sub := range s.webhooks {
+		if sub.UserID == userID {
+			c := *sub
+			c.EventTypes = slices.Clone(sub.EventTypes)
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+// DeleteWebhook удаляет подписку владельца вместе с журналом её доставок.
+func (s *Store) DeleteWebhook(ctx context.Context, userID, id string, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, i := s.webhook(userID, id)
+	if i < 0 {
+		return domain.ErrWebhookNotFound
+	}
+	s.webhooks = slices.Delete(s.webhooks, i, i+1)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d *webhookDelivery) bool {
+		return d.SubscriptionID == id
+	})
+	return nil
+}
+
+// EnqueueWebhookDeliveries ставит событие в очередь доставки каждой
+// подходящей подписке; повторная постановка ничего не меняет.
+func (s *Store) EnqueueWebhookDeliveries(ctx context.Context, ev outbox.Event, payload []byte, logger *zap.Logger) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	n := 0
+	for _, sub := range s.webhooks {
+		if sub.UserID != "" && sub.UserID != ev.UserID || !slices.Contains(sub.EventTypes, ev.Type) {
+			continue
+		}
+		if slices.ContainsFunc(s.deliveries, func(d *webhookDelivery) bool {
+			return d.SubscriptionID == sub.ID && d.EventID == ev.ID
+		}) {
+			continue
+		}
+		s.deliverySeq++
+		s.deliveries = append(s.deliveries, &webhookDelivery{WebhookDelivery: domain.WebhookDelivery{
+			ID:             s.deliverySeq,
+			SubscriptionID: sub.ID,
+			EventID:        ev.ID,
+			EventType:      ev.Type,
+			Payload:        slices.Clone(payload),
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}})
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	due := slices.DeleteFunc(slices.Clone(s.deliveries), func(d *webhookDelivery) bool {
+		return d.Status != domain.DeliveryPending || d.NextAttemptAt.After(now) || d.lockedUntil.After(now)
+	})
+	slices.SortStableFunc(due, func(a, b *webhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	var res []domain.WebhookDelivery
+	for _, d := range due {
+		if len(res) == limit {
+			break
+		}
+		d.lockedBy, d.lockedUntil = workerID, now.Add(lease)
+		d.Attempts++
+		c := d.WebhookDelivery
+		for _, sub := range s.webhooks {
+			if sub.ID == d.SubscriptionID {
+				c.URL, c.Secret = sub.URL, sub.Secret
+			}
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, id int64, res domain.DeliveryResult, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deliveries {
+		if d.ID != id {
+			continue
+		}
+		d.Status, d.ResponseStatus, d.LastError = res.Status, res.ResponseStatus, res.Error
+		switch res.Status {
+		case domain.DeliveryPending:
+			d.NextAttemptAt = res.NextAttemptAt
+		case domain.DeliverySucceeded:
+			d.DeliveredAt = s.timestamp()
+		}
+		d.lockedBy, d.lockedUntil = "", time.Time{}
+		break
+	}
+	return nil
+}
+
+// ListWebhookDeliveries возвращает журнал доставок подписки владельца,
+// начиная с последних.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, userID, webhookID string, limit int, logger *zap.Logger) ([]domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, _ := s.webhook(userID, webhookID); sub == nil {
+		return nil, domain.ErrWebhookNotFound
+	}
+	var res []domain.WebhookDelivery
+	for i := len(s.deliveries) - 1; i >= 0 && len(res) < limit; i-- {
+		if d := s.deliveries[i]; d.SubscriptionID == webhookID {
+			res = append(res, d.WebhookDelivery)
+		}
+	}
+	return res, nil
+}
+
+// RedeliverWebhook возвращает доставку в очередь с обнулённым счётчиком попыток.
+func (s *Store) RedeliverWebhook(ctx context.Context, userID, webhookID string, deliveryID int64, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, _ := s.webhook(userID, webhookID); sub == nil {
+		return domain.ErrDeliveryNotFound
+	}
+	for _, d := range s.deliveries {
+		if d.ID == deliveryID && d.SubscriptionID == webhookID {
+			d.Status, d.Attempts, d.NextAttemptAt = domain.DeliveryPending, 0, s.timestamp()
+			d.lockedBy, d.lockedUntil = "", time.Time{}
+			return nil
+		}
+	}
+	return domain.ErrDeliveryNotFound
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Withdraw списывает sum с баланса пользователя. Баланс не уходит в минус,
+// номер заказа уникален в пределах пользователя.
+func (s *Store) Withdraw(ctx context.Context, userID, orderNumber string, sum decimal.Decimal, logger *zap.Logger) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := decimal.Zero
+	if b, ok := s.balances[userID]; ok {
+		current = b.current
+	}
+	if current.LessThan(sum) {
+		logger.Warn("not enough funds",
+			zap.String("current", current.String()),
+			zap.String("sum", sum.String()),
+		)
+		return domain.ErrNotEnoughFunds
+	}
+
+	key := withdrawalKey{userID, orderNumber}
+	if s.withdrawn[key] {
+		logger.Warn("withdrawal order already exists", zap.String("order", orderNumber))
+		return domain.ErrInvalidOrder
+	}
+
+	w := domain.Withdrawal{
+		ID:          newID(),
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Sum:         numeric(sum).StringFixed(2),
+		ProcessedAt: s.timestamp(),
+	}
+	err := s.post(domain.Posting{
+		Kind:      domain.LedgerKindWithdrawal,
+		Reference: w.ID,
+		UserID:    userID,
+		From:      domain.AccountUserPoints,
+		To:        domain.AccountWithdrawalSink,
+		Amount:    sum,
+	})
+	if err != nil {
+		return err
+	}
+	s.withdrawn[key] = true
+	s.withdrawals = append(s.withdrawals, w)
+	s.addOutbox(outbox.NewWithdrawalCreated(userID, outbox.WithdrawalCreated{
+		WithdrawalID: w.ID,
+		Order:        orderNumber,
+		Sum:          numeric(sum),
+		ProcessedAt:  w.ProcessedAt,
+	}))
+
+	logger.Info("withdrawal created",
+		zap.String("order", orderNumber),
+		zap.String("sum", sum.String()),
+	)
+	return nil
+}
+
+// ListByUser возвращает списания пользователя с фильтром по дате
+// и keyset-пагинацией по (processed_at, id). Статусы в q не учитываются.
+func (s *Store) ListByUser(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Withdrawal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []domain.Withdrawal
+	for _, w := range s.withdrawals {
+		if w.UserID == userID {
+			res = append(res, w)
+		}
+	}
+	return page(res, q, func(w domain.Withdrawal) (time.Time, string) { return w.ProcessedAt, w.ID }), nil
+}
@@ -0,0 +1,29 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/storage"
+	"go-musthave-diploma-tpl/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorageConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+	require.NoError(t, postgres.RunMigrations(dsn, zap.NewNop()))
+
+	db, err := postgres.NewDBStorage(context.Background(), dsn, postgres.PoolConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	s := storage.NewPostgres(db, zap.NewNop())
+	storagetest.Run(t, func(t *testing.T) *storage.Storage { return s })
+}
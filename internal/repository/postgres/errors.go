@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// codeUniqueViolation — SQLSTATE unique_violation.
+const codeUniqueViolation = "23505"
+
+// constraintErrors сопоставляет ограничения схемы доменным ошибкам.
+// Имена ограничений — те, что PostgreSQL выдал при создании таблиц.
+var constraintErrors = map[string]error{
+	"users_login_key":                      domain.ErrUserExists,
+	"orders_number_key":                    domain.ErrOrderExists,
+	"withdrawals_user_id_order_number_key": domain.ErrInvalidOrder,
+}
+
+// translateError переводит нарушение ограничения в доменную ошибку по SQLSTATE
+// и имени ограничения. Остальные ошибки возвращаются без изменений.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if pgErr.Code == codeUniqueViolation {
+		if domainErr, ok := constraintErrors[pgErr.ConstraintName]; ok {
+			return domainErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestTranslateError(t *testing.T) {
+	unique := func(constraint string) error {
+		return &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: constraint}
+	}
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "#1 логин занят", err: unique("users_login_key"), want: domain.ErrUserExists},
+		{name: "#2 номер заказа занят", err: unique("orders_number_key"), want: domain.ErrOrderExists},
+		{name: "#3 повторное списание", err: unique("withdrawals_user_id_order_number_key"), want: domain.ErrInvalidOrder},
+		{name: "#4 обёрнутая ошибка", err: fmt.Errorf("insert: %w", unique("users_login_key")), want: domain.ErrUserExists},
+		{name: "#5 неизвестное ограничение", err: unique("sessions_token_hash_key")},
+		{
+			name: "#6 другой SQLSTATE",
+			err:  &pgconn.PgError{Code: "23503", ConstraintName: "users_login_key"},
+		},
+		{name: "#7 не ошибка PostgreSQL", err: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translateError(tt.err)
+			if tt.want != nil {
+				assert.ErrorIs(t, got, tt.want)
+				return
+			}
+			assert.Same(t, tt.err, got)
+		})
+	}
+}
+
+func TestUserRepository_Errors(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("alice", "hash").
+		WillReturnError(&pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "users_login_key"})
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT id, login, password_hash FROM users").
+		WithArgs("bob").
+		WillReturnError(pgx.ErrNoRows)
+
+	repo := NewUserRepository(mock)
+	logger := zaptest.NewLogger(t)
+
+	_, err = repo.CreateUser(context.Background(), "alice", "hash", logger)
+	assert.ErrorIs(t, err, domain.ErrUserExists)
+
+	_, err = repo.GetUserByLogin(context.Background(), "bob", logger)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckDSNScheme(t *testing.T) {
+	assert.NoError(t, checkDSNScheme("postgres://u:p@db:5432/app?sslmode=disable"))
+	assert.NoError(t, checkDSNScheme("postgresql://db/app"))
+	assert.NoError(t, checkDSNScheme("host=db port=5432 dbname=app sslmode=disable"))
+	assert.NoError(t, checkDSNScheme("host=db options='-c search_path=a://b'"))
+	assert.EqualError(t, checkDSNScheme("sqlite:///tmp/app.db"), `migrate: unsupported DSN scheme "sqlite"`)
+	assert.EqualError(t, checkDSNScheme("memory://"), `migrate: unsupported DSN scheme "memory"`)
+}
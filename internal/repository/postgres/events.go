@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// OrderEventsChannel — канал LISTEN/NOTIFY, в который уходит каждое событие заказа.
+const OrderEventsChannel = "order_events"
+
+// recordOrderEvent сохраняет текущее состояние заказа как событие и публикует его
+// через pg_notify. Уведомление уходит подписчикам только после коммита tx.
+func recordOrderEvent(ctx context.Context, tx DB, orderID string, logger *zap.Logger) error {
+	query := `
+		WITH ev AS (
+			INSERT INTO order_events (order_id, user_id, number, status, accrual)
+			SELECT id, user_id, number, status, accrual
+			FROM orders
+			WHERE id = $1
+			RETURNING id, order_id, user_id, number, status, accrual, created_at
+		)
+		SELECT pg_notify($2, json_build_object(
+			'id', id,
+			'order_id', order_id,
+			'user_id', user_id,
+			'number', number,
+			'status', status,
+			'accrual', accrual::text,
+			'created_at', created_at
+		)::text)
+		FROM ev
+	`
+	var ignored string
+	if err := queryRowContext(ctx, tx, query, orderID, OrderEventsChannel).Scan(&ignored); err != nil {
+		logger.Error("failed to record order event", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListOrderEvents возвращает события пользователя с ID больше afterID по возрастанию ID.
+func (r *OrderRepository) ListOrderEvents(ctx context.Context, userID string, afterID int64, logger *zap.Logger) ([]domain.OrderEvent, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.ListOrderEvents")
+	defer span.End()
+
+	events, err := listOrderEvents(ctx, r.db, "WHERE user_id = $1 AND id > $2", userID, afterID)
+	if err != nil {
+		logger.Error("failed to list order events", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
+
+func listOrderEvents(ctx context.Context, db DB, where string, args ...any) ([]domain.OrderEvent, error) {
+	rows, err := queryContext(ctx, db, `
+		SELECT id, order_id, user_id, number, status, accrual, created_at
+		FROM order_events
+		`+where+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.OrderEvent
+	for rows.Next() {
+		var ev domain.OrderEvent
+		if err := rows.Scan(&ev.ID, &ev.OrderID, &ev.UserID, &ev.Number, &ev.Status, &ev.Accrual, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// OrderEventListener слушает OrderEventsChannel и передаёт события в publish.
+// Так события доходят до клиентов, подключённых к любой реплике.
+type OrderEventListener struct {
+	pool    *pgxpool.Pool
+	publish func(domain.OrderEvent)
+	logger  *zap.Logger
+
+	lastID int64
+	seen   *domain.EventIDSet
+}
+
+func NewOrderEventListener(pool *pgxpool.Pool, publish func(domain.OrderEvent), logger *zap.Logger) *OrderEventListener {
+	return &OrderEventListener{
+		pool:    pool,
+		publish: publish,
+		logger:  logger,
+		seen:    domain.NewEventIDSet(2 * domain.EventReplayWindow),
+	}
+}
+
+// listenerRetryDelay — пауза перед переподключением после обрыва соединения.
+const listenerRetryDelay = time.Second
+
+// Run слушает канал до отмены ctx. После переподключения к базе события,
+// пропущенные за время разрыва, дочитываются из order_events с запасом в
+// EventReplayWindow, поэтому доставка — «хотя бы один раз»: подписчики
+// отсеивают повторы по ID.
+func (l *OrderEventListener) Run(ctx context.Context) error {
+	if err := l.pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM order_events`).Scan(&l.lastID); err != nil {
+		return err
+	}
+
+	for reconnect := false; ; reconnect = true {
+		err := l.listen(ctx, reconnect)
+		if ctx.Err() != nil {
+			return nil
+		}
+		l.logger.Warn("order event listener connection problem", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(listenerRetryDelay):
+		}
+	}
+}
+
+// listen держит отдельное соединение с LISTEN до ошибки или отмены ctx.
+// Соединение забирается из пула насовсем: после LISTEN оно не годится
+// для обычных запросов.
+func (l *OrderEventListener) listen(ctx context.Context, reconnect bool) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+OrderEventsChannel); err != nil {
+		return err
+	}
+	if reconnect {
+		// уведомления за время разрыва потеряны
+		l.catchUp(ctx)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev domain.OrderEvent
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			l.logger.Error("failed to decode order event", zap.String("payload", n.Payload), zap.Error(err))
+			continue
+		}
+		l.emit(ev)
+	}
+}
+
+func (l *OrderEventListener) catchUp(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// событие с меньшим ID могло закоммититься уже после уведомления о большем
+	events, err := listOrderEvents(ctx, l.pool, "WHERE id > $1", l.lastID-domain.EventReplayWindow)
+	if err != nil {
+		l.logger.Error("failed to catch up order events", zap.Error(err))
+		return
+	}
+	replayed := 0
+	for _, ev := range events {
+		if l.emit(ev) {
+			replayed++
+		}
+	}
+	if replayed > 0 {
+		l.logger.Info("order events replayed after reconnect", zap.Int("count", replayed))
+	}
+}
+
+// emit публикует событие, если оно ещё не публиковалось.
+func (l *OrderEventListener) emit(ev domain.OrderEvent) bool {
+	if !l.seen.Add(ev.ID) {
+		return false
+	}
+	if ev.ID > l.lastID {
+		l.lastID = ev.ID
+	}
+	l.publish(ev)
+	return true
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func insertStatusHistory(ctx context.Context, tx DB, orderID string, change domain.StatusChange, logger *zap.Logger) error {
+	var accrual decimal.NullDecimal
+	if change.Accrual != nil {
+		accrual = decimal.NullDecimal{Decimal: *change.Accrual, Valid: true}
+	}
+	var response any
+	if len(change.Response) > 0 {
+		response = []byte(change.Response)
+	}
+
+	_, err := execContext(ctx, tx, `
+		INSERT INTO order_status_history (order_id, old_status, new_status, accrual, source, reason, accrual_response)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+	`, orderID, change.From, change.Status, accrual, change.Source, change.Reason, response)
+	if err != nil {
+		logger.Error("failed to insert order status history", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetOrderByNumber возвращает заказ пользователя по номеру. Чужой заказ
+// неотличим от несуществующего: оба дают ErrOrderNotFound.
+func (r *OrderRepository) GetOrderByNumber(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.GetOrderByNumber")
+	defer span.End()
+
+	query := `
+		SELECT id, number, user_id, status, accrual, uploaded_at
+		FROM orders
+		WHERE number = $1 AND user_id = $2
+	`
+	var o domain.Order
+	err := queryRowContext(ctx, r.db, query, number, userID).
+		Scan(&o.ID, &o.Number, &o.UserID, &o.Status, &o.Accrual, &o.UploadedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Order{}, domain.ErrOrderNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get order by number", zap.Error(err))
+		return domain.Order{}, err
+	}
+	return o, nil
+}
+
+// ListStatusHistory возвращает историю статусов заказа в хронологическом порядке.
+func (r *OrderRepository) ListStatusHistory(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.ListStatusHistory")
+	defer span.End()
+
+	query := `
+		SELECT id, old_status, new_status, accrual, source, reason, accrual_response, changed_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY id
+	`
+	rows, err := queryContext(ctx, r.db, query, orderID)
+	if err != nil {
+		logger.Error("failed to query order status history", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.StatusHistoryEntry
+	for rows.Next() {
+		var (
+			e        domain.StatusHistoryEntry
+			response []byte
+		)
+		if err := rows.Scan(&e.ID, &e.OldStatus, &e.NewStatus, &e.Accrual, &e.Source, &e.Reason, &response, &e.ChangedAt); err != nil {
+			logger.Error("failed to scan order status history", zap.Error(err))
+			return nil, err
+		}
+		e.Response = response
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
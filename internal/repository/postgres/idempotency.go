@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type IdempotencyRepository struct {
+	db DB
+}
+
+func NewIdempotencyRepository(db DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve занимает ключ для нового запроса. Если ключ уже занят и не истёк,
+// возвращается существующая запись, а reserved == false.
+func (r *IdempotencyRepository) Reserve(
+	ctx context.Context,
+	userID, endpoint, key, requestHash string,
+	ttl time.Duration,
+	logger *zap.Logger,
+) (rec *domain.IdempotencyRecord, reserved bool, err error) {
+	ctx, span := startSpan(ctx, "IdempotencyRepository.Reserve")
+	defer span.End()
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, endpoint, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))
+		ON CONFLICT (user_id, endpoint, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+		    status_code = NULL,
+		    content_type = NULL,
+		    response_body = NULL,
+		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+		RETURNING expires_at
+	`
+	var expiresAt time.Time
+	err = queryRowContext(ctx, r.db, query, userID, endpoint, key, requestHash, ttl.Seconds()).Scan(&expiresAt)
+	if err == nil {
+		return &domain.IdempotencyRecord{RequestHash: requestHash, ExpiresAt: expiresAt}, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to reserve idempotency key", zap.Error(err))
+		return nil, false, err
+	}
+
+	rec = &domain.IdempotencyRecord{}
+	var (
+		status      *int32
+		contentType *string
+	)
+	err = queryRowContext(ctx, r.db, `
+		SELECT request_hash, status_code, content_type, response_body, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND endpoint = $2 AND key = $3
+	`, userID, endpoint, key).Scan(&rec.RequestHash, &status, &contentType, &rec.ResponseBody, &rec.ExpiresAt)
+	if err != nil {
+		logger.Error("failed to load idempotency key", zap.Error(err))
+		return nil, false, err
+	}
+	if status != nil {
+		rec.StatusCode = int(*status)
+	}
+	if contentType != nil {
+		rec.ContentType = *contentType
+	}
+
+	return rec, false, nil
+}
+
+// Complete сохраняет ответ, который будет воспроизводиться для повторов.
+func (r *IdempotencyRepository) Complete(
+	ctx context.Context,
+	userID, endpoint, key string,
+	statusCode int,
+	contentType string,
+	body []byte,
+	logger *zap.Logger,
+) error {
+	ctx, span := startSpan(ctx, "IdempotencyRepository.Complete")
+	defer span.End()
+
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $4, content_type = $5, response_body = $6
+		WHERE user_id = $1 AND endpoint = $2 AND key = $3
+	`
+	if _, err := execContext(ctx, r.db, query, userID, endpoint, key, statusCode, contentType, body); err != nil {
+		logger.Error("failed to save idempotent response", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Release освобождает ключ, чтобы клиент мог повторить неудавшийся запрос.
+func (r *IdempotencyRepository) Release(ctx context.Context, userID, endpoint, key string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "IdempotencyRepository.Release")
+	defer span.End()
+
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND endpoint = $2 AND key = $3`
+	if _, err := execContext(ctx, r.db, query, userID, endpoint, key); err != nil {
+		logger.Error("failed to release idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком хранения.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, logger *zap.Logger) (int64, error) {
+	ctx, span := startSpan(ctx, "IdempotencyRepository.DeleteExpired")
+	defer span.End()
+
+	res, err := execContext(ctx, r.db, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		logger.Error("failed to delete expired idempotency keys", zap.Error(err))
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
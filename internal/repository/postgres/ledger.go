@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type LedgerRepository struct {
+	db DB
+}
+
+func NewLedgerRepository(db DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// Post проводит posting в отдельной транзакции.
+func (r *LedgerRepository) Post(ctx context.Context, p domain.Posting, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "LedgerRepository.Post")
+	defer span.End()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to begin ledger transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := postLedger(ctx, tx, p, logger); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("failed to commit ledger transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetBalance читает поддерживаемую журналом строку баланса пользователя.
+func (r *LedgerRepository) GetBalance(
+	ctx context.Context,
+	userID string,
+	logger *zap.Logger,
+) (current, withdrawn decimal.Decimal, err error) {
+	ctx, span := startSpan(ctx, "LedgerRepository.GetBalance")
+	defer span.End()
+
+	query := `SELECT current, withdrawn FROM user_balances WHERE user_id = $1`
+
+	err = queryRowContext(ctx, r.db, query, userID).Scan(&current, &withdrawn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return decimal.Zero, decimal.Zero, nil
+	}
+	if err != nil {
+		logger.Error("failed to get balance", zap.Error(err))
+		return decimal.Zero, decimal.Zero, err
+	}
+
+	return current, withdrawn, nil
+}
+
+// ListEntries возвращает все записи журнала пользователя в порядке проводки.
+func (r *LedgerRepository) ListEntries(ctx context.Context, userID string, logger *zap.Logger) ([]domain.LedgerEntry, error) {
+	ctx, span := startSpan(ctx, "LedgerRepository.ListEntries")
+	defer span.End()
+
+	query := `
+		SELECT e.id, e.transaction_id, t.kind, t.reference, e.account, e.user_id, e.amount, e.created_at
+		FROM ledger_entries e
+		JOIN ledger_transactions t ON t.id = e.transaction_id
+		WHERE e.user_id = $1
+		ORDER BY e.id
+	`
+
+	rows, err := queryContext(ctx, r.db, query, userID)
+	if err != nil {
+		logger.Error("failed to query ledger entries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []domain.LedgerEntry
+	for rows.Next() {
+		var e domain.LedgerEntry
+		if err := rows.Scan(
+			&e.ID,
+			&e.TransactionID,
+			&e.Kind,
+			&e.Reference,
+			&e.Account,
+			&e.UserID,
+			&e.Amount,
+			&e.CreatedAt,
+		); err != nil {
+			logger.Error("failed to scan ledger entry", zap.Error(err))
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Reconcile сверяет user_balances с журналом и возвращает пользователей,
+// у которых сохранённый баланс расходится с суммой проводок.
+func (r *LedgerRepository) Reconcile(ctx context.Context, logger *zap.Logger) ([]string, error) {
+	ctx, span := startSpan(ctx, "LedgerRepository.Reconcile")
+	defer span.End()
+
+	query := `
+		SELECT b.user_id
+		FROM user_balances b
+		LEFT JOIN (
+			SELECT user_id,
+			       SUM(amount) FILTER (WHERE account = 'user_points')     AS current,
+			       SUM(amount) FILTER (WHERE account = 'withdrawal_sink') AS withdrawn
+			FROM ledger_entries
+			GROUP BY user_id
+		) l ON l.user_id = b.user_id
+		WHERE b.current <> COALESCE(l.current, 0)
+		   OR b.withdrawn <> COALESCE(l.withdrawn, 0)
+	`
+
+	rows, err := queryContext(ctx, r.db, query)
+	if err != nil {
+		logger.Error("failed to reconcile ledger", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mismatched []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		mismatched = append(mismatched, userID)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	if len(mismatched) > 0 {
+		logger.Warn("ledger balance mismatch", zap.Strings("users", mismatched))
+	}
+	return mismatched, nil
+}
+
+// postLedger записывает транзакцию, две её записи и обновляет баланс
+// пользователя внутри уже открытой транзакции tx.
+func postLedger(ctx context.Context, tx pgx.Tx, p domain.Posting, logger *zap.Logger) error {
+	if !p.Amount.IsPositive() || p.From == p.To || p.UserID == "" {
+		logger.Error("invalid ledger posting",
+			zap.String("kind", p.Kind),
+			zap.String("reference", p.Reference),
+			zap.String("amount", p.Amount.String()),
+		)
+		return domain.ErrInvalidPosting
+	}
+
+	var txID string
+	err := queryRowContext(ctx, tx, `
+		INSERT INTO ledger_transactions (kind, reference)
+		VALUES ($1, $2)
+		ON CONFLICT (kind, reference) DO NOTHING
+		RETURNING id
+	`, p.Kind, p.Reference).Scan(&txID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("ledger posting already exists",
+			zap.String("kind", p.Kind),
+			zap.String("reference", p.Reference),
+		)
+		return domain.ErrPostingExists
+	}
+	if err != nil {
+		logger.Error("failed to insert ledger transaction", zap.Error(err))
+		return err
+	}
+
+	_, err = execContext(ctx, tx, `
+		INSERT INTO ledger_entries (transaction_id, account, user_id, amount)
+		VALUES ($1, $2, $4, $5), ($1, $3, $4, $6)
+	`, txID, p.From, p.To, p.UserID, p.Amount.Neg(), p.Amount)
+	if err != nil {
+		logger.Error("failed to insert ledger entries", zap.Error(err))
+		return err
+	}
+
+	currentDelta, withdrawnDelta := balanceDelta(p)
+	_, err = execContext(ctx, tx, `
+		INSERT INTO user_balances (user_id, current, withdrawn)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET current = user_balances.current + EXCLUDED.current,
+		    withdrawn = user_balances.withdrawn + EXCLUDED.withdrawn,
+		    updated_at = NOW()
+	`, p.UserID, currentDelta, withdrawnDelta)
+	if err != nil {
+		logger.Error("failed to update user balance", zap.Error(err))
+		return err
+	}
+
+	logger.Info("ledger posting created",
+		zap.String("transaction_id", txID),
+		zap.String("kind", p.Kind),
+		zap.String("reference", p.Reference),
+		zap.String("amount", p.Amount.String()),
+	)
+	return nil
+}
+
+// balanceDelta переводит проводку в изменения строки user_balances.
+func balanceDelta(p domain.Posting) (current, withdrawn decimal.Decimal) {
+	current, withdrawn = decimal.Zero, decimal.Zero
+	if p.To == domain.AccountUserPoints {
+		current = current.Add(p.Amount)
+	}
+	if p.From == domain.AccountUserPoints {
+		current = current.Sub(p.Amount)
+	}
+	if p.To == domain.AccountWithdrawalSink {
+		withdrawn = withdrawn.Add(p.Amount)
+	}
+	if p.From == domain.AccountWithdrawalSink {
+		withdrawn = withdrawn.Sub(p.Amount)
+	}
+	return current, withdrawn
+}
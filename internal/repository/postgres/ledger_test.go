@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLedgerRepository_Post(t *testing.T) {
+	accrual := domain.Posting{
+		Kind:      domain.LedgerKindAccrual,
+		Reference: "order-1",
+		UserID:    "user-1",
+		From:      domain.AccountAccrualSource,
+		To:        domain.AccountUserPoints,
+		Amount:    decimal.RequireFromString("729.98"),
+	}
+
+	tests := []struct {
+		name    string
+		posting domain.Posting
+		setup   func(mock pgxmock.PgxPoolIface)
+		wantErr error
+	}{
+		{
+			name:    "#1 accrual posted",
+			posting: accrual,
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO ledger_transactions").
+					WithArgs(domain.LedgerKindAccrual, "order-1").
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("tx-1"))
+				mock.ExpectExec("INSERT INTO ledger_entries").
+					WithArgs("tx-1", domain.AccountAccrualSource, domain.AccountUserPoints, "user-1", accrual.Amount.Neg(), accrual.Amount).
+					WillReturnResult(pgxmock.NewResult("INSERT", 2))
+				mock.ExpectExec("INSERT INTO user_balances").
+					WithArgs("user-1", accrual.Amount, decimal.Zero).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:    "#2 duplicate posting",
+			posting: accrual,
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO ledger_transactions").
+					WithArgs(domain.LedgerKindAccrual, "order-1").
+					WillReturnRows(pgxmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: domain.ErrPostingExists,
+		},
+		{
+			name: "#3 non positive amount",
+			posting: domain.Posting{
+				Kind:      domain.LedgerKindWithdrawal,
+				Reference: "w-1",
+				UserID:    "user-1",
+				From:      domain.AccountUserPoints,
+				To:        domain.AccountWithdrawalSink,
+				Amount:    decimal.Zero,
+			},
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+			wantErr: domain.ErrInvalidPosting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			tt.setup(mock)
+
+			err = NewLedgerRepository(mock).Post(context.Background(), tt.posting, zaptest.NewLogger(t))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLedgerRepository_GetBalance_NoRow(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT current, withdrawn FROM user_balances").
+		WithArgs("user-1").
+		WillReturnError(pgx.ErrNoRows)
+
+	current, withdrawn, err := NewLedgerRepository(mock).GetBalance(context.Background(), "user-1", zaptest.NewLogger(t))
+	require.NoError(t, err)
+	assert.True(t, current.IsZero())
+	assert.True(t, withdrawn.IsZero())
+}
+
+func TestBalanceDelta(t *testing.T) {
+	amount := decimal.NewFromInt(100)
+
+	current, withdrawn := balanceDelta(domain.Posting{From: domain.AccountAccrualSource, To: domain.AccountUserPoints, Amount: amount})
+	assert.True(t, current.Equal(amount))
+	assert.True(t, withdrawn.IsZero())
+
+	current, withdrawn = balanceDelta(domain.Posting{From: domain.AccountUserPoints, To: domain.AccountWithdrawalSink, Amount: amount})
+	assert.True(t, current.Equal(amount.Neg()))
+	assert.True(t, withdrawn.Equal(amount))
+}
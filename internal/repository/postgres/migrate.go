@@ -1,41 +1,71 @@
 package postgres
 
 import (
-	"database/sql"
-	"log"
-	"path/filepath"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/migrations"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
 
-func RunMigrations(dns string, logger *zap.Logger) error {
-	log.Println("Running migrations...")
-	db, err := sql.Open("postgres", dns)
+// NewMigrator создаёт migrate.Migrate поверх миграций, встроенных в бинарник.
+// DSN разбирается pgx, поэтому подходят и URL, и строки вида host=... dbname=...
+func NewMigrator(dsn string) (*migrate.Migrate, error) {
+	if err := checkDSNScheme(dsn); err != nil {
+		return nil, err
+	}
+	connConfig, err := pgx.ParseConfig(dsn)
 	if err != nil {
-		logger.Error("cannot open DB", zap.Error(err))
-		return err
+		return nil, fmt.Errorf("migrate: %w", err)
 	}
-	defer db.Close()
 
-	path, err := filepath.Abs("internal/migrations")
+	src, err := iofs.New(migrations.FS, ".")
 	if err != nil {
-		logger.Error("failed to get absolute path", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	migrationsURL := "file://" + path
-	logger.Info("Running migrations", zap.String("path", migrationsURL))
+	db := stdlib.OpenDB(*connConfig)
+	driver, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return migrate.NewWithInstance("iofs", src, "pgx5", driver)
+}
 
-	m, err := migrate.New(migrationsURL, dns)
+// checkDSNScheme пропускает URL postgres:// и postgresql:// и строки key=value,
+// у которых схемы нет. Остальные схемы (sqlite://, memory://) migrate не обслуживает.
+func checkDSNScheme(dsn string) error {
+	scheme, _, ok := strings.Cut(dsn, "://")
+	if !ok || strings.ContainsAny(scheme, "= ") {
+		return nil
+	}
+	switch scheme {
+	case "postgres", "postgresql":
+		return nil
+	}
+	return fmt.Errorf("migrate: unsupported DSN scheme %q", scheme)
+}
+
+func RunMigrations(dsn string, logger *zap.Logger) error {
+	logger.Info("Running migrations")
+
+	m, err := NewMigrator(dsn)
 	if err != nil {
 		logger.Error("cannot create migration", zap.Error(err))
 		return err
 	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		logger.Info("cannot run migration", zap.Error(err))
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		logger.Error("cannot run migration", zap.Error(err))
 		return err
 	}
 	logger.Info("migrations successfully migrated")
@@ -2,47 +2,37 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"strings"
 	"time"
 
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
-var (
-	ErrOrderExists         = errors.New("order already exists")
-	ErrOrderUploadedByUser = errors.New("order already uploaded by this user")
-)
-
 type OrderRepository struct {
-	db *sql.DB
-}
-
-type Order struct {
-	ID         string              `db:"id"`
-	Number     string              `db:"number"`
-	UserID     string              `db:"user_id"`
-	Status     string              `db:"status"`
-	Accrual    decimal.NullDecimal `db:"accrual"`
-	UploadedAt time.Time           `db:"uploaded_at"`
+	db DB
 }
 
-func NewOrderRepository(db *sql.DB) *OrderRepository {
+func NewOrderRepository(db DB) *OrderRepository {
 	return &OrderRepository{
 		db: db,
 	}
 }
 
 func (r *OrderRepository) CreateOrder(ctx context.Context, userID, number string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "OrderRepository.CreateOrder")
+	defer span.End()
+
 	query := `INSERT INTO orders (user_id, number) VALUES ($1, $2)`
 
-	_, err := r.db.ExecContext(ctx, query, userID, number)
+	_, err := execContext(ctx, r.db, query, userID, number)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		if err := translateError(err); errors.Is(err, domain.ErrOrderExists) {
 			logger.Warn("order already exists", zap.String("number", number))
-			return ErrOrderExists
+			return err
 		}
 		logger.Error("failed to insert order", zap.Error(err))
 		return err
@@ -51,24 +41,89 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, userID, number string
 	return nil
 }
 
-func (r *OrderRepository) GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]Order, error) {
+// CreateOrders загружает пачку номеров одним запросом. Для каждого номера
+// возвращается nil, ErrOrderUploadedByUser или ErrOrderExists.
+// Номера, вставленные конкурентно в момент запроса, считаются чужими.
+func (r *OrderRepository) CreateOrders(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.CreateOrders")
+	defer span.End()
+
 	query := `
+		WITH input AS (
+			SELECT DISTINCT unnest($2::text[]) AS number
+		), inserted AS (
+			INSERT INTO orders (user_id, number)
+			SELECT $1::uuid, number FROM input
+			ON CONFLICT (number) DO NOTHING
+			RETURNING number
+		)
+		SELECT i.number,
+		       ins.number IS NOT NULL,
+		       COALESCE(o.user_id = $1::uuid, false)
+		FROM input i
+		LEFT JOIN inserted ins ON ins.number = i.number
+		LEFT JOIN orders o ON o.number = i.number
+	`
+	rows, err := queryContext(ctx, r.db, query, userID, numbers)
+	if err != nil {
+		logger.Error("failed to insert order batch", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make(map[string]error, len(numbers))
+	for rows.Next() {
+		var (
+			number  string
+			created bool
+			own     bool
+		)
+		if err := rows.Scan(&number, &created, &own); err != nil {
+			logger.Error("failed to scan order batch result", zap.Error(err))
+			return nil, err
+		}
+		switch {
+		case created:
+			results[number] = nil
+		case own:
+			results[number] = domain.ErrOrderUploadedByUser
+		default:
+			results[number] = domain.ErrOrderExists
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("order batch uploaded", zap.Int("numbers", len(numbers)))
+	return results, nil
+}
+
+func (r *OrderRepository) GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error) {
+	return r.ListOrders(ctx, userID, domain.ListQuery{}, logger)
+}
+
+// ListOrders возвращает заказы пользователя с фильтрами и keyset-пагинацией по (uploaded_at, id).
+func (r *OrderRepository) ListOrders(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.ListOrders")
+	defer span.End()
+
+	query, args := appendList(q, `
 		SELECT id, number, user_id, status, accrual, uploaded_at
 		FROM orders
-		WHERE user_id = $1
-		ORDER BY uploaded_at DESC
-	`
+		WHERE user_id = $1`, "uploaded_at", []any{userID})
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := queryContext(ctx, r.db, query, args...)
 	if err != nil {
 		logger.Error("failed to query order", zap.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []Order
+	var orders []domain.Order
 	for rows.Next() {
-		var o Order
+		var o domain.Order
 		if err := rows.Scan(&o.ID, &o.Number, &o.UserID, &o.Status, &o.Accrual, &o.UploadedAt); err != nil {
 			logger.Error("failed to scan order", zap.Error(err))
 			return nil, err
@@ -83,25 +138,47 @@ func (r *OrderRepository) GetOrderByUser(ctx context.Context, userID string, log
 	return orders, nil
 }
 
-func (r *OrderRepository) GetOrdersForProcessing(ctx context.Context, limit int, logger *zap.Logger) ([]Order, error) {
+// ClaimOrdersForProcessing берёт в аренду до limit необработанных заказов.
+// Заказы, уже арендованные другим воркером, пропускаются; аренда упавшего
+// воркера перестаёт действовать после locked_until.
+func (r *OrderRepository) ClaimOrdersForProcessing(
+	ctx context.Context,
+	workerID string,
+	limit int,
+	lease time.Duration,
+	logger *zap.Logger,
+) ([]domain.Order, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.ClaimOrdersForProcessing")
+	defer span.End()
+
 	query := `
-		SELECT id, number, user_id, status, accrual, uploaded_at
-		FROM orders
-		WHERE status IN ('NEW', 'PROCESSING')
-		ORDER BY uploaded_at
-		LIMIT $1
+		UPDATE orders o
+		SET locked_by = $1,
+		    locked_until = NOW() + make_interval(secs => $3)
+		FROM (
+			SELECT id
+			FROM orders
+			WHERE status = ANY($4)
+			  AND next_poll_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_poll_at, uploaded_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) claimed
+		WHERE o.id = claimed.id
+		RETURNING o.id, o.number, o.user_id, o.status, o.accrual, o.uploaded_at, o.attempts
 	`
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	rows, err := queryContext(ctx, r.db, query, workerID, limit, lease.Seconds(), pendingStatuses)
 	if err != nil {
-		logger.Error("failed to get orders for processing", zap.Error(err))
+		logger.Error("failed to claim orders for processing", zap.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []Order
+	var orders []domain.Order
 	for rows.Next() {
-		var o Order
-		if err := rows.Scan(&o.ID, &o.Number, &o.UserID, &o.Status, &o.Accrual, &o.UploadedAt); err != nil {
+		var o domain.Order
+		if err := rows.Scan(&o.ID, &o.Number, &o.UserID, &o.Status, &o.Accrual, &o.UploadedAt, &o.Attempts); err != nil {
 			return nil, err
 		}
 		orders = append(orders, o)
@@ -114,28 +191,248 @@ func (r *OrderRepository) GetOrdersForProcessing(ctx context.Context, limit int,
 	return orders, nil
 }
 
-func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, status string, accrual *decimal.Decimal, logger *zap.Logger) error {
+// ScheduleRetry откладывает следующий опрос заказа до nextPollAt и
+// увеличивает счётчик попыток. Пустой lastErr очищает last_error.
+func (r *OrderRepository) ScheduleRetry(
+	ctx context.Context,
+	orderID string,
+	nextPollAt time.Time,
+	lastErr string,
+	logger *zap.Logger,
+) error {
+	ctx, span := startSpan(ctx, "OrderRepository.ScheduleRetry")
+	defer span.End()
+
+	query := `
+		UPDATE orders
+		SET attempts = attempts + 1,
+		    next_poll_at = $2,
+		    last_error = NULLIF($3, '')
+		WHERE id = $1
+	`
+	if _, err := execContext(ctx, r.db, query, orderID, nextPollAt, lastErr); err != nil {
+		logger.Error("failed to schedule order retry", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// MarkOrderFailed переводит заказ, который так и не удалось рассчитать,
+// в терминальный статус INVALID с сохранением причины.
+func (r *OrderRepository) MarkOrderFailed(ctx context.Context, orderID, lastErr string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "OrderRepository.MarkOrderFailed")
+	defer span.End()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE orders o
+		SET status = $3, last_error = $2
+		FROM orders old
+		WHERE o.id = $1 AND old.id = o.id AND o.status = ANY($4)
+		RETURNING old.status, o.user_id, o.number, o.accrual
+	`
+	var (
+		oldStatus domain.OrderStatus
+		current   = domain.OrderEvent{OrderID: orderID}
+	)
+	err = queryRowContext(ctx, tx, query, orderID, lastErr, domain.OrderStatusInvalid, pendingStatuses).
+		Scan(&oldStatus, &current.UserID, &current.Number, &current.Accrual)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// заказ уже в терминальном статусе
+		return nil
+	}
+	if err != nil {
+		logger.Error("failed to mark order as failed", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+
+	change := domain.StatusChange{From: oldStatus, Status: domain.OrderStatusInvalid, Source: domain.StatusSourceWorker, Reason: lastErr}
+	if err := insertStatusHistory(ctx, tx, orderID, change, logger); err != nil {
+		return err
+	}
+	if err := recordOrderEvent(ctx, tx, orderID, logger); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, domain.OrderOutboxEvent(current, change), logger); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("failed to commit order failure", zap.Error(err))
+		return err
+	}
+	logger.Warn("order polling gave up", zap.String("order_id", orderID), zap.String("reason", lastErr))
+	return nil
+}
+
+// ReleaseOrder снимает аренду, если она всё ещё принадлежит workerID.
+func (r *OrderRepository) ReleaseOrder(ctx context.Context, orderID, workerID string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "OrderRepository.ReleaseOrder")
+	defer span.End()
+
+	query := `
+		UPDATE orders
+		SET locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND locked_by = $2
+	`
+	if _, err := execContext(ctx, r.db, query, orderID, workerID); err != nil {
+		logger.Error("failed to release order", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ReleaseWorkerOrders снимает все аренды воркера, например при остановке.
+func (r *OrderRepository) ReleaseWorkerOrders(ctx context.Context, workerID string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "OrderRepository.ReleaseWorkerOrders")
+	defer span.End()
+
+	query := `
+		UPDATE orders
+		SET locked_by = NULL, locked_until = NULL
+		WHERE locked_by = $1
+	`
+	res, err := execContext(ctx, r.db, query, workerID)
+	if err != nil {
+		logger.Error("failed to release worker orders", zap.String("worker_id", workerID), zap.Error(err))
+		return err
+	}
+	if n := res.RowsAffected(); n > 0 {
+		logger.Info("released worker orders", zap.String("worker_id", workerID), zap.Int64("count", n))
+	}
+	return nil
+}
+
+// GetBacklog возвращает число заказов NEW/PROCESSING по статусам
+// и время загрузки самого старого из них (нулевое, если очередь пуста).
+func (r *OrderRepository) GetBacklog(ctx context.Context, logger *zap.Logger) (map[string]int, time.Time, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.GetBacklog")
+	defer span.End()
+
+	query := `
+		SELECT status, COUNT(*), MIN(uploaded_at)
+		FROM orders
+		WHERE status = ANY($1)
+		GROUP BY status
+	`
+	rows, err := queryContext(ctx, r.db, query, pendingStatuses)
+	if err != nil {
+		logger.Error("failed to query order backlog", zap.Error(err))
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	var oldest time.Time
+	for rows.Next() {
+		var (
+			status   string
+			count    int
+			uploaded time.Time
+		)
+		if err := rows.Scan(&status, &count, &uploaded); err != nil {
+			logger.Error("failed to scan order backlog", zap.Error(err))
+			return nil, time.Time{}, err
+		}
+		counts[status] = count
+		if oldest.IsZero() || uploaded.Before(oldest) {
+			oldest = uploaded
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("failed to read order backlog", zap.Error(err))
+		return nil, time.Time{}, err
+	}
+	return counts, oldest, nil
+}
+
+// UpdateOrderStatus переводит заказ из change.From в change.Status и, если заказ
+// рассчитан, в той же транзакции проводит начисление по журналу. Обновление
+// условное: если статус в базе уже не change.From, возвращается ErrStatusConflict.
+// Переход попадает в историю статусов, в поток событий заказа и в outbox.
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, change domain.StatusChange, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "OrderRepository.UpdateOrderStatus")
+	defer span.End()
+
+	if !change.From.CanTransitionTo(change.Status) {
+		logger.Warn("order status transition not allowed",
+			zap.String("order_id", orderID),
+			zap.String("from", string(change.From)),
+			zap.String("to", string(change.Status)),
+		)
+		return domain.ErrTransitionNotAllowed
+	}
+
 	var dbAccrual decimal.NullDecimal
-	if accrual != nil {
+	if change.Accrual != nil {
 		dbAccrual = decimal.NullDecimal{
-			Decimal: *accrual,
+			Decimal: *change.Accrual,
 			Valid:   true,
 		}
-	} else {
-		dbAccrual = decimal.NullDecimal{
-			Valid: false,
-		}
 	}
 
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// пустое начисление не затирает уже сохранённое
 	query := `
 		UPDATE orders
-		SET status = $2, accrual = $3
-		WHERE id = $1
+		SET status = $2, accrual = COALESCE($3, accrual)
+		WHERE id = $1 AND status = $4
+		RETURNING user_id, number, accrual
 	`
-	_, err := r.db.ExecContext(ctx, query, orderID, status, dbAccrual)
+	current := domain.OrderEvent{OrderID: orderID}
+	err = queryRowContext(ctx, tx, query, orderID, change.Status, dbAccrual, change.From).
+		Scan(&current.UserID, &current.Number, &current.Accrual)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("order status changed concurrently",
+			zap.String("order_id", orderID),
+			zap.String("expected", string(change.From)),
+			zap.String("to", string(change.Status)),
+		)
+		return domain.ErrStatusConflict
+	}
 	if err != nil {
 		logger.Error("failed to update order", zap.Error(err))
 		return err
 	}
+
+	if err := insertStatusHistory(ctx, tx, orderID, change, logger); err != nil {
+		return err
+	}
+	if err := recordOrderEvent(ctx, tx, orderID, logger); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, domain.OrderOutboxEvent(current, change), logger); err != nil {
+		return err
+	}
+
+	if change.Status == domain.OrderStatusProcessed && change.Accrual != nil && change.Accrual.IsPositive() {
+		err = postLedger(ctx, tx, domain.Posting{
+			Kind:      domain.LedgerKindAccrual,
+			Reference: orderID,
+			UserID:    current.UserID,
+			From:      domain.AccountAccrualSource,
+			To:        domain.AccountUserPoints,
+			Amount:    *change.Accrual,
+		}, logger)
+		if err != nil && !errors.Is(err, domain.ErrPostingExists) {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("failed to commit order update", zap.Error(err))
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+type OutboxRepository struct {
+	db DB
+}
+
+func NewOutboxRepository(db DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// insertOutbox записывает событие в outbox внутри транзакции tx,
+// поэтому оно появляется только вместе с изменением, которое описывает.
+func insertOutbox(ctx context.Context, tx DB, ev outbox.Event, logger *zap.Logger) error {
+	query := `
+		INSERT INTO outbox (event_id, event_type, schema_version, user_id, data, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := execContext(ctx, tx, query, ev.ID, ev.Type, ev.SchemaVersion, ev.UserID, ev.Data, ev.OccurredAt)
+	if err != nil {
+		logger.Error("failed to insert outbox event", zap.String("type", ev.Type), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ClaimOutbox берёт в аренду до limit неопубликованных событий в порядке
+// записи и увеличивает их счётчик попыток. События с действующей арендой
+// другой реплики и отложенные до next_attempt_at пропускаются.
+func (r *OutboxRepository) ClaimOutbox(
+	ctx context.Context,
+	workerID string,
+	limit int,
+	lease time.Duration,
+	logger *zap.Logger,
+) ([]outbox.Message, error) {
+	ctx, span := startSpan(ctx, "OutboxRepository.ClaimOutbox")
+	defer span.End()
+
+	query := `
+		UPDATE outbox o
+		SET locked_by = $1,
+		    locked_until = NOW() + make_interval(secs => $3),
+		    attempts = o.attempts + 1
+		FROM (
+			SELECT id
+			FROM outbox
+			WHERE published_at IS NULL
+			  AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) claimed
+		WHERE o.id = claimed.id
+		RETURNING o.id, o.attempts, o.event_id, o.event_type, o.schema_version, o.user_id, o.data, o.occurred_at
+	`
+	rows, err := queryContext(ctx, r.db, query, workerID, limit, lease.Seconds())
+	if err != nil {
+		logger.Error("failed to claim outbox events", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []outbox.Message
+	for rows.Next() {
+		var m outbox.Message
+		if err := rows.Scan(
+			&m.Seq,
+			&m.Attempts,
+			&m.Event.ID,
+			&m.Event.Type,
+			&m.Event.SchemaVersion,
+			&m.Event.UserID,
+			&m.Event.Data,
+			&m.Event.OccurredAt,
+		); err != nil {
+			logger.Error("failed to scan outbox event", zap.Error(err))
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок подзапроса
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Seq < msgs[j].Seq })
+	return msgs, nil
+}
+
+// MarkOutboxPublished отмечает событие доставленным и снимает аренду.
+func (r *OutboxRepository) MarkOutboxPublished(ctx context.Context, seq int64, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "OutboxRepository.MarkOutboxPublished")
+	defer span.End()
+
+	query := `
+		UPDATE outbox
+		SET published_at = NOW(), locked_by = NULL, locked_until = NULL, last_error = NULL
+		WHERE id = $1
+	`
+	if _, err := execContext(ctx, r.db, query, seq); err != nil {
+		logger.Error("failed to mark outbox event published", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RetryOutbox снимает аренду и откладывает следующую доставку до nextAttemptAt.
+func (r *OutboxRepository) RetryOutbox(
+	ctx context.Context,
+	seq int64,
+	nextAttemptAt time.Time,
+	lastErr string,
+	logger *zap.Logger,
+) error {
+	ctx, span := startSpan(ctx, "OutboxRepository.RetryOutbox")
+	defer span.End()
+
+	query := `
+		UPDATE outbox
+		SET next_attempt_at = $2, last_error = NULLIF($3, ''), locked_by = NULL, locked_until = NULL
+		WHERE id = $1
+	`
+	if _, err := execContext(ctx, r.db, query, seq, nextAttemptAt, lastErr); err != nil {
+		logger.Error("failed to schedule outbox retry", zap.Error(err))
+		return err
+	}
+	return nil
+}
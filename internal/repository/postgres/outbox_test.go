@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestUserRepository_CreateUserWritesOutbox(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("alice", "hash").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("user-1"))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(pgxmock.AnyArg(), outbox.TypeUserRegistered, outbox.SchemaVersion, "user-1", json.RawMessage(`{"login":"alice"}`), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	id, err := NewUserRepository(mock).CreateUser(context.Background(), "alice", "hash", zaptest.NewLogger(t))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_ClaimOutboxKeepsOrder(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	columns := []string{"id", "attempts", "event_id", "event_type", "schema_version", "user_id", "data", "occurred_at"}
+	mock.ExpectQuery(`UPDATE outbox o\s+SET locked_by = \$1`).
+		WithArgs("relay-1", 10, float64(30)).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(int64(7), 2, "event-7", outbox.TypeOrderProcessed, 1, "user-1", json.RawMessage(`{}`), now).
+			AddRow(int64(5), 1, "event-5", outbox.TypeUserRegistered, 1, "user-1", json.RawMessage(`{}`), now))
+
+	msgs, err := NewOutboxRepository(mock).ClaimOutbox(context.Background(), "relay-1", 10, 30*time.Second, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, int64(5), msgs[0].Seq)
+	assert.Equal(t, "event-5", msgs[0].Event.ID)
+	assert.Equal(t, int64(7), msgs[1].Seq)
+	assert.Equal(t, 2, msgs[1].Attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/domain"
+)
+
+// appendList дописывает к запросу с условием по user_id фильтры, keyset-условие,
+// сортировку по (timeColumn, id) и лимит из q. args должен содержать параметры base.
+func appendList(q domain.ListQuery, base, timeColumn string, args []any) (string, []any) {
+	var b strings.Builder
+	b.WriteString(base)
+
+	param := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Statuses) > 0 {
+		statuses := make([]string, len(q.Statuses))
+		for i, s := range q.Statuses {
+			statuses[i] = string(s)
+		}
+		fmt.Fprintf(&b, " AND status = ANY(%s)", param(statuses))
+	}
+	if !q.From.IsZero() {
+		fmt.Fprintf(&b, " AND %s >= %s", timeColumn, param(q.From))
+	}
+	if !q.To.IsZero() {
+		fmt.Fprintf(&b, " AND %s < %s", timeColumn, param(q.To))
+	}
+
+	direction, cmp := "DESC", "<"
+	if q.Ascending {
+		direction, cmp = "ASC", ">"
+	}
+	if q.After != nil {
+		fmt.Fprintf(&b, " AND (%s, id) %s (%s::timestamptz, %s::uuid)",
+			timeColumn, cmp, param(q.After.At), param(q.After.ID))
+	}
+
+	fmt.Fprintf(&b, " ORDER BY %s %s, id %s", timeColumn, direction, direction)
+	if q.Limit > 0 {
+		fmt.Fprintf(&b, " LIMIT %s", param(q.Limit))
+	}
+	return b.String(), args
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestAppendList(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		q         domain.ListQuery
+		wantQuery string
+		wantArgs  int
+	}{
+		{
+			name:      "#1 нулевой запрос — все записи по убыванию",
+			q:         domain.ListQuery{},
+			wantQuery: "SELECT * FROM t WHERE user_id = $1 ORDER BY ts DESC, id DESC",
+			wantArgs:  1,
+		},
+		{
+			name: "#2 фильтры, курсор и лимит",
+			q: domain.ListQuery{
+				Statuses: []domain.OrderStatus{domain.OrderStatusNew},
+				From:     from,
+				To:       at,
+				After:    &domain.Cursor{At: at, ID: "id-1"},
+				Limit:    11,
+			},
+			wantQuery: "SELECT * FROM t WHERE user_id = $1 AND status = ANY($2) AND ts >= $3 AND ts < $4" +
+				" AND (ts, id) < ($5::timestamptz, $6::uuid) ORDER BY ts DESC, id DESC LIMIT $7",
+			wantArgs: 7,
+		},
+		{
+			name:      "#3 по возрастанию курсор сравнивается через >",
+			q:         domain.ListQuery{Ascending: true, After: &domain.Cursor{At: at, ID: "id-1"}},
+			wantQuery: "SELECT * FROM t WHERE user_id = $1 AND (ts, id) > ($2::timestamptz, $3::uuid) ORDER BY ts ASC, id ASC",
+			wantArgs:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := appendList(tt.q, "SELECT * FROM t WHERE user_id = $1", "ts", []any{"user-1"})
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Len(t, args, tt.wantArgs)
+		})
+	}
+}
+
+func TestOrderRepositoryListOrders(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`status = ANY\(\$2\) AND \(uploaded_at, id\) < .* LIMIT \$5`).
+		WithArgs("user-1", []string{"PROCESSED"}, at, "id-9", 3).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "number", "user_id", "status", "accrual", "uploaded_at"}).
+			AddRow("id-8", "12345678903", "user-1", "PROCESSED", "10.5", at.Add(-time.Hour)))
+
+	orders, err := NewOrderRepository(mock).ListOrders(context.Background(), "user-1", domain.ListQuery{
+		Statuses: []domain.OrderStatus{domain.OrderStatusProcessed},
+		After:    &domain.Cursor{At: at, ID: "id-9"},
+		Limit:    3,
+	}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "id-8", orders[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,45 +2,105 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
+	"errors"
+	"time"
 
-	_ "github.com/lib/pq"
-
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	pgxdecimal "github.com/jackc/pgx-shopspring-decimal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
-var openDB = sql.Open
+// DB — запросы, общие для пула, транзакции и pgxmock.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PoolConfig — настройки пула соединений. Нулевые поля оставляют
+// значения из DSN или умолчания pgxpool.
+type PoolConfig struct {
+	MaxConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
 
 type DBStorage struct {
-	DB     *sql.DB
+	Pool   *pgxpool.Pool
 	Logger *zap.Logger
 }
 
-func NewDBStorage(dns string, logger *zap.Logger) (*DBStorage, error) {
+func NewDBStorage(ctx context.Context, dsn string, pc PoolConfig, logger *zap.Logger) (*DBStorage, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		logger.Error("failed to parse database DSN", zap.Error(err))
+		return nil, err
+	}
+	pc.apply(cfg)
+	// decimal.Decimal читается и пишется как numeric без потери точности
+	cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		pgxdecimal.Register(conn.TypeMap())
+		return nil
+	}
 
-	db, err := openDB("postgres", dns)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		logger.Error("failed to open database", zap.Error(err))
+		return nil, err
 	}
-
-	if err := db.Ping(); err != nil {
+	if err := pool.Ping(ctx); err != nil {
 		logger.Error("failed to ping database", zap.Error(err))
+		pool.Close()
 		return nil, err
 	}
 
-	logger.Info("successfully connected to database", zap.String("dns", dns))
+	logger.Info("successfully connected to database",
+		zap.String("host", cfg.ConnConfig.Host),
+		zap.String("database", cfg.ConnConfig.Database),
+		zap.Int32("max_conns", cfg.MaxConns),
+	)
 
 	return &DBStorage{
-		DB:     db,
+		Pool:   pool,
 		Logger: logger,
 	}, nil
 }
 
+func (pc PoolConfig) apply(cfg *pgxpool.Config) {
+	if pc.MaxConns > 0 {
+		cfg.MaxConns = pc.MaxConns
+	}
+	if pc.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = pc.MaxConnLifetime
+	}
+	if pc.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = pc.MaxConnIdleTime
+	}
+	if pc.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = pc.HealthCheckPeriod
+	}
+}
+
 func (s *DBStorage) PingContext(ctx context.Context) error {
-	return s.DB.PingContext(ctx)
+	return s.Pool.Ping(ctx)
+}
+
+// SchemaVersion читает версию схемы из таблицы golang-migrate.
+// Если миграции ещё не применялись, возвращается версия 0.
+func (s *DBStorage) SchemaVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	var v int64
+	err = s.Pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return uint(v), dirty, err
 }
 
 func (s *DBStorage) Close() error {
-	return s.DB.Close()
+	s.Pool.Close()
+	return nil
 }
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type SessionRepository struct {
+	db DB
+}
+
+func NewSessionRepository(db DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession открывает новое семейство сессий для пользователя.
+func (r *SessionRepository) CreateSession(
+	ctx context.Context,
+	userID, tokenHash string,
+	expiresAt time.Time,
+	logger *zap.Logger,
+) (*domain.Session, error) {
+	ctx, span := startSpan(ctx, "SessionRepository.CreateSession")
+	defer span.End()
+
+	s := &domain.Session{UserID: userID, ExpiresAt: expiresAt}
+	err := queryRowContext(ctx, r.db, `
+		INSERT INTO sessions (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, gen_random_uuid(), $2, $3)
+		RETURNING id, family_id
+	`, userID, tokenHash, expiresAt).Scan(&s.ID, &s.FamilyID)
+	if err != nil {
+		logger.Error("failed to create session", zap.Error(err))
+		return nil, err
+	}
+	logger.Info("session created", zap.String("user_id", userID), zap.String("family_id", s.FamilyID))
+	return s, nil
+}
+
+// RotateSession обменивает refresh-токен с хешем oldHash на новый.
+// Повторное предъявление уже обменянного токена отзывает всё семейство.
+func (r *SessionRepository) RotateSession(
+	ctx context.Context,
+	oldHash, newHash string,
+	newExpiresAt time.Time,
+	logger *zap.Logger,
+) (*domain.Session, error) {
+	ctx, span := startSpan(ctx, "SessionRepository.RotateSession")
+	defer span.End()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		old       domain.Session
+		rotatedAt *time.Time
+		revokedAt *time.Time
+	)
+	err = queryRowContext(ctx, tx, `
+		SELECT id, user_id, family_id, expires_at, rotated_at, revoked_at
+		FROM sessions
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, oldHash).Scan(&old.ID, &old.UserID, &old.FamilyID, &old.ExpiresAt, &rotatedAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrSessionNotFound
+	}
+	if err != nil {
+		logger.Error("failed to load session", zap.Error(err))
+		return nil, err
+	}
+
+	switch {
+	case revokedAt != nil:
+		return nil, domain.ErrSessionRevoked
+	case rotatedAt != nil:
+		if err := revokeFamily(ctx, tx, old.FamilyID); err != nil {
+			logger.Error("failed to revoke session family", zap.Error(err))
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			logger.Error("failed to commit session revocation", zap.Error(err))
+			return nil, err
+		}
+		logger.Warn("refresh token reuse detected, session family revoked",
+			zap.String("user_id", old.UserID),
+			zap.String("family_id", old.FamilyID),
+		)
+		return nil, domain.ErrRefreshTokenReused
+	case !old.ExpiresAt.After(time.Now()):
+		return nil, domain.ErrSessionExpired
+	}
+
+	if _, err := execContext(ctx, tx, `UPDATE sessions SET rotated_at = NOW() WHERE id = $1`, old.ID); err != nil {
+		logger.Error("failed to rotate session", zap.Error(err))
+		return nil, err
+	}
+
+	next := &domain.Session{UserID: old.UserID, FamilyID: old.FamilyID, ExpiresAt: newExpiresAt}
+	err = queryRowContext(ctx, tx, `
+		INSERT INTO sessions (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, old.UserID, old.FamilyID, newHash, newExpiresAt).Scan(&next.ID)
+	if err != nil {
+		logger.Error("failed to insert rotated session", zap.Error(err))
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("failed to commit session rotation", zap.Error(err))
+		return nil, err
+	}
+	return next, nil
+}
+
+// RevokeFamily отзывает все сессии семейства (logout).
+func (r *SessionRepository) RevokeFamily(ctx context.Context, familyID string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "SessionRepository.RevokeFamily")
+	defer span.End()
+
+	if err := revokeFamily(ctx, r.db, familyID); err != nil {
+		logger.Error("failed to revoke session family", zap.String("family_id", familyID), zap.Error(err))
+		return err
+	}
+	logger.Info("session family revoked", zap.String("family_id", familyID))
+	return nil
+}
+
+// IsSessionActive сообщает, есть ли в семействе неотозванная и неистёкшая сессия.
+func (r *SessionRepository) IsSessionActive(ctx context.Context, familyID string, logger *zap.Logger) (bool, error) {
+	ctx, span := startSpan(ctx, "SessionRepository.IsSessionActive")
+	defer span.End()
+
+	var active bool
+	err := queryRowContext(ctx, r.db, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions
+			WHERE family_id = $1
+			  AND revoked_at IS NULL
+			  AND expires_at > NOW()
+		)
+	`, familyID).Scan(&active)
+	if err != nil {
+		logger.Error("failed to check session", zap.String("family_id", familyID), zap.Error(err))
+		return false, err
+	}
+	return active, nil
+}
+
+func revokeFamily(ctx context.Context, db DB, familyID string) error {
+	_, err := execContext(ctx, db, `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
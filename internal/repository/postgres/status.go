@@ -0,0 +1,8 @@
+package postgres
+
+import (
+	"go-musthave-diploma-tpl/internal/domain"
+)
+
+// pendingStatuses — PendingStatuses как параметр запроса.
+var pendingStatuses = domain.StatusNames(domain.PendingStatuses)
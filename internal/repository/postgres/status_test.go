@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  domain.StatusChange
+		applied bool
+		wantErr error
+	}{
+		{
+			name: "#1 переход записывается в историю, события и outbox",
+			change: domain.StatusChange{
+				From:     domain.OrderStatusNew,
+				Status:   domain.OrderStatusProcessing,
+				Source:   domain.StatusSourceWorker,
+				Response: []byte(`{"status":"PROCESSING"}`),
+			},
+			applied: true,
+		},
+		{
+			name:    "#2 статус в базе уже другой",
+			change:  domain.StatusChange{From: domain.OrderStatusNew, Status: domain.OrderStatusProcessing, Source: domain.StatusSourceWorker},
+			wantErr: domain.ErrStatusConflict,
+		},
+		{
+			name:    "#3 терминальный статус не меняется",
+			change:  domain.StatusChange{From: domain.OrderStatusProcessed, Status: domain.OrderStatusProcessing, Source: domain.StatusSourceWorker},
+			wantErr: domain.ErrTransitionNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			if tt.wantErr != domain.ErrTransitionNotAllowed {
+				mock.ExpectBegin()
+				update := mock.ExpectQuery(`UPDATE orders\s+SET status = \$2, accrual = COALESCE\(\$3, accrual\)\s+WHERE id = \$1 AND status = \$4`).
+					WithArgs("order-1", tt.change.Status, pgxmock.AnyArg(), tt.change.From)
+				if tt.applied {
+					update.WillReturnRows(pgxmock.NewRows([]string{"user_id", "number", "accrual"}).
+						AddRow("user-1", "12345678903", decimal.NullDecimal{}))
+					mock.ExpectExec("INSERT INTO order_status_history").
+						WithArgs("order-1", tt.change.From, tt.change.Status, pgxmock.AnyArg(), domain.StatusSourceWorker, "", []byte(tt.change.Response)).
+						WillReturnResult(pgxmock.NewResult("INSERT", 1))
+					mock.ExpectQuery("INSERT INTO order_events").
+						WithArgs("order-1", OrderEventsChannel).
+						WillReturnRows(pgxmock.NewRows([]string{"pg_notify"}).AddRow(""))
+					mock.ExpectExec("INSERT INTO outbox").
+						WithArgs(pgxmock.AnyArg(), outbox.TypeOrderProcessing, outbox.SchemaVersion, "user-1", pgxmock.AnyArg(), pgxmock.AnyArg()).
+						WillReturnResult(pgxmock.NewResult("INSERT", 1))
+					mock.ExpectCommit()
+				} else {
+					update.WillReturnRows(pgxmock.NewRows([]string{"user_id", "number", "accrual"}))
+					mock.ExpectRollback()
+				}
+			}
+
+			err = NewOrderRepository(mock).UpdateOrderStatus(context.Background(), "order-1", tt.change, zaptest.NewLogger(t))
+			require.ErrorIs(t, err, tt.wantErr)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("go-musthave-diploma-tpl/internal/repository/postgres")
+
+// startSpan открывает спан метода репозитория; запросы внутри него
+// становятся дочерними спанами с текстом SQL.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	statement := strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(statement, " ")
+
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(statement),
+		),
+	)
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func execContext(ctx context.Context, db DB, query string, args ...any) (pgconn.CommandTag, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	tag, err := db.Exec(ctx, query, args...)
+	endQuerySpan(span, err)
+	return tag, err
+}
+
+func queryContext(ctx context.Context, db DB, query string, args ...any) (pgx.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := db.Query(ctx, query, args...)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+// queryRowContext закрывает спан в Scan: pgx откладывает выполнение
+// запроса QueryRow до чтения строки.
+func queryRowContext(ctx context.Context, db DB, query string, args ...any) pgx.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	return tracedRow{row: db.QueryRow(ctx, query, args...), span: span}
+}
+
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	endQuerySpan(r.span, err)
+	return err
+}
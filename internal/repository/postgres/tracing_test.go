@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/tracing/tracingtest"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRepositorySpans(t *testing.T) {
+	exporter := tracingtest.Install(t)
+
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO orders").
+		WithArgs("user-1", "12345678903").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err = NewOrderRepository(mock).CreateOrder(context.Background(), "user-1", "12345678903", zaptest.NewLogger(t))
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	query, method := spans[0], spans[1]
+
+	assert.Equal(t, "OrderRepository.CreateOrder", method.Name)
+	assert.Equal(t, "INSERT", query.Name)
+	assert.Equal(t, method.SpanContext.SpanID(), query.Parent.SpanID())
+	assert.Contains(t, query.Attributes, semconv.DBQueryText("INSERT INTO orders (user_id, number) VALUES ($1, $2)"))
+	assert.Contains(t, query.Attributes, semconv.DBSystemNamePostgreSQL)
+}
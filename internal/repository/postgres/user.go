@@ -2,64 +2,78 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"strings"
 
-	"go.uber.org/zap"
-)
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
 
-var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
 )
 
-type User struct {
-	ID           string
-	Login        string
-	PasswordHash string
-}
-
 type UserRepository struct {
-	DB *sql.DB
+	DB DB
 }
 
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db DB) *UserRepository {
 	return &UserRepository{
 		DB: db,
 	}
 }
 
+// CreateUser регистрирует пользователя и в той же транзакции пишет
+// событие user.registered в outbox.
 func (r *UserRepository) CreateUser(ctx context.Context, login, passwordHash string, logger *zap.Logger) (string, error) {
+	ctx, span := startSpan(ctx, "UserRepository.CreateUser")
+	defer span.End()
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
 	var userID string
 
-	err := r.DB.QueryRowContext(ctx,
+	err = queryRowContext(ctx, tx,
 		"INSERT INTO users (login, password_hash) VALUES ($1, $2) RETURNING id",
 		login, passwordHash).Scan(&userID)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") &&
-			strings.Contains(err.Error(), "users_login_key") {
+		if err := translateError(err); errors.Is(err, domain.ErrUserExists) {
 			logger.Warn("user already exists", zap.String("login", login))
-			return "", ErrUserExists
+			return "", err
 		}
 
 		logger.Error("failed to insert user", zap.Error(err))
 		return "", err
 	}
+
+	if err := insertOutbox(ctx, tx, outbox.NewUserRegistered(userID, outbox.UserRegistered{Login: login}), logger); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("failed to commit user", zap.Error(err))
+		return "", err
+	}
 	logger.Info("user created", zap.String("id", userID))
 	return userID, nil
 }
 
-func (r *UserRepository) GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*User, error) {
-	u := &User{}
-	err := r.DB.QueryRowContext(ctx,
+func (r *UserRepository) GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*domain.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetUserByLogin")
+	defer span.End()
+
+	u := &domain.User{}
+	err := queryRowContext(ctx, r.DB,
 		"SELECT id, login, password_hash FROM users WHERE login = $1",
 		login).Scan(&u.ID, &u.Login, &u.PasswordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Warn("failed to find user by login", zap.String("login", login))
+		return nil, domain.ErrUserNotFound
+	}
 	if err != nil {
-		if errors.Is(ErrUserNotFound, sql.ErrNoRows) {
-			logger.Warn("failed to find user by login", zap.String("login", login))
-			return nil, err
-		}
+		logger.Error("failed to get user", zap.Error(err))
 		return nil, err
 	}
 	logger.Info("user found", zap.String("id", u.ID))
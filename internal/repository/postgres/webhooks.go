@@ -0,0 +1,308 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type WebhookRepository struct {
+	db DB
+}
+
+func NewWebhookRepository(db DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// owner переводит владельца подписки в параметр запроса: пустой — NULL, партнёр.
+func owner(userID string) any {
+	if userID == "" {
+		return nil
+	}
+	return userID
+}
+
+// CreateWebhook сохраняет подписку и возвращает её с присвоенными ID и CreatedAt.
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, sub domain.WebhookSubscription, logger *zap.Logger) (domain.WebhookSubscription, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.CreateWebhook")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhook_subscriptions (user_id, url, secret, event_types)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := queryRowContext(ctx, r.db, query, owner(sub.UserID), sub.URL, sub.Secret, sub.EventTypes).
+		Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		logger.Error("failed to create webhook subscription", zap.Error(err))
+		return domain.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+// ListWebhooks возвращает подписки владельца userID в порядке создания.
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, userID string, logger *zap.Logger) ([]domain.WebhookSubscription, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ListWebhooks")
+	defer span.End()
+
+	query := `
+		SELECT id, COALESCE(user_id::text, ''), url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		WHERE user_id IS NOT DISTINCT FROM $1
+		ORDER BY created_at, id
+	`
+	rows, err := queryContext(ctx, r.db, query, owner(userID))
+	if err != nil {
+		logger.Error("failed to query webhook subscriptions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.WebhookSubscription
+	for rows.Next() {
+		var s domain.WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.URL, &s.Secret, &s.EventTypes, &s.CreatedAt); err != nil {
+			logger.Error("failed to scan webhook subscription", zap.Error(err))
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeleteWebhook удаляет подписку владельца вместе с журналом её доставок.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, userID, id string, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.DeleteWebhook")
+	defer span.End()
+
+	query := `
+		DELETE FROM webhook_subscriptions
+		WHERE id = $1 AND user_id IS NOT DISTINCT FROM $2
+	`
+	res, err := execContext(ctx, r.db, query, id, owner(userID))
+	if err != nil {
+		logger.Error("failed to delete webhook subscription", zap.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries ставит событие в очередь доставки каждой
+// подписке, которая на него подписана: партнёрским и подпискам владельца
+// события. Повторная постановка того же события ничего не меняет.
+func (r *WebhookRepository) EnqueueWebhookDeliveries(ctx context.Context, ev outbox.Event, payload []byte, logger *zap.Logger) (int, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.EnqueueWebhookDeliveries")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1::uuid, $2::text, $3::jsonb
+		FROM webhook_subscriptions
+		WHERE (user_id IS NULL OR user_id = $4::uuid) AND $2::text = ANY(event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	res, err := execContext(ctx, r.db, query, ev.ID, ev.Type, payload, ev.UserID)
+	if err != nil {
+		logger.Error("failed to enqueue webhook deliveries", zap.String("event_id", ev.ID), zap.Error(err))
+		return 0, err
+	}
+	return int(res.RowsAffected()), nil
+}
+
+// ClaimWebhookDeliveries берёт в аренду до limit доставок, время которых
+// подошло, и увеличивает их счётчик попыток.
+func (r *WebhookRepository) ClaimWebhookDeliveries(
+	ctx context.Context,
+	workerID string,
+	limit int,
+	lease time.Duration,
+	logger *zap.Logger,
+) ([]domain.WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ClaimWebhookDeliveries")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET locked_by = $1,
+		    locked_until = NOW() + make_interval(secs => $3),
+		    attempts = d.attempts + 1
+		FROM (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending'
+			  AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) claimed, webhook_subscriptions s
+		WHERE d.id = claimed.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+	`
+	rows, err := queryContext(ctx, r.db, query, workerID, limit, lease.Seconds())
+	if err != nil {
+		logger.Error("failed to claim webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		d := domain.WebhookDelivery{Status: domain.DeliveryPending}
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			logger.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CompleteWebhookDelivery записывает итог попытки и снимает аренду.
+func (r *WebhookRepository) CompleteWebhookDelivery(ctx context.Context, id int64, res domain.DeliveryResult, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.CompleteWebhookDelivery")
+	defer span.End()
+
+	var next any
+	if res.Status == domain.DeliveryPending {
+		next = res.NextAttemptAt
+	}
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2,
+		    response_status = NULLIF($3, 0),
+		    last_error = NULLIF($4, ''),
+		    next_attempt_at = COALESCE($5, next_attempt_at),
+		    delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() ELSE delivered_at END,
+		    locked_by = NULL,
+		    locked_until = NULL
+		WHERE id = $1
+	`
+	if _, err := execContext(ctx, r.db, query, id, res.Status, res.ResponseStatus, res.Error, next); err != nil {
+		logger.Error("failed to record webhook delivery attempt", zap.Int64("delivery_id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListWebhookDeliveries возвращает журнал доставок подписки владельца,
+// начиная с последних.
+func (r *WebhookRepository) ListWebhookDeliveries(
+	ctx context.Context,
+	userID, webhookID string,
+	limit int,
+	logger *zap.Logger,
+) ([]domain.WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ListWebhookDeliveries")
+	defer span.End()
+
+	var exists bool
+	err := queryRowContext(ctx, r.db, `
+		SELECT TRUE FROM webhook_subscriptions
+		WHERE id = $1 AND user_id IS NOT DISTINCT FROM $2
+	`, webhookID, owner(userID)).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrWebhookNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get webhook subscription", zap.Error(err))
+		return nil, err
+	}
+
+	query := `
+		SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+		       COALESCE(response_status, 0), COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+	rows, err := queryContext(ctx, r.db, query, webhookID, limit)
+	if err != nil {
+		logger.Error("failed to query webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		var (
+			d           domain.WebhookDelivery
+			deliveredAt *time.Time
+		)
+		if err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.ResponseStatus,
+			&d.LastError,
+			&d.CreatedAt,
+			&deliveredAt,
+		); err != nil {
+			logger.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		if deliveredAt != nil {
+			d.DeliveredAt = *deliveredAt
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook возвращает доставку в очередь с обнулённым счётчиком
+// попыток, в том числе уже успешную или исчерпавшую попытки.
+func (r *WebhookRepository) RedeliverWebhook(ctx context.Context, userID, webhookID string, deliveryID int64, logger *zap.Logger) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.RedeliverWebhook")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET status = 'pending',
+		    attempts = 0,
+		    next_attempt_at = NOW(),
+		    locked_by = NULL,
+		    locked_until = NULL
+		FROM webhook_subscriptions s
+		WHERE d.id = $1
+		  AND d.subscription_id = $2
+		  AND s.id = d.subscription_id
+		  AND s.user_id IS NOT DISTINCT FROM $3
+	`
+	res, err := execContext(ctx, r.db, query, deliveryID, webhookID, owner(userID))
+	if err != nil {
+		logger.Error("failed to schedule webhook redelivery", zap.Int64("delivery_id", deliveryID), zap.Error(err))
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
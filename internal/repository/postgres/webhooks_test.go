@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWebhookRepository_EnqueueWebhookDeliveries(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ev := outbox.NewUserRegistered("user-1", outbox.UserRegistered{Login: "alice"})
+	payload := []byte(`{"id":"` + ev.ID + `"}`)
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(ev.ID, ev.Type, payload, "user-1").
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+
+	n, err := NewWebhookRepository(mock).EnqueueWebhookDeliveries(context.Background(), ev, payload, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_OwnerScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		wantOwner any
+	}{
+		{name: "#1 подписка пользователя", userID: "user-1", wantOwner: "user-1"},
+		{name: "#2 партнёрская подписка — user_id IS NULL", userID: "", wantOwner: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectExec("DELETE FROM webhook_subscriptions").
+				WithArgs("hook-1", tt.wantOwner).
+				WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+			err = NewWebhookRepository(mock).DeleteWebhook(context.Background(), tt.userID, "hook-1", zaptest.NewLogger(t))
+			assert.ErrorIs(t, err, domain.ErrWebhookNotFound)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
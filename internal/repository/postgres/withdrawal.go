@@ -2,57 +2,122 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"strings"
 	"time"
 
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
-var (
-	ErrNotEnoughFunds = errors.New("not enough funds")
-	ErrInvalidOrder   = errors.New("invalid order number")
-)
-
-type Withdrawal struct {
-	ID          string
-	UserID      string
-	OrderNumber string
-	Sum         string
-	ProcessedAt time.Time
-}
-
 type WithdrawalRepository struct {
-	db *sql.DB
+	db DB
 }
 
-func NewWithdrawalRepository(db *sql.DB) *WithdrawalRepository {
+func NewWithdrawalRepository(db DB) *WithdrawalRepository {
 	return &WithdrawalRepository{db: db}
 }
 
-func (r *WithdrawalRepository) Create(
+// Withdraw списывает sum с баланса пользователя. Строка user_balances
+// блокируется на время транзакции, поэтому параллельные списания одного
+// пользователя выполняются по очереди и не уводят баланс в минус.
+// Вместе со списанием в outbox пишется событие withdrawal.created.
+func (r *WithdrawalRepository) Withdraw(
 	ctx context.Context,
 	userID, orderNumber string,
 	sum decimal.Decimal,
 	logger *zap.Logger,
 ) error {
+	ctx, span := startSpan(ctx, "WithdrawalRepository.Withdraw")
+	defer span.End()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = execContext(ctx, tx, `
+		INSERT INTO user_balances (user_id)
+		VALUES ($1)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID)
+	if err != nil {
+		logger.Error("failed to ensure balance row", zap.Error(err))
+		return err
+	}
+
+	var current decimal.Decimal
+	err = queryRowContext(ctx, tx, `
+		SELECT current
+		FROM user_balances
+		WHERE user_id = $1
+		FOR UPDATE
+	`, userID).Scan(&current)
+	if err != nil {
+		logger.Error("failed to lock balance", zap.Error(err))
+		return err
+	}
+
+	if current.LessThan(sum) {
+		logger.Warn("not enough funds",
+			zap.String("current", current.String()),
+			zap.String("sum", sum.String()),
+		)
+		return domain.ErrNotEnoughFunds
+	}
+
 	query := `
 		INSERT INTO withdrawals (user_id, order_number, sum)
 		VALUES ($1, $2, $3)
+		RETURNING id, sum, processed_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query, userID, orderNumber, sum)
+	var (
+		withdrawalID string
+		stored       decimal.Decimal
+		processedAt  time.Time
+	)
+	err = queryRowContext(ctx, tx, query, userID, orderNumber, sum).Scan(&withdrawalID, &stored, &processedAt)
 	if err != nil {
-		if strings.Contains(err.Error(), "withdrawals_user_id_order_number_key") {
+		if err := translateError(err); errors.Is(err, domain.ErrInvalidOrder) {
 			logger.Warn("withdrawal order already exists", zap.String("order", orderNumber))
-			return ErrInvalidOrder
+			return err
 		}
 		logger.Error("failed to create withdrawal", zap.Error(err))
 		return err
 	}
 
+	err = postLedger(ctx, tx, domain.Posting{
+		Kind:      domain.LedgerKindWithdrawal,
+		Reference: withdrawalID,
+		UserID:    userID,
+		From:      domain.AccountUserPoints,
+		To:        domain.AccountWithdrawalSink,
+		Amount:    sum,
+	}, logger)
+	if err != nil {
+		return err
+	}
+
+	ev := outbox.NewWithdrawalCreated(userID, outbox.WithdrawalCreated{
+		WithdrawalID: withdrawalID,
+		Order:        orderNumber,
+		Sum:          stored,
+		ProcessedAt:  processedAt,
+	})
+	if err := insertOutbox(ctx, tx, ev, logger); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("failed to commit withdrawal", zap.Error(err))
+		return err
+	}
+
 	logger.Info("withdrawal created",
 		zap.String("order", orderNumber),
 		zap.String("sum", sum.String()),
@@ -60,28 +125,33 @@ func (r *WithdrawalRepository) Create(
 	return nil
 }
 
+// ListByUser возвращает списания пользователя с фильтром по дате
+// и keyset-пагинацией по (processed_at, id). Статусы в q не учитываются.
 func (r *WithdrawalRepository) ListByUser(
 	ctx context.Context,
 	userID string,
+	q domain.ListQuery,
 	logger *zap.Logger,
-) ([]Withdrawal, error) {
-	query := `
+) ([]domain.Withdrawal, error) {
+	ctx, span := startSpan(ctx, "WithdrawalRepository.ListByUser")
+	defer span.End()
+
+	q.Statuses = nil
+	query, args := appendList(q, `
 		SELECT id, user_id, order_number, sum, processed_at
 		FROM withdrawals
-		WHERE user_id = $1
-		ORDER BY processed_at DESC
-	`
+		WHERE user_id = $1`, "processed_at", []any{userID})
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := queryContext(ctx, r.db, query, args...)
 	if err != nil {
 		logger.Error("failed to query withdrawals", zap.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
 
-	var res []Withdrawal
+	var res []domain.Withdrawal
 	for rows.Next() {
-		var w Withdrawal
+		var w domain.Withdrawal
 		if err := rows.Scan(
 			&w.ID,
 			&w.UserID,
@@ -101,30 +171,3 @@ func (r *WithdrawalRepository) ListByUser(
 
 	return res, nil
 }
-
-func (r *WithdrawalRepository) GetTotals(
-	ctx context.Context,
-	userID string,
-	logger *zap.Logger,
-) (accrued, withdrawn decimal.Decimal, err error) {
-	query := `
-		SELECT
-			COALESCE(SUM(o.accrual), 0),
-			COALESCE((
-				SELECT SUM(w.sum)
-				FROM withdrawals w
-				WHERE w.user_id = $1
-			), 0)
-		FROM orders o
-		WHERE o.user_id = $1
-		  AND o.status = 'PROCESSED'
-	`
-
-	err = r.db.QueryRowContext(ctx, query, userID).Scan(&accrued, &withdrawn)
-	if err != nil {
-		logger.Error("failed to calculate totals", zap.Error(err))
-		return decimal.Zero, decimal.Zero, err
-	}
-
-	return accrued, withdrawn, nil
-}
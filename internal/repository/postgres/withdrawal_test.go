@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWithdrawalRepository_Withdraw_NotEnoughFunds(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO user_balances").
+		WithArgs("user-1").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectQuery("SELECT current\\s+FROM user_balances\\s+WHERE user_id = \\$1\\s+FOR UPDATE").
+		WithArgs("user-1").
+		WillReturnRows(pgxmock.NewRows([]string{"current"}).AddRow("50"))
+	mock.ExpectRollback()
+
+	err = NewWithdrawalRepository(mock).Withdraw(
+		context.Background(), "user-1", "2377225624", decimal.NewFromInt(51), zaptest.NewLogger(t),
+	)
+	assert.ErrorIs(t, err, domain.ErrNotEnoughFunds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// openTestDB подключается к TEST_DATABASE_URI и накатывает миграции.
+// Без переменной окружения интеграционные тесты пропускаются.
+func openTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+
+	require.NoError(t, RunMigrations(dsn, zap.NewNop()))
+
+	db, err := NewDBStorage(context.Background(), dsn, PoolConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db.Pool
+}
+
+func TestWithdrawalRepository_Withdraw_Concurrent(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	userID, err := NewUserRepository(db).CreateUser(ctx, fmt.Sprintf("race-%d", time.Now().UnixNano()), "hash", logger)
+	require.NoError(t, err)
+
+	err = NewLedgerRepository(db).Post(ctx, domain.Posting{
+		Kind:      domain.LedgerKindAccrual,
+		Reference: "race-" + userID,
+		UserID:    userID,
+		From:      domain.AccountAccrualSource,
+		To:        domain.AccountUserPoints,
+		Amount:    decimal.NewFromInt(100),
+	}, logger)
+	require.NoError(t, err)
+
+	repo := NewWithdrawalRepository(db)
+
+	const attempts = 25
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.Withdraw(ctx, userID, fmt.Sprintf("order-%d", i), decimal.NewFromInt(10), logger)
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrNotEnoughFunds)
+		}(i)
+	}
+	wg.Wait()
+
+	current, withdrawn, err := NewLedgerRepository(db).GetBalance(ctx, userID, logger)
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, succeeded)
+	assert.False(t, current.IsNegative(), "balance went below zero: %s", current)
+	assert.True(t, current.IsZero())
+	assert.True(t, withdrawn.Equal(decimal.NewFromInt(100)))
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE users (login TEXT NOT NULL UNIQUE, age INTEGER CHECK (age > 0))`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO users (login, age) VALUES ('alice', 1)`)
+	require.NoError(t, err)
+
+	_, duplicate := db.Exec(`INSERT INTO users (login, age) VALUES ('alice', 2)`)
+	_, check := db.Exec(`INSERT INTO users (login, age) VALUES ('bob', 0)`)
+	_, notNull := db.Exec(`INSERT INTO users (login, age) VALUES (NULL, 1)`)
+
+	tests := []struct {
+		name    string
+		err     error
+		columns string
+		want    bool
+	}{
+		{name: "#1 повтор логина", err: duplicate, columns: "users.login", want: true},
+		{name: "#2 обёрнутая ошибка", err: fmt.Errorf("insert: %w", duplicate), columns: "users.login", want: true},
+		{name: "#3 другие колонки", err: duplicate, columns: "users.age", want: false},
+		{name: "#4 нарушение CHECK", err: check, columns: "users.login", want: false},
+		{name: "#5 нарушение NOT NULL", err: notNull, columns: "users.login", want: false},
+		{name: "#6 чужая ошибка с тем же текстом", err: errors.New("UNIQUE constraint failed: users.login"), columns: "users.login", want: false},
+		{name: "#7 нет ошибки", err: nil, columns: "users.login", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isUniqueViolation(tt.err, tt.columns))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"sync"
+
+	"go-musthave-diploma-tpl/internal/domain"
+)
+
+// Notifier рассылает события заказов подписчикам этого процесса. У SQLite
+// нет LISTEN/NOTIFY, а база принадлежит одному процессу, поэтому события
+// передаются в памяти после коммита транзакции.
+type Notifier struct {
+	mu        sync.Mutex
+	listeners map[int]func(domain.OrderEvent)
+	seq       int
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{listeners: map[int]func(domain.OrderEvent){}}
+}
+
+// Listen передаёт в publish события, записанные после подписки, пока не отменён ctx.
+func (n *Notifier) Listen(ctx context.Context, publish func(domain.OrderEvent)) error {
+	n.mu.Lock()
+	n.seq++
+	id := n.seq
+	n.listeners[id] = publish
+	n.mu.Unlock()
+
+	<-ctx.Done()
+
+	n.mu.Lock()
+	delete(n.listeners, id)
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *Notifier) publish(events ...domain.OrderEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ev := range events {
+		for _, fn := range n.listeners {
+			fn(ev)
+		}
+	}
+}
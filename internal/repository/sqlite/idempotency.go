@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve занимает ключ для нового запроса. Если ключ уже занят и не истёк,
+// возвращается существующая запись, а reserved == false.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, userID, endpoint, key, requestHash string, ttl time.Duration, logger *zap.Logger) (*domain.IdempotencyRecord, bool, error) {
+	ts := now()
+	var expiresAt int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, endpoint, key, request_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, endpoint, key) DO UPDATE
+		SET request_hash = excluded.request_hash,
+		    status_code = NULL,
+		    content_type = NULL,
+		    response_body = NULL,
+		    created_at = excluded.created_at,
+		    expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at < excluded.created_at
+		RETURNING expires_at
+	`, userID, endpoint, key, requestHash, ts, ts+ttl.Microseconds()).Scan(&expiresAt)
+	if err == nil {
+		return &domain.IdempotencyRecord{RequestHash: requestHash, ExpiresAt: fromMicros(expiresAt)}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("failed to reserve idempotency key", zap.Error(err))
+		return nil, false, err
+	}
+
+	rec := &domain.IdempotencyRecord{}
+	var (
+		status      sql.NullInt64
+		contentType sql.NullString
+	)
+	err = r.db.QueryRowContext(ctx, `
+		SELECT request_hash, status_code, content_type, response_body, expires_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND endpoint = ? AND key = ?
+	`, userID, endpoint, key).Scan(&rec.RequestHash, &status, &contentType, &rec.ResponseBody, &expiresAt)
+	if err != nil {
+		logger.Error("failed to load idempotency key", zap.Error(err))
+		return nil, false, err
+	}
+	rec.StatusCode = int(status.Int64)
+	rec.ContentType = contentType.String
+	rec.ExpiresAt = fromMicros(expiresAt)
+
+	return rec, false, nil
+}
+
+// Complete сохраняет ответ, который будет воспроизводиться для повторов.
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID, endpoint, key string, statusCode int, contentType string, body []byte, logger *zap.Logger) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = ?, content_type = ?, response_body = ?
+		WHERE user_id = ? AND endpoint = ? AND key = ?
+	`, statusCode, contentType, body, userID, endpoint, key)
+	if err != nil {
+		logger.Error("failed to save idempotent response", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Release освобождает ключ, чтобы клиент мог повторить неудавшийся запрос.
+func (r *IdempotencyRepository) Release(ctx context.Context, userID, endpoint, key string, logger *zap.Logger) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = ? AND endpoint = ? AND key = ?`,
+		userID, endpoint, key)
+	if err != nil {
+		logger.Error("failed to release idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком хранения.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, logger *zap.Logger) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < ?`, now())
+	if err != nil {
+		logger.Error("failed to delete expired idempotency keys", zap.Error(err))
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
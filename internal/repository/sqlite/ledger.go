@@ -0,0 +1,242 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// Post проводит posting в отдельной транзакции.
+func (r *LedgerRepository) Post(ctx context.Context, p domain.Posting, logger *zap.Logger) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin ledger transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := postLedger(ctx, tx, p, logger); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit ledger transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetBalance читает поддерживаемую журналом строку баланса пользователя.
+func (r *LedgerRepository) GetBalance(ctx context.Context, userID string, logger *zap.Logger) (current, withdrawn decimal.Decimal, err error) {
+	current, withdrawn, err = getBalance(ctx, r.db, userID)
+	if err != nil {
+		logger.Error("failed to get balance", zap.Error(err))
+		return decimal.Zero, decimal.Zero, err
+	}
+	return current, withdrawn, nil
+}
+
+// ListEntries возвращает все записи журнала пользователя в порядке проводки.
+func (r *LedgerRepository) ListEntries(ctx context.Context, userID string, logger *zap.Logger) ([]domain.LedgerEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT e.id, e.transaction_id, t.kind, t.reference, e.account, e.user_id, e.amount, e.created_at
+		FROM ledger_entries e
+		JOIN ledger_transactions t ON t.id = e.transaction_id
+		WHERE e.user_id = ?
+		ORDER BY e.id
+	`, userID)
+	if err != nil {
+		logger.Error("failed to query ledger entries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []domain.LedgerEntry
+	for rows.Next() {
+		var (
+			e         domain.LedgerEntry
+			createdAt int64
+		)
+		if err := rows.Scan(&e.ID, &e.TransactionID, &e.Kind, &e.Reference, &e.Account, &e.UserID, &e.Amount, &createdAt); err != nil {
+			logger.Error("failed to scan ledger entry", zap.Error(err))
+			return nil, err
+		}
+		e.CreatedAt = fromMicros(createdAt)
+		res = append(res, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return res, nil
+}
+
+// Reconcile сверяет user_balances с журналом и возвращает пользователей,
+// у которых сохранённый баланс расходится с суммой проводок. Суммы
+// складываются в Go: SUM в SQLite считает в плавающей точке.
+func (r *LedgerRepository) Reconcile(ctx context.Context, logger *zap.Logger) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT b.user_id, b.current, b.withdrawn, e.account, e.amount
+		FROM user_balances b
+		LEFT JOIN ledger_entries e
+		       ON e.user_id = b.user_id AND e.account IN (?, ?)
+		ORDER BY b.user_id
+	`, domain.AccountUserPoints, domain.AccountWithdrawalSink)
+	if err != nil {
+		logger.Error("failed to reconcile ledger", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	type sums struct{ stored, ledger [2]decimal.Decimal }
+	byUser := map[string]*sums{}
+	var order []string
+	for rows.Next() {
+		var (
+			userID             string
+			current, withdrawn decimal.Decimal
+			account            sql.NullString
+			amount             decimal.NullDecimal
+		)
+		if err := rows.Scan(&userID, &current, &withdrawn, &account, &amount); err != nil {
+			return nil, err
+		}
+		s, ok := byUser[userID]
+		if !ok {
+			s = &sums{stored: [2]decimal.Decimal{current, withdrawn}}
+			byUser[userID] = s
+			order = append(order, userID)
+		}
+		switch account.String {
+		case domain.AccountUserPoints:
+			s.ledger[0] = s.ledger[0].Add(amount.Decimal)
+		case domain.AccountWithdrawalSink:
+			s.ledger[1] = s.ledger[1].Add(amount.Decimal)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	var mismatched []string
+	for _, userID := range order {
+		s := byUser[userID]
+		if !s.stored[0].Equal(s.ledger[0]) || !s.stored[1].Equal(s.ledger[1]) {
+			mismatched = append(mismatched, userID)
+		}
+	}
+	if len(mismatched) > 0 {
+		logger.Warn("ledger balance mismatch", zap.Strings("users", mismatched))
+	}
+	return mismatched, nil
+}
+
+func getBalance(ctx context.Context, db rowQueryer, userID string) (current, withdrawn decimal.Decimal, err error) {
+	err = db.QueryRowContext(ctx, `SELECT current, withdrawn FROM user_balances WHERE user_id = ?`, userID).
+		Scan(&current, &withdrawn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, decimal.Zero, nil
+	}
+	return current, withdrawn, err
+}
+
+// postLedger записывает транзакцию, две её записи и обновляет баланс
+// пользователя внутри уже открытой транзакции tx. Баланс пересчитывается
+// в Go, чтобы не терять точность на арифметике SQLite.
+func postLedger(ctx context.Context, tx tx, p domain.Posting, logger *zap.Logger) error {
+	if !p.Amount.IsPositive() || p.From == p.To || p.UserID == "" {
+		logger.Error("invalid ledger posting",
+			zap.String("kind", p.Kind),
+			zap.String("reference", p.Reference),
+			zap.String("amount", p.Amount.String()),
+		)
+		return domain.ErrInvalidPosting
+	}
+
+	txID, ts := newID(), now()
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO ledger_transactions (id, kind, reference, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, reference) DO NOTHING
+	`, txID, p.Kind, p.Reference, ts)
+	if err != nil {
+		logger.Error("failed to insert ledger transaction", zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		logger.Warn("ledger posting already exists",
+			zap.String("kind", p.Kind),
+			zap.String("reference", p.Reference),
+		)
+		return domain.ErrPostingExists
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ledger_entries (transaction_id, account, user_id, amount, created_at)
+		VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)
+	`, txID, p.From, p.UserID, numeric(p.Amount.Neg()), ts,
+		txID, p.To, p.UserID, numeric(p.Amount), ts)
+	if err != nil {
+		logger.Error("failed to insert ledger entries", zap.Error(err))
+		return err
+	}
+
+	current, withdrawn, err := getBalance(ctx, tx, p.UserID)
+	if err != nil {
+		logger.Error("failed to read user balance", zap.Error(err))
+		return err
+	}
+	currentDelta, withdrawnDelta := balanceDelta(p)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_balances (user_id, current, withdrawn, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE
+		SET current = excluded.current,
+		    withdrawn = excluded.withdrawn,
+		    updated_at = excluded.updated_at
+	`, p.UserID, numeric(current.Add(currentDelta)), numeric(withdrawn.Add(withdrawnDelta)), ts)
+	if err != nil {
+		logger.Error("failed to update user balance", zap.Error(err))
+		return err
+	}
+
+	logger.Info("ledger posting created",
+		zap.String("transaction_id", txID),
+		zap.String("kind", p.Kind),
+		zap.String("reference", p.Reference),
+		zap.String("amount", p.Amount.String()),
+	)
+	return nil
+}
+
+// balanceDelta переводит проводку в изменения строки user_balances.
+func balanceDelta(p domain.Posting) (current, withdrawn decimal.Decimal) {
+	current, withdrawn = decimal.Zero, decimal.Zero
+	if p.To == domain.AccountUserPoints {
+		current = current.Add(p.Amount)
+	}
+	if p.From == domain.AccountUserPoints {
+		current = current.Sub(p.Amount)
+	}
+	if p.To == domain.AccountWithdrawalSink {
+		withdrawn = withdrawn.Add(p.Amount)
+	}
+	if p.From == domain.AccountWithdrawalSink {
+		withdrawn = withdrawn.Sub(p.Amount)
+	}
+	return current, withdrawn
+}
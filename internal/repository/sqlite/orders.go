@@ -0,0 +1,485 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+const pendingCondition = "status IN ('NEW', 'PROCESSING')"
+
+type OrderRepository struct {
+	db     *sql.DB
+	events *Notifier
+}
+
+func NewOrderRepository(db *sql.DB, events *Notifier) *OrderRepository {
+	return &OrderRepository{db: db, events: events}
+}
+
+func (r *OrderRepository) CreateOrder(ctx context.Context, userID, number string, logger *zap.Logger) error {
+	if err := insertOrder(ctx, r.db, userID, number); err != nil {
+		if isUniqueViolation(err, "orders.number") {
+			logger.Warn("order already exists", zap.String("number", number))
+			return domain.ErrOrderExists
+		}
+		logger.Error("failed to insert order", zap.Error(err))
+		return err
+	}
+	logger.Info("order created", zap.String("number", number))
+	return nil
+}
+
+// CreateOrders загружает пачку номеров в одной транзакции. Для каждого номера
+// возвращается nil, ErrOrderUploadedByUser или ErrOrderExists.
+func (r *OrderRepository) CreateOrders(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make(map[string]error, len(numbers))
+	for _, number := range numbers {
+		if _, seen := results[number]; seen {
+			continue
+		}
+		var owner string
+		err := tx.QueryRowContext(ctx, `SELECT user_id FROM orders WHERE number = ?`, number).Scan(&owner)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if err := insertOrder(ctx, tx, userID, number); err != nil {
+				logger.Error("failed to insert order batch", zap.Error(err))
+				return nil, err
+			}
+			results[number] = nil
+		case err != nil:
+			logger.Error("failed to insert order batch", zap.Error(err))
+			return nil, err
+		case owner == userID:
+			results[number] = domain.ErrOrderUploadedByUser
+		default:
+			results[number] = domain.ErrOrderExists
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit order batch", zap.Error(err))
+		return nil, err
+	}
+	logger.Info("order batch uploaded", zap.Int("numbers", len(numbers)))
+	return results, nil
+}
+
+func insertOrder(ctx context.Context, db execer, userID, number string) error {
+	ts := now()
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO orders (id, number, user_id, uploaded_at, next_poll_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, newID(), number, userID, ts, ts)
+	return err
+}
+
+func (r *OrderRepository) GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error) {
+	return r.ListOrders(ctx, userID, domain.ListQuery{}, logger)
+}
+
+// ListOrders возвращает заказы пользователя с фильтрами и keyset-пагинацией по (uploaded_at, id).
+func (r *OrderRepository) ListOrders(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+	query, args := appendList(q, `
+		SELECT id, number, user_id, status, accrual, uploaded_at, 0
+		FROM orders
+		WHERE user_id = ?`, "uploaded_at", []any{userID})
+
+	orders, err := queryOrders(ctx, r.db, query, args...)
+	if err != nil {
+		logger.Error("failed to query order", zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetOrderByNumber возвращает заказ пользователя по номеру; чужой заказ даёт ErrOrderNotFound.
+func (r *OrderRepository) GetOrderByNumber(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error) {
+	var (
+		o          domain.Order
+		uploadedAt int64
+	)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, number, user_id, status, accrual, uploaded_at
+		FROM orders
+		WHERE number = ? AND user_id = ?
+	`, number, userID).Scan(&o.ID, &o.Number, &o.UserID, &o.Status, &o.Accrual, &uploadedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Order{}, domain.ErrOrderNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get order by number", zap.Error(err))
+		return domain.Order{}, err
+	}
+	o.UploadedAt = fromMicros(uploadedAt)
+	return o, nil
+}
+
+// ListStatusHistory возвращает историю статусов заказа в хронологическом порядке.
+func (r *OrderRepository) ListStatusHistory(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, old_status, new_status, accrual, source, reason, accrual_response, changed_at
+		FROM order_status_history
+		WHERE order_id = ?
+		ORDER BY id
+	`, orderID)
+	if err != nil {
+		logger.Error("failed to query order status history", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.StatusHistoryEntry
+	for rows.Next() {
+		var (
+			e         domain.StatusHistoryEntry
+			response  []byte
+			changedAt int64
+		)
+		if err := rows.Scan(&e.ID, &e.OldStatus, &e.NewStatus, &e.Accrual, &e.Source, &e.Reason, &response, &changedAt); err != nil {
+			logger.Error("failed to scan order status history", zap.Error(err))
+			return nil, err
+		}
+		e.Response = response
+		e.ChangedAt = fromMicros(changedAt)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListOrderEvents возвращает события пользователя с ID больше afterID по возрастанию ID.
+func (r *OrderRepository) ListOrderEvents(ctx context.Context, userID string, afterID int64, logger *zap.Logger) ([]domain.OrderEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, order_id, user_id, number, status, accrual, created_at
+		FROM order_events
+		WHERE user_id = ? AND id > ?
+		ORDER BY id
+	`, userID, afterID)
+	if err != nil {
+		logger.Error("failed to list order events", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.OrderEvent
+	for rows.Next() {
+		var (
+			ev        domain.OrderEvent
+			createdAt int64
+		)
+		if err := rows.Scan(&ev.ID, &ev.OrderID, &ev.UserID, &ev.Number, &ev.Status, &ev.Accrual, &createdAt); err != nil {
+			logger.Error("failed to scan order event", zap.Error(err))
+			return nil, err
+		}
+		ev.CreatedAt = fromMicros(createdAt)
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
+
+// ClaimOrdersForProcessing берёт в аренду до limit необработанных заказов,
+// пропуская заказы с действующей арендой другого воркера. Запрос выполняется
+// одной инструкцией, а писатель в SQLite один, поэтому SKIP LOCKED не нужен.
+func (r *OrderRepository) ClaimOrdersForProcessing(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.Order, error) {
+	ts := now()
+	orders, err := queryOrders(ctx, r.db, `
+		UPDATE orders
+		SET locked_by = ?, locked_until = ?
+		WHERE id IN (
+			SELECT id
+			FROM orders
+			WHERE `+pendingCondition+`
+			  AND next_poll_at <= ?
+			  AND (locked_until IS NULL OR locked_until < ?)
+			ORDER BY next_poll_at, uploaded_at
+			LIMIT ?
+		)
+		RETURNING id, number, user_id, status, accrual, uploaded_at, attempts
+	`, workerID, ts+lease.Microseconds(), ts, ts, limit)
+	if err != nil {
+		logger.Error("failed to claim orders for processing", zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ReleaseOrder снимает аренду, если она всё ещё принадлежит workerID.
+func (r *OrderRepository) ReleaseOrder(ctx context.Context, orderID, workerID string, logger *zap.Logger) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE orders
+		SET locked_by = NULL, locked_until = NULL
+		WHERE id = ? AND locked_by = ?
+	`, orderID, workerID)
+	if err != nil {
+		logger.Error("failed to release order", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ReleaseWorkerOrders снимает все аренды воркера, например при остановке.
+func (r *OrderRepository) ReleaseWorkerOrders(ctx context.Context, workerID string, logger *zap.Logger) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE orders
+		SET locked_by = NULL, locked_until = NULL
+		WHERE locked_by = ?
+	`, workerID)
+	if err != nil {
+		logger.Error("failed to release worker orders", zap.String("worker_id", workerID), zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		logger.Info("released worker orders", zap.String("worker_id", workerID), zap.Int64("count", n))
+	}
+	return nil
+}
+
+// ScheduleRetry откладывает следующий опрос заказа до nextPollAt и
+// увеличивает счётчик попыток. Пустой lastErr очищает last_error.
+func (r *OrderRepository) ScheduleRetry(ctx context.Context, orderID string, nextPollAt time.Time, lastErr string, logger *zap.Logger) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE orders
+		SET attempts = attempts + 1,
+		    next_poll_at = ?,
+		    last_error = NULLIF(?, '')
+		WHERE id = ?
+	`, micros(nextPollAt), lastErr, orderID)
+	if err != nil {
+		logger.Error("failed to schedule order retry", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// MarkOrderFailed переводит заказ, который так и не удалось рассчитать,
+// в терминальный статус INVALID с сохранением причины.
+func (r *OrderRepository) MarkOrderFailed(ctx context.Context, orderID, lastErr string, logger *zap.Logger) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldStatus domain.OrderStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ? AND `+pendingCondition, orderID).Scan(&oldStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		// заказ уже в терминальном статусе
+		return nil
+	}
+	if err != nil {
+		logger.Error("failed to mark order as failed", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE orders SET status = ?, last_error = ? WHERE id = ?`,
+		domain.OrderStatusInvalid, lastErr, orderID)
+	if err != nil {
+		logger.Error("failed to mark order as failed", zap.String("order_id", orderID), zap.Error(err))
+		return err
+	}
+
+	change := domain.StatusChange{From: oldStatus, Status: domain.OrderStatusInvalid, Source: domain.StatusSourceWorker, Reason: lastErr}
+	ev, err := recordChange(ctx, tx, orderID, change, logger)
+	if err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, domain.OrderOutboxEvent(ev, change), logger); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit order failure", zap.Error(err))
+		return err
+	}
+
+	r.events.publish(ev)
+	logger.Warn("order polling gave up", zap.String("order_id", orderID), zap.String("reason", lastErr))
+	return nil
+}
+
+// UpdateOrderStatus переводит заказ из change.From в change.Status и, если заказ
+// рассчитан, в той же транзакции проводит начисление по журналу. Обновление
+// условное: если статус в базе уже не change.From, возвращается ErrStatusConflict.
+func (r *OrderRepository) UpdateOrderStatus(ctx context.Context, orderID string, change domain.StatusChange, logger *zap.Logger) error {
+	if !change.From.CanTransitionTo(change.Status) {
+		logger.Warn("order status transition not allowed",
+			zap.String("order_id", orderID),
+			zap.String("from", string(change.From)),
+			zap.String("to", string(change.Status)),
+		)
+		return domain.ErrTransitionNotAllowed
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	// пустое начисление не затирает уже сохранённое
+	var userID string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE orders
+		SET status = ?, accrual = COALESCE(?, accrual)
+		WHERE id = ? AND status = ?
+		RETURNING user_id
+	`, change.Status, nullNumeric(change.Accrual), orderID, change.From).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Warn("order status changed concurrently",
+			zap.String("order_id", orderID),
+			zap.String("expected", string(change.From)),
+			zap.String("to", string(change.Status)),
+		)
+		return domain.ErrStatusConflict
+	}
+	if err != nil {
+		logger.Error("failed to update order", zap.Error(err))
+		return err
+	}
+
+	ev, err := recordChange(ctx, tx, orderID, change, logger)
+	if err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, domain.OrderOutboxEvent(ev, change), logger); err != nil {
+		return err
+	}
+
+	if change.Status == domain.OrderStatusProcessed && change.Accrual != nil && change.Accrual.IsPositive() {
+		err = postLedger(ctx, tx, domain.Posting{
+			Kind:      domain.LedgerKindAccrual,
+			Reference: orderID,
+			UserID:    userID,
+			From:      domain.AccountAccrualSource,
+			To:        domain.AccountUserPoints,
+			Amount:    *change.Accrual,
+		}, logger)
+		if err != nil && !errors.Is(err, domain.ErrPostingExists) {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit order update", zap.Error(err))
+		return err
+	}
+
+	r.events.publish(ev)
+	return nil
+}
+
+// GetBacklog возвращает число заказов NEW/PROCESSING по статусам
+// и время загрузки самого старого из них (нулевое, если очередь пуста).
+func (r *OrderRepository) GetBacklog(ctx context.Context, logger *zap.Logger) (map[string]int, time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*), MIN(uploaded_at)
+		FROM orders
+		WHERE `+pendingCondition+`
+		GROUP BY status
+	`)
+	if err != nil {
+		logger.Error("failed to query order backlog", zap.Error(err))
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	var oldest time.Time
+	for rows.Next() {
+		var (
+			status   string
+			count    int
+			uploaded int64
+		)
+		if err := rows.Scan(&status, &count, &uploaded); err != nil {
+			logger.Error("failed to scan order backlog", zap.Error(err))
+			return nil, time.Time{}, err
+		}
+		counts[status] = count
+		if t := fromMicros(uploaded); oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("failed to read order backlog", zap.Error(err))
+		return nil, time.Time{}, err
+	}
+	return counts, oldest, nil
+}
+
+// recordChange пишет переход в историю и сохраняет текущее состояние заказа
+// как событие. Событие публикуется вызывающим после коммита tx.
+func recordChange(ctx context.Context, tx tx, orderID string, change domain.StatusChange, logger *zap.Logger) (domain.OrderEvent, error) {
+	var response any
+	if len(change.Response) > 0 {
+		response = []byte(change.Response)
+	}
+	ts := now()
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO order_status_history (order_id, old_status, new_status, accrual, source, reason, accrual_response, changed_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`, orderID, change.From, change.Status, nullNumeric(change.Accrual), change.Source, change.Reason, response, ts)
+	if err != nil {
+		logger.Error("failed to insert order status history", zap.String("order_id", orderID), zap.Error(err))
+		return domain.OrderEvent{}, err
+	}
+
+	ev := domain.OrderEvent{CreatedAt: fromMicros(ts)}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO order_events (order_id, user_id, number, status, accrual, created_at)
+		SELECT id, user_id, number, status, accrual, ?
+		FROM orders
+		WHERE id = ?
+		RETURNING id, order_id, user_id, number, status, accrual
+	`, ts, orderID).Scan(&ev.ID, &ev.OrderID, &ev.UserID, &ev.Number, &ev.Status, &ev.Accrual)
+	if err != nil {
+		logger.Error("failed to record order event", zap.String("order_id", orderID), zap.Error(err))
+		return domain.OrderEvent{}, err
+	}
+	return ev, nil
+}
+
+// queryOrders выполняет запрос, возвращающий колонки
+// id, number, user_id, status, accrual, uploaded_at, attempts.
+func queryOrders(ctx context.Context, db *sql.DB, query string, args ...any) ([]domain.Order, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []domain.Order
+	for rows.Next() {
+		var (
+			o          domain.Order
+			uploadedAt int64
+		)
+		if err := rows.Scan(&o.ID, &o.Number, &o.UserID, &o.Status, &o.Accrual, &uploadedAt, &o.Attempts); err != nil {
+			return nil, err
+		}
+		o.UploadedAt = fromMicros(uploadedAt)
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// insertOutbox записывает событие в outbox внутри транзакции tx.
+func insertOutbox(ctx context.Context, tx execer, ev outbox.Event, logger *zap.Logger) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (event_id, event_type, schema_version, user_id, data, occurred_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, ev.ID, ev.Type, ev.SchemaVersion, ev.UserID, string(ev.Data), micros(ev.OccurredAt), micros(ev.OccurredAt))
+	if err != nil {
+		logger.Error("failed to insert outbox event", zap.String("type", ev.Type), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ClaimOutbox берёт в аренду до limit неопубликованных событий в порядке
+// записи и увеличивает их счётчик попыток.
+func (r *OutboxRepository) ClaimOutbox(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]outbox.Message, error) {
+	ts := now()
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE outbox
+		SET locked_by = ?, locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE published_at IS NULL
+			  AND next_attempt_at <= ?
+			  AND (locked_until IS NULL OR locked_until < ?)
+			ORDER BY id
+			LIMIT ?
+		)
+		RETURNING id, attempts, event_id, event_type, schema_version, user_id, data, occurred_at
+	`, workerID, ts+lease.Microseconds(), ts, ts, limit)
+	if err != nil {
+		logger.Error("failed to claim outbox events", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []outbox.Message
+	for rows.Next() {
+		var (
+			m          outbox.Message
+			data       string
+			occurredAt int64
+		)
+		if err := rows.Scan(&m.Seq, &m.Attempts, &m.Event.ID, &m.Event.Type, &m.Event.SchemaVersion, &m.Event.UserID, &data, &occurredAt); err != nil {
+			logger.Error("failed to scan outbox event", zap.Error(err))
+			return nil, err
+		}
+		m.Event.Data = json.RawMessage(data)
+		m.Event.OccurredAt = fromMicros(occurredAt)
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Seq < msgs[j].Seq })
+	return msgs, nil
+}
+
+// MarkOutboxPublished отмечает событие доставленным и снимает аренду.
+func (r *OutboxRepository) MarkOutboxPublished(ctx context.Context, seq int64, logger *zap.Logger) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET published_at = ?, locked_by = NULL, locked_until = NULL, last_error = NULL
+		WHERE id = ?
+	`, now(), seq)
+	if err != nil {
+		logger.Error("failed to mark outbox event published", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RetryOutbox снимает аренду и откладывает следующую доставку до nextAttemptAt.
+func (r *OutboxRepository) RetryOutbox(ctx context.Context, seq int64, nextAttemptAt time.Time, lastErr string, logger *zap.Logger) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET next_attempt_at = ?, last_error = NULLIF(?, ''), locked_by = NULL, locked_until = NULL
+		WHERE id = ?
+	`, micros(nextAttemptAt), lastErr, seq)
+	if err != nil {
+		logger.Error("failed to schedule outbox retry", zap.Error(err))
+		return err
+	}
+	return nil
+}
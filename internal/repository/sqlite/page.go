@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/domain"
+)
+
+// appendList дописывает к запросу с условием по user_id фильтры, keyset-условие,
+// сортировку по (timeColumn, id) и лимит из q — то же, что appendList в пакете postgres, но для SQLite.
+func appendList(q domain.ListQuery, base, timeColumn string, args []any) (string, []any) {
+	var b strings.Builder
+	b.WriteString(base)
+
+	if len(q.Statuses) > 0 {
+		placeholders := make([]string, len(q.Statuses))
+		for i, s := range q.Statuses {
+			placeholders[i] = "?"
+			args = append(args, string(s))
+		}
+		fmt.Fprintf(&b, " AND status IN (%s)", strings.Join(placeholders, ", "))
+	}
+	if !q.From.IsZero() {
+		fmt.Fprintf(&b, " AND %s >= ?", timeColumn)
+		args = append(args, micros(q.From))
+	}
+	if !q.To.IsZero() {
+		fmt.Fprintf(&b, " AND %s < ?", timeColumn)
+		args = append(args, micros(q.To))
+	}
+
+	direction, cmp := "DESC", "<"
+	if q.Ascending {
+		direction, cmp = "ASC", ">"
+	}
+	if q.After != nil {
+		fmt.Fprintf(&b, " AND (%s, id) %s (?, ?)", timeColumn, cmp)
+		args = append(args, micros(q.After.At), q.After.ID)
+	}
+
+	fmt.Fprintf(&b, " ORDER BY %s %s, id %s", timeColumn, direction, direction)
+	if q.Limit > 0 {
+		b.WriteString(" LIMIT ?")
+		args = append(args, q.Limit)
+	}
+	return b.String(), args
+}
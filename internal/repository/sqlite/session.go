@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession открывает новое семейство сессий для пользователя.
+func (r *SessionRepository) CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time, logger *zap.Logger) (*domain.Session, error) {
+	s := &domain.Session{ID: newID(), UserID: userID, FamilyID: newID(), ExpiresAt: expiresAt}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, family_id, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, s.ID, userID, s.FamilyID, tokenHash, now(), micros(expiresAt))
+	if err != nil {
+		logger.Error("failed to create session", zap.Error(err))
+		return nil, err
+	}
+	logger.Info("session created", zap.String("user_id", userID), zap.String("family_id", s.FamilyID))
+	return s, nil
+}
+
+// RotateSession обменивает refresh-токен с хешем oldHash на новый.
+// Повторное предъявление уже обменянного токена отзывает всё семейство.
+func (r *SessionRepository) RotateSession(ctx context.Context, oldHash, newHash string, newExpiresAt time.Time, logger *zap.Logger) (*domain.Session, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		old                  domain.Session
+		expiresAt            int64
+		rotatedAt, revokedAt sql.NullInt64
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, user_id, family_id, expires_at, rotated_at, revoked_at
+		FROM sessions
+		WHERE token_hash = ?
+	`, oldHash).Scan(&old.ID, &old.UserID, &old.FamilyID, &expiresAt, &rotatedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrSessionNotFound
+	}
+	if err != nil {
+		logger.Error("failed to load session", zap.Error(err))
+		return nil, err
+	}
+
+	switch {
+	case revokedAt.Valid:
+		return nil, domain.ErrSessionRevoked
+	case rotatedAt.Valid:
+		if err := revokeFamily(ctx, tx, old.FamilyID); err != nil {
+			logger.Error("failed to revoke session family", zap.Error(err))
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			logger.Error("failed to commit session revocation", zap.Error(err))
+			return nil, err
+		}
+		logger.Warn("refresh token reuse detected, session family revoked",
+			zap.String("user_id", old.UserID),
+			zap.String("family_id", old.FamilyID),
+		)
+		return nil, domain.ErrRefreshTokenReused
+	case expiresAt <= now():
+		return nil, domain.ErrSessionExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET rotated_at = ? WHERE id = ?`, now(), old.ID); err != nil {
+		logger.Error("failed to rotate session", zap.Error(err))
+		return nil, err
+	}
+
+	next := &domain.Session{ID: newID(), UserID: old.UserID, FamilyID: old.FamilyID, ExpiresAt: newExpiresAt}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, family_id, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, next.ID, next.UserID, next.FamilyID, newHash, now(), micros(newExpiresAt))
+	if err != nil {
+		logger.Error("failed to insert rotated session", zap.Error(err))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit session rotation", zap.Error(err))
+		return nil, err
+	}
+	return next, nil
+}
+
+// RevokeFamily отзывает все сессии семейства (logout).
+func (r *SessionRepository) RevokeFamily(ctx context.Context, familyID string, logger *zap.Logger) error {
+	if err := revokeFamily(ctx, r.db, familyID); err != nil {
+		logger.Error("failed to revoke session family", zap.String("family_id", familyID), zap.Error(err))
+		return err
+	}
+	logger.Info("session family revoked", zap.String("family_id", familyID))
+	return nil
+}
+
+// IsSessionActive сообщает, есть ли в семействе неотозванная и неистёкшая сессия.
+func (r *SessionRepository) IsSessionActive(ctx context.Context, familyID string, logger *zap.Logger) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM sessions
+			WHERE family_id = ?
+			  AND revoked_at IS NULL
+			  AND expires_at > ?
+		)
+	`, familyID, now()).Scan(&active)
+	if err != nil {
+		logger.Error("failed to check session", zap.String("family_id", familyID), zap.Error(err))
+		return false, err
+	}
+	return active, nil
+}
+
+func revokeFamily(ctx context.Context, db execer, familyID string) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = ?
+		WHERE family_id = ? AND revoked_at IS NULL
+	`, now(), familyID)
+	return err
+}
@@ -0,0 +1,147 @@
+// Package sqlite — бэкенд хранилища на SQLite для однонодовых установок
+// без PostgreSQL. Драйвер modernc.org/sqlite написан на чистом Go.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	sqlitemigrations "go-musthave-diploma-tpl/internal/migrations/sqlite"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// pragmas включают внешние ключи, WAL и ожидание блокировки вместо SQLITE_BUSY.
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+type DBStorage struct {
+	DB     *sql.DB
+	Logger *zap.Logger
+}
+
+// NewDBStorage открывает файл базы по пути path (":memory:" — база в памяти).
+// Соединение одно: SQLite всё равно допускает одного писателя, а так
+// транзакции выполняются по очереди без ошибок блокировки.
+func NewDBStorage(path string, logger *zap.Logger) (*DBStorage, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", path+sep+pragmas)
+	if err != nil {
+		logger.Error("failed to open database", zap.Error(err))
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		logger.Error("failed to ping database", zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	logger.Info("successfully opened SQLite database", zap.String("path", path))
+
+	return &DBStorage{DB: db, Logger: logger}, nil
+}
+
+func (s *DBStorage) PingContext(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+func (s *DBStorage) Close() error {
+	return s.DB.Close()
+}
+
+// RunMigrations применяет встроенные миграции SQLite.
+func (s *DBStorage) RunMigrations() error {
+	s.Logger.Info("Running SQLite migrations")
+
+	src, err := iofs.New(sqlitemigrations.FS, ".")
+	if err != nil {
+		return err
+	}
+	// migrate.Close закрыл бы и переданное соединение, поэтому закрываем только источник
+	defer src.Close()
+
+	driver, err := migratesqlite.WithInstance(s.DB, &migratesqlite.Config{})
+	if err != nil {
+		s.Logger.Error("cannot create migration driver", zap.Error(err))
+		return err
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "sqlite", driver)
+	if err != nil {
+		s.Logger.Error("cannot create migration", zap.Error(err))
+		return err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		s.Logger.Error("cannot run migration", zap.Error(err))
+		return err
+	}
+	s.Logger.Info("migrations successfully migrated")
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// tx объединяет методы *sql.Tx, нужные вспомогательным функциям.
+type tx interface {
+	execer
+	rowQueryer
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// micros переводит время в микросекунды UTC — формат колонок времени.
+func micros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func fromMicros(v int64) time.Time {
+	return time.UnixMicro(v).UTC()
+}
+
+func now() int64 {
+	return micros(time.Now())
+}
+
+func newID() string {
+	return uuid.NewString()
+}
+
+// numeric переводит сумму в текст DECIMAL(12,2) без потери точности.
+func numeric(d decimal.Decimal) string {
+	return d.StringFixed(2)
+}
+
+func nullNumeric(d *decimal.Decimal) any {
+	if d == nil {
+		return nil
+	}
+	return numeric(*d)
+}
+
+// isUniqueViolation сообщает о нарушении ограничения уникальности на колонках columns.
+// Нарушение определяется по коду ошибки драйвера; колонки есть только в тексте
+// сообщения, по нему различаются ограничения одной таблицы.
+func isUniqueViolation(err error, columns string) bool {
+	var sqliteErr *sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return false
+	}
+	return strings.Contains(sqliteErr.Error(), "UNIQUE constraint failed: "+columns)
+}
@@ -0,0 +1,79 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/storage"
+	"go-musthave-diploma-tpl/internal/storage/storagetest"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+func openSQLite(t *testing.T) *storage.Storage {
+	t.Helper()
+	s, err := storage.OpenSQLite(filepath.Join(t.TempDir(), "gophermart.db"), zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStorageConformance(t *testing.T) {
+	s := openSQLite(t)
+	storagetest.Run(t, func(t *testing.T) *storage.Storage { return s })
+}
+
+func TestLedgerKeepsDecimalPrecision(t *testing.T) {
+	s := openSQLite(t)
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	userID, err := s.Users.CreateUser(ctx, "precise", "hash", logger)
+	require.NoError(t, err)
+
+	// 0.1 + 0.2 в плавающей точке дало бы 0.30000000000000004
+	for _, amount := range []string{"0.1", "0.2", "99999999.99"} {
+		require.NoError(t, s.Ledger.Post(ctx, domain.Posting{
+			Kind:      domain.LedgerKindAccrual,
+			Reference: amount,
+			UserID:    userID,
+			From:      domain.AccountAccrualSource,
+			To:        domain.AccountUserPoints,
+			Amount:    decimal.RequireFromString(amount),
+		}, logger))
+	}
+
+	current, withdrawn, err := s.Ledger.GetBalance(ctx, userID, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "100000000.29", current.StringFixed(2))
+	assert.True(t, withdrawn.IsZero())
+
+	mismatched, err := s.Ledger.Reconcile(ctx, logger)
+	require.NoError(t, err)
+	assert.Empty(t, mismatched)
+}
+
+func TestOpenAppliesMigrationsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gophermart.db")
+	ctx := context.Background()
+
+	s, err := storage.Open(ctx, "sqlite://"+path, postgres.PoolConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	_, err = s.Users.CreateUser(ctx, "kept", "hash", zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = storage.Open(ctx, "sqlite://"+path, postgres.PoolConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	defer s.Close()
+	u, err := s.Users.GetUserByLogin(ctx, "kept", zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, "kept", u.Login)
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// CreateUser регистрирует пользователя и в той же транзакции пишет
+// событие user.registered в outbox.
+func (r *UserRepository) CreateUser(ctx context.Context, login, passwordHash string, logger *zap.Logger) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return "", err
+	}
+	defer tx.Rollback()
+
+	userID := newID()
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users (id, login, password_hash, created_at) VALUES (?, ?, ?, ?)",
+		userID, login, passwordHash, now())
+	if err != nil {
+		if isUniqueViolation(err, "users.login") {
+			logger.Warn("user already exists", zap.String("login", login))
+			return "", domain.ErrUserExists
+		}
+		logger.Error("failed to insert user", zap.Error(err))
+		return "", err
+	}
+
+	if err := insertOutbox(ctx, tx, outbox.NewUserRegistered(userID, outbox.UserRegistered{Login: login}), logger); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit user", zap.Error(err))
+		return "", err
+	}
+	logger.Info("user created", zap.String("id", userID))
+	return userID, nil
+}
+
+func (r *UserRepository) GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*domain.User, error) {
+	u := &domain.User{}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, login, password_hash FROM users WHERE login = ?",
+		login).Scan(&u.ID, &u.Login, &u.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Warn("failed to find user by login", zap.String("login", login))
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get user", zap.Error(err))
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,285 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// owner переводит владельца подписки в параметр запроса: пустой — NULL, партнёр.
+// Сравнивается через IS, чтобы NULL совпадал с NULL.
+func owner(userID string) any {
+	if userID == "" {
+		return nil
+	}
+	return userID
+}
+
+// CreateWebhook сохраняет подписку; типы событий хранятся JSON-массивом.
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, sub domain.WebhookSubscription, logger *zap.Logger) (domain.WebhookSubscription, error) {
+	types, err := json.Marshal(sub.EventTypes)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	sub.ID, sub.CreatedAt = newID(), time.Now().UTC().Truncate(time.Microsecond)
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, user_id, url, secret, event_types, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sub.ID, owner(sub.UserID), sub.URL, sub.Secret, string(types), micros(sub.CreatedAt))
+	if err != nil {
+		logger.Error("failed to create webhook subscription", zap.Error(err))
+		return domain.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+// ListWebhooks возвращает подписки владельца userID в порядке создания.
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, userID string, logger *zap.Logger) ([]domain.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, COALESCE(user_id, ''), url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		WHERE user_id IS ?
+		ORDER BY created_at, id
+	`, owner(userID))
+	if err != nil {
+		logger.Error("failed to query webhook subscriptions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.WebhookSubscription
+	for rows.Next() {
+		var (
+			s         domain.WebhookSubscription
+			types     string
+			createdAt int64
+		)
+		if err := rows.Scan(&s.ID, &s.UserID, &s.URL, &s.Secret, &types, &createdAt); err != nil {
+			logger.Error("failed to scan webhook subscription", zap.Error(err))
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(types), &s.EventTypes); err != nil {
+			logger.Error("failed to decode webhook event types", zap.Error(err))
+			return nil, err
+		}
+		s.CreatedAt = fromMicros(createdAt)
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeleteWebhook удаляет подписку владельца вместе с журналом её доставок.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, userID, id string, logger *zap.Logger) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM webhook_subscriptions
+		WHERE id = ? AND user_id IS ?
+	`, id, owner(userID))
+	if err != nil {
+		logger.Error("failed to delete webhook subscription", zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries ставит событие в очередь доставки каждой
+// подходящей подписке; повторная постановка ничего не меняет.
+func (r *WebhookRepository) EnqueueWebhookDeliveries(ctx context.Context, ev outbox.Event, payload []byte, logger *zap.Logger) (int, error) {
+	ts := now()
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, next_attempt_at, created_at)
+		SELECT s.id, ?, ?, ?, ?, ?
+		FROM webhook_subscriptions s
+		WHERE (s.user_id IS NULL OR s.user_id = ?)
+		  AND EXISTS (SELECT 1 FROM json_each(s.event_types) WHERE value = ?)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`, ev.ID, ev.Type, string(payload), ts, ts, ev.UserID, ev.Type)
+	if err != nil {
+		logger.Error("failed to enqueue webhook deliveries", zap.String("event_id", ev.ID), zap.Error(err))
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ClaimWebhookDeliveries берёт в аренду до limit доставок, время которых
+// подошло, и увеличивает их счётчик попыток.
+func (r *WebhookRepository) ClaimWebhookDeliveries(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.WebhookDelivery, error) {
+	ts := now()
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries
+		SET locked_by = ?, locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending'
+			  AND next_attempt_at <= ?
+			  AND (locked_until IS NULL OR locked_until < ?)
+			ORDER BY next_attempt_at, id
+			LIMIT ?
+		)
+		RETURNING id, subscription_id, event_id, event_type, payload, attempts,
+		          (SELECT url FROM webhook_subscriptions s WHERE s.id = subscription_id),
+		          (SELECT secret FROM webhook_subscriptions s WHERE s.id = subscription_id)
+	`, workerID, ts+lease.Microseconds(), ts, ts, limit)
+	if err != nil {
+		logger.Error("failed to claim webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		var (
+			d       = domain.WebhookDelivery{Status: domain.DeliveryPending}
+			payload string
+		)
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			logger.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CompleteWebhookDelivery записывает итог попытки и снимает аренду.
+func (r *WebhookRepository) CompleteWebhookDelivery(ctx context.Context, id int64, res domain.DeliveryResult, logger *zap.Logger) error {
+	var next, deliveredAt any
+	switch res.Status {
+	case domain.DeliveryPending:
+		next = micros(res.NextAttemptAt)
+	case domain.DeliverySucceeded:
+		deliveredAt = now()
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?,
+		    response_status = NULLIF(?, 0),
+		    last_error = NULLIF(?, ''),
+		    next_attempt_at = COALESCE(?, next_attempt_at),
+		    delivered_at = COALESCE(?, delivered_at),
+		    locked_by = NULL,
+		    locked_until = NULL
+		WHERE id = ?
+	`, string(res.Status), res.ResponseStatus, res.Error, next, deliveredAt, id)
+	if err != nil {
+		logger.Error("failed to record webhook delivery attempt", zap.Int64("delivery_id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListWebhookDeliveries возвращает журнал доставок подписки владельца,
+// начиная с последних.
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, userID, webhookID string, limit int, logger *zap.Logger) ([]domain.WebhookDelivery, error) {
+	var exists int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT 1 FROM webhook_subscriptions
+		WHERE id = ? AND user_id IS ?
+	`, webhookID, owner(userID)).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrWebhookNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get webhook subscription", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+		       COALESCE(response_status, 0), COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, webhookID, limit)
+	if err != nil {
+		logger.Error("failed to query webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		var (
+			d                        domain.WebhookDelivery
+			payload                  string
+			nextAttemptAt, createdAt int64
+			deliveredAt              sql.NullInt64
+		)
+		if err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&payload,
+			&d.Status,
+			&d.Attempts,
+			&nextAttemptAt,
+			&d.ResponseStatus,
+			&d.LastError,
+			&createdAt,
+			&deliveredAt,
+		); err != nil {
+			logger.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		d.NextAttemptAt, d.CreatedAt = fromMicros(nextAttemptAt), fromMicros(createdAt)
+		if deliveredAt.Valid {
+			d.DeliveredAt = fromMicros(deliveredAt.Int64)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook возвращает доставку в очередь с обнулённым счётчиком попыток.
+func (r *WebhookRepository) RedeliverWebhook(ctx context.Context, userID, webhookID string, deliveryID int64, logger *zap.Logger) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = ?, locked_by = NULL, locked_until = NULL
+		WHERE id = ?
+		  AND subscription_id = ?
+		  AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE user_id IS ?)
+	`, now(), deliveryID, webhookID, owner(userID))
+	if err != nil {
+		logger.Error("failed to schedule webhook redelivery", zap.Int64("delivery_id", deliveryID), zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type WithdrawalRepository struct {
+	db *sql.DB
+}
+
+func NewWithdrawalRepository(db *sql.DB) *WithdrawalRepository {
+	return &WithdrawalRepository{db: db}
+}
+
+// Withdraw списывает sum с баланса пользователя. Транзакции SQLite
+// выполняются по очереди, поэтому проверка баланса и проводка не
+// пересекаются с параллельными списаниями.
+func (r *WithdrawalRepository) Withdraw(ctx context.Context, userID, orderNumber string, sum decimal.Decimal, logger *zap.Logger) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	current, _, err := getBalance(ctx, tx, userID)
+	if err != nil {
+		logger.Error("failed to read balance", zap.Error(err))
+		return err
+	}
+	if current.LessThan(sum) {
+		logger.Warn("not enough funds",
+			zap.String("current", current.String()),
+			zap.String("sum", sum.String()),
+		)
+		return domain.ErrNotEnoughFunds
+	}
+
+	withdrawalID, processedAt := newID(), now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO withdrawals (id, user_id, order_number, sum, processed_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, withdrawalID, userID, orderNumber, numeric(sum), processedAt)
+	if err != nil {
+		if isUniqueViolation(err, "withdrawals.user_id, withdrawals.order_number") {
+			logger.Warn("withdrawal order already exists", zap.String("order", orderNumber))
+			return domain.ErrInvalidOrder
+		}
+		logger.Error("failed to create withdrawal", zap.Error(err))
+		return err
+	}
+
+	err = postLedger(ctx, tx, domain.Posting{
+		Kind:      domain.LedgerKindWithdrawal,
+		Reference: withdrawalID,
+		UserID:    userID,
+		From:      domain.AccountUserPoints,
+		To:        domain.AccountWithdrawalSink,
+		Amount:    sum,
+	}, logger)
+	if err != nil {
+		return err
+	}
+
+	ev := outbox.NewWithdrawalCreated(userID, outbox.WithdrawalCreated{
+		WithdrawalID: withdrawalID,
+		Order:        orderNumber,
+		Sum:          sum.Round(2),
+		ProcessedAt:  fromMicros(processedAt),
+	})
+	if err := insertOutbox(ctx, tx, ev, logger); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit withdrawal", zap.Error(err))
+		return err
+	}
+
+	logger.Info("withdrawal created",
+		zap.String("order", orderNumber),
+		zap.String("sum", sum.String()),
+	)
+	return nil
+}
+
+// ListByUser возвращает списания пользователя с фильтром по дате
+// и keyset-пагинацией по (processed_at, id). Статусы в q не учитываются.
+func (r *WithdrawalRepository) ListByUser(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Withdrawal, error) {
+	q.Statuses = nil
+	query, args := appendList(q, `
+		SELECT id, user_id, order_number, sum, processed_at
+		FROM withdrawals
+		WHERE user_id = ?`, "processed_at", []any{userID})
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("failed to query withdrawals", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []domain.Withdrawal
+	for rows.Next() {
+		var (
+			w           domain.Withdrawal
+			processedAt int64
+		)
+		if err := rows.Scan(&w.ID, &w.UserID, &w.OrderNumber, &w.Sum, &processedAt); err != nil {
+			logger.Error("failed to scan withdrawal", zap.Error(err))
+			return nil, err
+		}
+		w.ProcessedAt = fromMicros(processedAt)
+		res = append(res, w)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return res, nil
+}
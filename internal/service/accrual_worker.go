@@ -5,63 +5,341 @@ import (
 	"errors"
 	"go-musthave-diploma-tpl/internal/accrual"
 	"go-musthave-diploma-tpl/internal/config"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/metrics"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
+// dbTimeout ограничивает отдельные обращения воркера к базе.
+const dbTimeout = 2 * time.Second
+
+type AccrualOrderRepository interface {
+	ClaimOrdersForProcessing(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.Order, error)
+	ReleaseOrder(ctx context.Context, orderID, workerID string, logger *zap.Logger) error
+	ReleaseWorkerOrders(ctx context.Context, workerID string, logger *zap.Logger) error
+	ScheduleRetry(ctx context.Context, orderID string, nextPollAt time.Time, lastErr string, logger *zap.Logger) error
+	MarkOrderFailed(ctx context.Context, orderID, lastErr string, logger *zap.Logger) error
+	UpdateOrderStatus(ctx context.Context, orderID string, change domain.StatusChange, logger *zap.Logger) error
+}
+
+type AccrualClient interface {
+	GetOrder(ctx context.Context, number string) (*accrual.OrderResponse, error)
+}
+
 type AccrualWorker struct {
-	OrderRepo *postgres.OrderRepository
-	Client    *accrual.Client
+	OrderRepo AccrualOrderRepository
+	Client    AccrualClient
 	Logger    *zap.Logger
+
+	workerID       string
+	workers        int
+	batchSize      int
+	pollInterval   time.Duration
+	requestTimeout time.Duration
+	lease          time.Duration
+	backoffBase    time.Duration
+	backoffMax     time.Duration
+	maxOrderAge    time.Duration
+
+	mu         sync.Mutex
+	limiter    *rate.Limiter
+	pauseUntil time.Time
+
+	startedAt   time.Time
+	lastSuccess atomic.Int64
 }
 
-func NewAccrualWorker(orderRepo *postgres.OrderRepository, client *accrual.Client, logger *zap.Logger) *AccrualWorker {
+func NewAccrualWorker(orderRepo AccrualOrderRepository, client AccrualClient, cfg *config.Config, logger *zap.Logger) *AccrualWorker {
 	return &AccrualWorker{
-		OrderRepo: orderRepo,
-		Client:    client,
-		Logger:    logger,
+		OrderRepo:      orderRepo,
+		Client:         client,
+		Logger:         logger,
+		workerID:       cfg.AccrualWorkerID,
+		workers:        cfg.AccrualWorkers,
+		batchSize:      cfg.AccrualBatchSize,
+		pollInterval:   cfg.AccrualPollInterval,
+		requestTimeout: cfg.AccrualRequestTimeout,
+		lease:          cfg.AccrualLease,
+		backoffBase:    cfg.AccrualBackoffBase,
+		backoffMax:     cfg.AccrualBackoffMax,
+		maxOrderAge:    cfg.AccrualMaxOrderAge,
+		limiter:        rate.NewLimiter(rate.Inf, 1),
+		startedAt:      time.Now(),
+	}
+}
+
+// LastSuccess возвращает время последнего успешного цикла опроса:
+// заказы взяты из базы и система расчёта ответила хотя бы на один запрос.
+// До первого успешного цикла возвращается время создания воркера.
+func (w *AccrualWorker) LastSuccess() time.Time {
+	if ns := w.lastSuccess.Load(); ns != 0 {
+		return time.Unix(0, ns)
 	}
+	return w.startedAt
+}
+
+func (w *AccrualWorker) markSuccess() {
+	w.lastSuccess.Store(time.Now().UnixNano())
 }
 
 func NewAccrualClient(cfg *config.Config) *accrual.Client {
 	return accrual.NewClient(cfg.AccrualSystemAddress)
 }
 
+// Run опрашивает систему расчёта каждые pollInterval до отмены ctx.
+func (w *AccrualWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.Logger.Info("accrual worker stopped", zap.Error(ctx.Err()))
+			releaseCtx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+			_ = w.OrderRepo.ReleaseWorkerOrders(releaseCtx, w.workerID, w.Logger)
+			cancel()
+			return
+		case <-ticker.C:
+			w.Process(ctx)
+		}
+	}
+}
+
+// Process выполняет один цикл опроса: продюсер берёт в аренду пачку заказов,
+// а workers потребителей параллельно опрашивают по ним систему расчёта.
 func (w *AccrualWorker) Process(ctx context.Context) {
-	dbCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-	defer cancel()
+	if d := w.pauseRemaining(time.Now()); d > 0 {
+		w.Logger.Debug("accrual polling paused by rate limit", zap.Duration("remaining", d))
+		return
+	}
 
-	orders, err := w.OrderRepo.GetOrdersForProcessing(dbCtx, 10, w.Logger)
+	dbCtx, cancel := context.WithTimeout(ctx, dbTimeout)
+	orders, err := w.OrderRepo.ClaimOrdersForProcessing(dbCtx, w.workerID, w.batchSize, w.lease, w.Logger)
+	cancel()
 	if err != nil {
-		w.Logger.Error("failed to get orders for processing", zap.Error(err))
+		w.Logger.Error("failed to claim orders for processing", zap.Error(err))
+		return
+	}
+	if len(orders) == 0 {
+		w.markSuccess()
 		return
 	}
 
+	jobs := make(chan domain.Order)
+	var (
+		wg      sync.WaitGroup
+		reached atomic.Bool
+	)
+	for i := 0; i < min(w.workers, len(orders)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for order := range jobs {
+				if w.processOrder(ctx, order) {
+					reached.Store(true)
+				}
+			}
+		}()
+	}
+
+produce:
 	for _, order := range orders {
-		if dbCtx.Err() != nil {
+		select {
+		case <-ctx.Done():
 			w.Logger.Info("context cancelled, stopping accrual worker")
-			return
+			break produce
+		case jobs <- order:
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		resp, err := w.Client.GetOrder(dbCtx, order.Number)
-		if err != nil {
-			if errors.Is(err, accrual.ErrTooManyRequests) {
-				w.Logger.Warn("accrual rate limit")
-				return
-			}
-			continue
+	if reached.Load() {
+		w.markSuccess()
+	}
+}
+
+// processOrder опрашивает систему расчёта по заказу и возвращает true,
+// если она ответила (в том числе 204 или 429).
+func (w *AccrualWorker) processOrder(ctx context.Context, order domain.Order) bool {
+	ctx, span := tracer.Start(ctx, "AccrualWorker.processOrder",
+		trace.WithAttributes(attribute.String("order.number", order.Number)))
+	defer span.End()
+	defer w.release(order)
+
+	if err := w.wait(ctx); err != nil {
+		w.Logger.Debug("accrual rate limiter wait aborted", zap.Error(err))
+		return false
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, w.requestTimeout)
+	resp, err := w.Client.GetOrder(reqCtx, order.Number)
+	cancel()
+	if err != nil {
+		var rlErr *accrual.RateLimitError
+		if errors.As(err, &rlErr) {
+			w.applyRateLimit(rlErr, time.Now())
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
 		}
+		w.Logger.Debug("failed to get accrual", zap.String("number", order.Number), zap.Error(err))
+		w.retryLater(ctx, order, err.Error())
+		return errors.Is(err, accrual.ErrOrderNotFound)
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
 
-		switch resp.Status {
-		case accrual.StatusInvalid:
-			_ = w.OrderRepo.UpdateOrderStatus(dbCtx, order.ID, "INVALID", nil, w.Logger)
-		case accrual.StatusProcessing:
-			_ = w.OrderRepo.UpdateOrderStatus(dbCtx, order.ID, "PROCESSING", nil, w.Logger)
-		case accrual.StatusProcessed:
-			dec := resp.Accrual
-			_ = w.OrderRepo.UpdateOrderStatus(dbCtx, order.ID, "PROCESSED", &dec, w.Logger)
+	change := domain.StatusChange{From: order.Status, Source: domain.StatusSourceWorker, Response: resp.Raw}
+	switch resp.Status {
+	case accrual.StatusInvalid:
+		change.Status = domain.OrderStatusInvalid
+	case accrual.StatusProcessing:
+		change.Status = domain.OrderStatusProcessing
+	case accrual.StatusProcessed:
+		dec := resp.Accrual
+		change.Status, change.Accrual = domain.OrderStatusProcessed, &dec
+	default:
+		// REGISTERED: расчёт ещё не начат
+		w.retryLater(ctx, order, "")
+		return true
+	}
+
+	if change.Status != order.Status {
+		if err := w.transition(dbCtx, order, change); err != nil {
+			return true
+		}
+		if change.Status == domain.OrderStatusProcessed {
+			metrics.AddPoints(metrics.PointsAccrued, *change.Accrual)
+		}
+	}
+	if !change.Status.Terminal() {
+		w.retryLater(ctx, order, "")
+	}
+	return true
+}
+
+// transition применяет смену статуса, если её допускает машина состояний.
+// Отклонённые переходы, в том числе проигранные конкурентному обновлению,
+// логируются и учитываются в метриках.
+func (w *AccrualWorker) transition(ctx context.Context, order domain.Order, change domain.StatusChange) error {
+	reason := ""
+	err := domain.ErrTransitionNotAllowed
+	if order.Status.CanTransitionTo(change.Status) {
+		err = w.OrderRepo.UpdateOrderStatus(ctx, order.ID, change, w.Logger)
+	}
+	switch {
+	case errors.Is(err, domain.ErrTransitionNotAllowed):
+		reason = "not_allowed"
+	case errors.Is(err, domain.ErrStatusConflict):
+		reason = "conflict"
+	default:
+		return err
+	}
+
+	w.Logger.Warn("order status transition rejected",
+		zap.String("number", order.Number),
+		zap.String("from", string(order.Status)),
+		zap.String("to", string(change.Status)),
+		zap.String("reason", reason),
+	)
+	metrics.OrderTransitionsRejected.WithLabelValues(string(order.Status), string(change.Status), reason).Inc()
+	return err
+}
+
+// retryLater откладывает следующий опрос заказа с экспоненциальной задержкой,
+// а слишком старые заказы переводит в терминальный статус.
+func (w *AccrualWorker) retryLater(ctx context.Context, order domain.Order, lastErr string) {
+	dbCtx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	now := time.Now()
+	if w.maxOrderAge > 0 && now.Sub(order.UploadedAt) > w.maxOrderAge {
+		reason := "accrual polling gave up after " + w.maxOrderAge.String()
+		if lastErr != "" {
+			reason += ": " + lastErr
+		}
+		_ = w.OrderRepo.MarkOrderFailed(dbCtx, order.ID, reason, w.Logger)
+		return
+	}
+
+	delay := backoff(order.Attempts, w.backoffBase, w.backoffMax)
+	_ = w.OrderRepo.ScheduleRetry(dbCtx, order.ID, now.Add(delay), lastErr, w.Logger)
+}
+
+// backoff возвращает задержку base*2^attempt, ограниченную ceiling,
+// со случайным разбросом в пределах её второй половины.
+func backoff(attempt int, base, ceiling time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 0; i < attempt && d < ceiling; i++ {
+		d *= 2
+	}
+	if ceiling > 0 && d > ceiling {
+		d = ceiling
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+func (w *AccrualWorker) release(order domain.Order) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+	_ = w.OrderRepo.ReleaseOrder(ctx, order.ID, w.workerID, w.Logger)
+}
+
+// wait блокируется до появления токена в бакете или до окончания паузы по 429.
+func (w *AccrualWorker) wait(ctx context.Context) error {
+	if d := w.pauseRemaining(time.Now()); d > 0 {
+		return accrual.ErrTooManyRequests
+	}
+
+	w.mu.Lock()
+	limiter := w.limiter
+	w.mu.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+func (w *AccrualWorker) pauseRemaining(now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pauseUntil.Sub(now)
+}
+
+// applyRateLimit ставит опрос на глобальную паузу и пересоздаёт бакет
+// под лимит, объявленный системой расчёта.
+func (w *AccrualWorker) applyRateLimit(rlErr *accrual.RateLimitError, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if until := now.Add(rlErr.RetryAfter); until.After(w.pauseUntil) {
+		w.pauseUntil = until
+	}
+
+	if rlErr.RPM > 0 {
+		perSecond := float64(rlErr.RPM) / 60
+		burst := rlErr.RPM / 60
+		if burst < 1 {
+			burst = 1
 		}
+		w.limiter = rate.NewLimiter(rate.Limit(perSecond), burst)
 	}
+
+	w.Logger.Warn("accrual rate limit",
+		zap.Duration("retry_after", rlErr.RetryAfter),
+		zap.Int("rpm", rlErr.RPM),
+	)
 }
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/accrual"
+	"go-musthave-diploma-tpl/internal/accrual/accrualmock"
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/time/rate"
+)
+
+type fakeAccrualRepo struct {
+	mu      sync.Mutex
+	orders  []domain.Order
+	updates map[string]domain.OrderStatus
+	current map[string]domain.OrderStatus
+	locked  map[string]string
+	retries map[string]time.Time
+	errors  map[string]string
+}
+
+func newFakeAccrualRepo(orders []domain.Order) *fakeAccrualRepo {
+	return &fakeAccrualRepo{
+		orders:  orders,
+		updates: map[string]domain.OrderStatus{},
+		current: map[string]domain.OrderStatus{},
+		locked:  map[string]string{},
+		retries: map[string]time.Time{},
+		errors:  map[string]string{},
+	}
+}
+
+func (r *fakeAccrualRepo) ScheduleRetry(ctx context.Context, orderID string, nextPollAt time.Time, lastErr string, logger *zap.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries[orderID] = nextPollAt
+	r.errors[orderID] = lastErr
+	return nil
+}
+
+func (r *fakeAccrualRepo) MarkOrderFailed(ctx context.Context, orderID, lastErr string, logger *zap.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates[orderID] = domain.OrderStatusInvalid
+	r.errors[orderID] = lastErr
+	return nil
+}
+
+func (r *fakeAccrualRepo) ClaimOrdersForProcessing(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []domain.Order
+	for _, o := range r.orders {
+		if len(claimed) == limit {
+			break
+		}
+		if _, ok := r.locked[o.ID]; ok {
+			continue
+		}
+		r.locked[o.ID] = workerID
+		claimed = append(claimed, o)
+	}
+	return claimed, nil
+}
+
+func (r *fakeAccrualRepo) ReleaseOrder(ctx context.Context, orderID, workerID string, logger *zap.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.locked[orderID] == workerID {
+		delete(r.locked, orderID)
+	}
+	return nil
+}
+
+func (r *fakeAccrualRepo) ReleaseWorkerOrders(ctx context.Context, workerID string, logger *zap.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, owner := range r.locked {
+		if owner == workerID {
+			delete(r.locked, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeAccrualRepo) UpdateOrderStatus(ctx context.Context, orderID string, change domain.StatusChange, logger *zap.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// как условный UPDATE: статус в «базе» мог уйти вперёд после выборки заказа
+	if current, ok := r.current[orderID]; ok && current != change.From {
+		return domain.ErrStatusConflict
+	}
+	r.updates[orderID] = change.Status
+	return nil
+}
+
+type fakeAccrualClient struct {
+	delay    time.Duration
+	inFlight atomic.Int32
+	peak     atomic.Int32
+	getFn    func(number string) (*accrual.OrderResponse, error)
+}
+
+func (c *fakeAccrualClient) GetOrder(ctx context.Context, number string) (*accrual.OrderResponse, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return c.getFn(number)
+}
+
+func newTestOrders(n int) []domain.Order {
+	orders := make([]domain.Order, 0, n)
+	for i := 0; i < n; i++ {
+		orders = append(orders, domain.Order{
+			ID:         fmt.Sprintf("id-%d", i),
+			Number:     fmt.Sprintf("%d", i),
+			Status:     domain.OrderStatusNew,
+			UploadedAt: time.Now(),
+		})
+	}
+	return orders
+}
+
+func TestAccrualWorker_Process_Parallel(t *testing.T) {
+	repo := newFakeAccrualRepo(newTestOrders(8))
+	client := &fakeAccrualClient{
+		delay: 50 * time.Millisecond,
+		getFn: func(number string) (*accrual.OrderResponse, error) {
+			return &accrual.OrderResponse{Order: number, Status: accrual.StatusProcessed, Accrual: decimal.NewFromInt(10)}, nil
+		},
+	}
+	cfg := &config.Config{AccrualWorkerID: "w1", AccrualWorkers: 4, AccrualBatchSize: 10, AccrualRequestTimeout: time.Second}
+	w := NewAccrualWorker(repo, client, cfg, zaptest.NewLogger(t))
+
+	w.Process(context.Background())
+
+	require.Len(t, repo.updates, 8)
+	for _, status := range repo.updates {
+		assert.Equal(t, domain.OrderStatusProcessed, status)
+	}
+	assert.Equal(t, int32(4), client.peak.Load())
+	assert.Empty(t, repo.locked, "all leases must be released after the cycle")
+}
+
+func TestAccrualWorker_Process_SkipsOrdersClaimedByOtherReplica(t *testing.T) {
+	repo := newFakeAccrualRepo(newTestOrders(4))
+	repo.locked["id-0"] = "other"
+	repo.locked["id-1"] = "other"
+
+	client := &fakeAccrualClient{
+		getFn: func(number string) (*accrual.OrderResponse, error) {
+			return &accrual.OrderResponse{Order: number, Status: accrual.StatusProcessing}, nil
+		},
+	}
+	cfg := &config.Config{AccrualWorkerID: "w1", AccrualWorkers: 2, AccrualBatchSize: 10, AccrualRequestTimeout: time.Second}
+	w := NewAccrualWorker(repo, client, cfg, zaptest.NewLogger(t))
+
+	w.Process(context.Background())
+
+	assert.Equal(t, map[string]domain.OrderStatus{"id-2": "PROCESSING", "id-3": "PROCESSING"}, repo.updates)
+	assert.Equal(t, map[string]string{"id-0": "other", "id-1": "other"}, repo.locked)
+}
+
+func TestAccrualWorker_Process_RequestTimeout(t *testing.T) {
+	repo := newFakeAccrualRepo(newTestOrders(2))
+	client := &fakeAccrualClient{
+		delay: time.Second,
+		getFn: func(number string) (*accrual.OrderResponse, error) {
+			return &accrual.OrderResponse{Order: number, Status: accrual.StatusProcessed}, nil
+		},
+	}
+	cfg := &config.Config{AccrualWorkers: 2, AccrualBatchSize: 10, AccrualRequestTimeout: 20 * time.Millisecond}
+	w := NewAccrualWorker(repo, client, cfg, zaptest.NewLogger(t))
+
+	start := time.Now()
+	w.Process(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Empty(t, repo.updates)
+	assert.Len(t, repo.retries, 2)
+}
+
+func TestAccrualWorker_Process_Backoff(t *testing.T) {
+	orders := newTestOrders(3)
+	orders[1].Attempts = 3
+	orders[2].UploadedAt = time.Now().Add(-2 * time.Hour)
+	repo := newFakeAccrualRepo(orders)
+
+	client := &fakeAccrualClient{
+		getFn: func(number string) (*accrual.OrderResponse, error) {
+			return nil, accrual.ErrOrderNotFound
+		},
+	}
+	cfg := &config.Config{
+		AccrualWorkerID:       "w1",
+		AccrualWorkers:        1,
+		AccrualBatchSize:      10,
+		AccrualRequestTimeout: time.Second,
+		AccrualBackoffBase:    time.Second,
+		AccrualBackoffMax:     time.Minute,
+		AccrualMaxOrderAge:    time.Hour,
+	}
+	w := NewAccrualWorker(repo, client, cfg, zaptest.NewLogger(t))
+
+	start := time.Now()
+	w.Process(context.Background())
+
+	require.Len(t, repo.retries, 2)
+	assert.WithinRange(t, repo.retries["id-0"], start.Add(500*time.Millisecond), start.Add(2*time.Second))
+	assert.WithinRange(t, repo.retries["id-1"], start.Add(4*time.Second), start.Add(10*time.Second))
+	assert.Equal(t, accrual.ErrOrderNotFound.Error(), repo.errors["id-0"])
+
+	assert.Equal(t, domain.OrderStatusInvalid, repo.updates["id-2"])
+	assert.Contains(t, repo.errors["id-2"], "gave up")
+}
+
+func TestAccrualWorker_LastSuccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		orders      int
+		getErr      error
+		wantSuccess bool
+	}{
+		{"#1 accrual system answered", 2, nil, true},
+		{"#2 order not registered is still an answer", 2, accrual.ErrOrderNotFound, true},
+		{"#3 accrual system unreachable", 2, errors.New("connection refused"), false},
+		{"#4 nothing to poll", 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeAccrualRepo(newTestOrders(tt.orders))
+			client := &fakeAccrualClient{
+				getFn: func(number string) (*accrual.OrderResponse, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return &accrual.OrderResponse{Order: number, Status: accrual.StatusProcessing}, nil
+				},
+			}
+			cfg := &config.Config{
+				AccrualWorkers:        2,
+				AccrualBatchSize:      10,
+				AccrualRequestTimeout: time.Second,
+				AccrualMaxOrderAge:    time.Hour,
+			}
+			w := NewAccrualWorker(repo, client, cfg, zaptest.NewLogger(t))
+			created := w.LastSuccess()
+
+			w.Process(context.Background())
+
+			assert.Equal(t, tt.wantSuccess, w.LastSuccess().After(created))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempt  int
+		min, max time.Duration
+	}{
+		{"#1 first attempt", 0, 500 * time.Millisecond, time.Second},
+		{"#2 third attempt", 2, 2 * time.Second, 4 * time.Second},
+		{"#3 capped", 20, 30 * time.Second, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := backoff(tt.attempt, time.Second, time.Minute)
+				assert.GreaterOrEqual(t, d, tt.min)
+				assert.LessOrEqual(t, d, tt.max)
+			}
+		})
+	}
+}
+
+func TestAccrualWorker_ApplyRateLimit(t *testing.T) {
+	w := NewAccrualWorker(nil, nil, &config.Config{}, zaptest.NewLogger(t))
+	now := time.Now()
+
+	w.applyRateLimit(&accrual.RateLimitError{RetryAfter: 30 * time.Second, RPM: 120}, now)
+
+	assert.Equal(t, 30*time.Second, w.pauseRemaining(now))
+	assert.Equal(t, rate.Limit(2), w.limiter.Limit())
+	assert.Equal(t, 2, w.limiter.Burst())
+
+	err := w.wait(context.Background())
+	assert.ErrorIs(t, err, accrual.ErrTooManyRequests)
+
+	// более короткая пауза не сокращает уже объявленную
+	w.applyRateLimit(&accrual.RateLimitError{RetryAfter: time.Second}, now)
+	assert.Equal(t, 30*time.Second, w.pauseRemaining(now))
+	assert.Equal(t, rate.Limit(2), w.limiter.Limit())
+}
+
+func TestAccrualWorker_ApplyRateLimit_LowRPM(t *testing.T) {
+	w := NewAccrualWorker(nil, nil, &config.Config{}, zaptest.NewLogger(t))
+
+	w.applyRateLimit(&accrual.RateLimitError{RPM: 30}, time.Now())
+
+	assert.Equal(t, rate.Limit(0.5), w.limiter.Limit())
+	assert.Equal(t, 1, w.limiter.Burst())
+}
+
+func TestAccrualWorker_Process_StateMachine(t *testing.T) {
+	orders := newTestOrders(3)
+	orders[0].Status = domain.OrderStatusProcessing // PROCESSING → PROCESSING: без записи
+	orders[1].Status = domain.OrderStatusProcessed  // терминальный статус не меняется
+	// orders[2] — NEW, но в «базе» его уже рассчитал другой воркер
+
+	repo := newFakeAccrualRepo(orders)
+	repo.current["id-2"] = domain.OrderStatusProcessed
+
+	client := &fakeAccrualClient{
+		getFn: func(number string) (*accrual.OrderResponse, error) {
+			return &accrual.OrderResponse{Order: number, Status: accrual.StatusProcessing}, nil
+		},
+	}
+	cfg := &config.Config{AccrualWorkers: 1, AccrualBatchSize: 10, AccrualRequestTimeout: time.Second}
+	w := NewAccrualWorker(repo, client, cfg, zaptest.NewLogger(t))
+
+	notAllowed := testutil.ToFloat64(metrics.OrderTransitionsRejected.WithLabelValues("PROCESSED", "PROCESSING", "not_allowed"))
+	conflicts := testutil.ToFloat64(metrics.OrderTransitionsRejected.WithLabelValues("NEW", "PROCESSING", "conflict"))
+
+	w.Process(context.Background())
+
+	assert.Empty(t, repo.updates)
+	assert.Contains(t, repo.retries, "id-0", "незавершённый заказ опрашивается снова")
+	assert.Equal(t, notAllowed+1, testutil.ToFloat64(metrics.OrderTransitionsRejected.WithLabelValues("PROCESSED", "PROCESSING", "not_allowed")))
+	assert.Equal(t, conflicts+1, testutil.ToFloat64(metrics.OrderTransitionsRejected.WithLabelValues("NEW", "PROCESSING", "conflict")))
+}
+
+func TestAccrualWorker_Process_AccrualMock(t *testing.T) {
+	points := decimal.NewFromInt(500)
+	mock := accrualmock.New(accrualmock.Config{RPM: 3})
+	mock.Script("0", accrualmock.Step{Status: accrual.StatusProcessing}, accrualmock.Step{Status: accrual.StatusProcessed, Accrual: &points})
+	mock.Script("1", accrualmock.Step{Status: accrual.StatusInvalid})
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	repo := newFakeAccrualRepo(newTestOrders(2))
+	cfg := &config.Config{AccrualWorkers: 1, AccrualBatchSize: 10, AccrualRequestTimeout: time.Second}
+	w := NewAccrualWorker(repo, accrual.NewClient(srv.URL), cfg, zaptest.NewLogger(t))
+
+	w.Process(context.Background())
+	assert.Equal(t, domain.OrderStatusProcessing, repo.updates["id-0"])
+	assert.Equal(t, domain.OrderStatusInvalid, repo.updates["id-1"])
+
+	// третий запрос укладывается в лимит, четвёртый получает 429
+	w.Process(context.Background())
+	assert.Equal(t, domain.OrderStatusProcessed, repo.updates["id-0"])
+	assert.Positive(t, w.pauseRemaining(time.Now()), "воркер встаёт на паузу до конца минутного окна")
+	assert.Equal(t, 1, mock.Calls("1"))
+}
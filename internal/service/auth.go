@@ -2,100 +2,229 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"fmt"
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/metrics"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRefreshToken = apperr.New(apperr.CodeInvalidRefreshToken, "invalid refresh token")
+	ErrCredentialsRequired = apperr.New(apperr.CodeCredentialsRequired, "login and password required")
+	ErrPasswordEqualsLogin = apperr.New(apperr.CodePasswordEqualsLogin, "login and password should not be equal")
+	ErrPasswordTooShort    = apperr.New(apperr.CodePasswordTooShort, "password too short")
+	ErrInvalidCredentials  = apperr.New(apperr.CodeInvalidCredentials, "invalid credentials")
+)
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, login, passwordHash string, logger *zap.Logger) (string, error)
-	GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*postgres.User, error)
+	GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*domain.User, error)
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time, logger *zap.Logger) (*domain.Session, error)
+	RotateSession(ctx context.Context, oldHash, newHash string, newExpiresAt time.Time, logger *zap.Logger) (*domain.Session, error)
+	RevokeFamily(ctx context.Context, familyID string, logger *zap.Logger) error
+}
+
+// TokenPair — короткоживущий access-токен и непрозрачный refresh-токен.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
 }
 
 type AuthService struct {
-	userRepo UserRepository
-	secret   string
-	logger   *zap.Logger
+	userRepo    UserRepository
+	sessionRepo SessionRepository
+	keys        *Keyring
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	logger      *zap.Logger
 }
 
-func NewAuthService(userRepo UserRepository, secret string, logger *zap.Logger) *AuthService {
+func NewAuthService(
+	userRepo UserRepository,
+	sessionRepo SessionRepository,
+	keys *Keyring,
+	accessTTL, refreshTTL time.Duration,
+	logger *zap.Logger,
+) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		secret:   secret,
-		logger:   logger,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		keys:        keys,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+		logger:      logger,
 	}
 }
 
 func checkLogin(login, password string, logger *zap.Logger) (bool, error) {
 	if login == "" || password == "" {
 		logger.Error("login or password is empty")
-		return false, errors.New("login and password required")
+		return false, ErrCredentialsRequired
 	}
 	if login == password {
 		logger.Error("No secure", zap.String("login", login))
-		return false, errors.New("login and password should not be equal")
+		return false, ErrPasswordEqualsLogin
 	}
 	if len(password) < 8 {
 		logger.Error("password is too short, need 8 symbol", zap.String("login", login))
-		return false, errors.New("password too short")
+		return false, ErrPasswordTooShort
 	}
 	return true, nil
 }
 
-func (s *AuthService) Register(ctx context.Context, login, password string) (string, error) {
+func (s *AuthService) Register(ctx context.Context, login, password string) (*TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
+
 	accept, err := checkLogin(login, password, s.logger)
 	if err != nil {
 		s.logger.Error("login or password is wrong", zap.String("login", login), zap.Error(err))
-		return "", err
+		return nil, err
 	}
 	if accept {
+		_, hashSpan := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
 		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashSpan.End()
 		if err != nil {
 			s.logger.Error("failed to hash password", zap.Error(err))
-			return "", err
+			return nil, err
 		}
 
 		userID, err := s.userRepo.CreateUser(ctx, login, string(hash), s.logger)
 		if err != nil {
 			s.logger.Error("failed to create user", zap.Error(err))
-			return "", err
+			return nil, err
 		}
+		metrics.UsersRegistered.Inc()
 
-		token, err := GenerateToken(userID, s.secret)
-		if err != nil {
-			s.logger.Error("failed to generate token", zap.Error(err))
-			return "", err
-		}
-		return token, nil
+		return s.openSession(ctx, userID)
 	}
-	return "", nil
+	return nil, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, login, password string) (string, error) {
+func (s *AuthService) Login(ctx context.Context, login, password string) (*TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
 	accept, err := checkLogin(login, password, s.logger)
 	if err != nil {
 		s.logger.Error("login or password is wrong", zap.String("login", login), zap.Error(err))
-		return "", err
+		return nil, err
 	}
 	if accept {
 		user, err := s.userRepo.GetUserByLogin(ctx, login, s.logger)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
 		if err != nil {
 			s.logger.Error("failed to get user by login", zap.String("login", login), zap.Error(err))
-			return "", err
-		}
-		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-			s.logger.Error("password is wrong", zap.String("login", login), zap.Error(err))
-			return "", err
+			return nil, err
 		}
-		token, err := GenerateToken(user.ID, s.secret)
+		_, hashSpan := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+		hashSpan.End()
 		if err != nil {
-			s.logger.Error("failed to generate token", zap.Error(err))
-			return "", err
+			s.logger.Warn("password is wrong", zap.String("login", login), zap.Error(err))
+			return nil, ErrInvalidCredentials
+		}
+		return s.openSession(ctx, user.ID)
+
+	}
+	return nil, nil
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов того же семейства.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Refresh")
+	defer span.End()
+
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, err := newRefreshToken()
+	if err != nil {
+		s.logger.Error("failed to generate refresh token", zap.Error(err))
+		return nil, err
+	}
+
+	sess, err := s.sessionRepo.RotateSession(ctx, hashRefreshToken(refreshToken), hashRefreshToken(next), time.Now().Add(s.refreshTTL), s.logger)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) ||
+			errors.Is(err, domain.ErrSessionExpired) ||
+			errors.Is(err, domain.ErrSessionRevoked) ||
+			errors.Is(err, domain.ErrRefreshTokenReused) {
+			s.logger.Warn("refresh rejected", zap.Error(err))
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRefreshToken, err)
 		}
-		return token, nil
+		return nil, err
+	}
+
+	return s.issueTokens(sess, next)
+}
+
+// Logout отзывает семейство сессий, к которому относится access-токен.
+func (s *AuthService) Logout(ctx context.Context, familyID string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	if familyID == "" {
+		return nil
+	}
+	return s.sessionRepo.RevokeFamily(ctx, familyID, s.logger)
+}
+
+func (s *AuthService) openSession(ctx context.Context, userID string) (*TokenPair, error) {
+	refresh, err := newRefreshToken()
+	if err != nil {
+		s.logger.Error("failed to generate refresh token", zap.Error(err))
+		return nil, err
+	}
 
+	sess, err := s.sessionRepo.CreateSession(ctx, userID, hashRefreshToken(refresh), time.Now().Add(s.refreshTTL), s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(sess, refresh)
+}
+
+func (s *AuthService) issueTokens(sess *domain.Session, refresh string) (*TokenPair, error) {
+	token, err := GenerateToken(s.keys, sess.UserID, sess.FamilyID, s.accessTTL)
+	if err != nil {
+		s.logger.Error("failed to generate token", zap.Error(err))
+		return nil, err
 	}
-	return "", nil
+	return &TokenPair{
+		AccessToken:  token,
+		RefreshToken: refresh,
+		ExpiresIn:    s.accessTTL,
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken — в базе хранится только хеш refresh-токена.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
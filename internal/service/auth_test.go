@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/domain"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -19,7 +20,7 @@ import (
 
 type mockUserRepo struct {
 	createUserFn     func(ctx context.Context, login, hash string) (string, error)
-	getUserByLoginFn func(ctx context.Context, login string) (*postgres.User, error)
+	getUserByLoginFn func(ctx context.Context, login string) (*domain.User, error)
 }
 
 func (m *mockUserRepo) CreateUser(
@@ -35,10 +36,32 @@ func (m *mockUserRepo) GetUserByLogin(
 	ctx context.Context,
 	login string,
 	logger *zap.Logger,
-) (*postgres.User, error) {
+) (*domain.User, error) {
 	return m.getUserByLoginFn(ctx, login)
 }
 
+type mockSessionRepo struct {
+	createFn func(ctx context.Context, userID, tokenHash string) (*domain.Session, error)
+	rotateFn func(ctx context.Context, oldHash, newHash string) (*domain.Session, error)
+	revoked  []string
+}
+
+func (m *mockSessionRepo) CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time, logger *zap.Logger) (*domain.Session, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, userID, tokenHash)
+	}
+	return &domain.Session{ID: "session-1", UserID: userID, FamilyID: "family-1", ExpiresAt: expiresAt}, nil
+}
+
+func (m *mockSessionRepo) RotateSession(ctx context.Context, oldHash, newHash string, newExpiresAt time.Time, logger *zap.Logger) (*domain.Session, error) {
+	return m.rotateFn(ctx, oldHash, newHash)
+}
+
+func (m *mockSessionRepo) RevokeFamily(ctx context.Context, familyID string, logger *zap.Logger) error {
+	m.revoked = append(m.revoked, familyID)
+	return nil
+}
+
 /*
 ================ TESTS =================
 */
@@ -90,9 +113,9 @@ func TestAuthService_Register(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			logger := zaptest.NewLogger(t)
 
-			service := NewAuthService(tt.repo, "secret", logger)
+			service := NewAuthService(tt.repo, &mockSessionRepo{}, testKeyring(t), 15*time.Minute, time.Hour, logger)
 
-			token, err := service.Register(context.Background(), tt.login, tt.password)
+			tokens, err := service.Register(context.Background(), tt.login, tt.password)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -101,9 +124,10 @@ func TestAuthService_Register(t *testing.T) {
 			}
 
 			if tt.wantToken {
-				assert.NotEmpty(t, token)
+				assert.NotEmpty(t, tokens.AccessToken)
+				assert.NotEmpty(t, tokens.RefreshToken)
 			} else {
-				assert.Empty(t, token)
+				assert.Nil(t, tokens)
 			}
 		})
 	}
@@ -119,6 +143,7 @@ func TestAuthService_Login(t *testing.T) {
 		password  string
 		repo      *mockUserRepo
 		wantErr   bool
+		wantErrIs error
 		wantToken bool
 	}{
 		{
@@ -126,8 +151,8 @@ func TestAuthService_Login(t *testing.T) {
 			login:    "test",
 			password: password,
 			repo: &mockUserRepo{
-				getUserByLoginFn: func(ctx context.Context, login string) (*postgres.User, error) {
-					return &postgres.User{
+				getUserByLoginFn: func(ctx context.Context, login string) (*domain.User, error) {
+					return &domain.User{
 						ID:           "user-id-1",
 						PasswordHash: string(hash),
 					}, nil
@@ -141,22 +166,36 @@ func TestAuthService_Login(t *testing.T) {
 			login:    "test",
 			password: "wrongpassword",
 			repo: &mockUserRepo{
-				getUserByLoginFn: func(ctx context.Context, login string) (*postgres.User, error) {
-					return &postgres.User{
+				getUserByLoginFn: func(ctx context.Context, login string) (*domain.User, error) {
+					return &domain.User{
 						ID:           "user-id-1",
 						PasswordHash: string(hash),
 					}, nil
 				},
 			},
 			wantErr:   true,
+			wantErrIs: ErrInvalidCredentials,
+			wantToken: false,
+		},
+		{
+			name:     "#3 unknown login",
+			login:    "test",
+			password: password,
+			repo: &mockUserRepo{
+				getUserByLoginFn: func(ctx context.Context, login string) (*domain.User, error) {
+					return nil, domain.ErrUserNotFound
+				},
+			},
+			wantErr:   true,
+			wantErrIs: ErrInvalidCredentials,
 			wantToken: false,
 		},
 		{
-			name:     "#3 repo error",
+			name:     "#4 repo error",
 			login:    "test",
 			password: password,
 			repo: &mockUserRepo{
-				getUserByLoginFn: func(ctx context.Context, login string) (*postgres.User, error) {
+				getUserByLoginFn: func(ctx context.Context, login string) (*domain.User, error) {
 					return nil, errors.New("db error")
 				},
 			},
@@ -169,21 +208,88 @@ func TestAuthService_Login(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			logger := zaptest.NewLogger(t)
 
-			service := NewAuthService(tt.repo, "secret", logger)
+			service := NewAuthService(tt.repo, &mockSessionRepo{}, testKeyring(t), 15*time.Minute, time.Hour, logger)
 
-			token, err := service.Login(context.Background(), tt.login, tt.password)
+			tokens, err := service.Login(context.Background(), tt.login, tt.password)
 
 			if tt.wantErr {
 				assert.Error(t, err)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
 			} else {
 				assert.NoError(t, err)
 			}
 
 			if tt.wantToken {
-				assert.NotEmpty(t, token)
+				assert.NotEmpty(t, tokens.AccessToken)
+				assert.NotEmpty(t, tokens.RefreshToken)
 			} else {
-				assert.Empty(t, token)
+				assert.Nil(t, tokens)
 			}
 		})
 	}
 }
+
+func TestAuthService_Refresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		rotate  func(ctx context.Context, oldHash, newHash string) (*domain.Session, error)
+		wantErr error
+	}{
+		{
+			name:  "#1 rotated",
+			token: "refresh-old",
+			rotate: func(ctx context.Context, oldHash, newHash string) (*domain.Session, error) {
+				assert.Equal(t, hashRefreshToken("refresh-old"), oldHash)
+				assert.NotEqual(t, oldHash, newHash)
+				return &domain.Session{ID: "session-2", UserID: "user-id-1", FamilyID: "family-1"}, nil
+			},
+		},
+		{
+			name:  "#2 reused token",
+			token: "refresh-old",
+			rotate: func(ctx context.Context, oldHash, newHash string) (*domain.Session, error) {
+				return nil, domain.ErrRefreshTokenReused
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name:    "#3 empty token",
+			token:   "",
+			wantErr: ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockSessionRepo{rotateFn: tt.rotate}
+			keys := testKeyring(t)
+			service := NewAuthService(&mockUserRepo{}, repo, keys, 15*time.Minute, time.Hour, zaptest.NewLogger(t))
+
+			tokens, err := service.Refresh(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, tokens)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEqual(t, tt.token, tokens.RefreshToken)
+
+			claims, err := ValidateToken(keys, tokens.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-id-1", claims.UserID)
+			assert.Equal(t, "family-1", claims.FamilyID)
+		})
+	}
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	repo := &mockSessionRepo{}
+	service := NewAuthService(&mockUserRepo{}, repo, testKeyring(t), 15*time.Minute, time.Hour, zaptest.NewLogger(t))
+
+	assert.NoError(t, service.Logout(context.Background(), "family-1"))
+	assert.Equal(t, []string{"family-1"}, repo.revoked)
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"time"
 
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/metrics"
+	"go-musthave-diploma-tpl/internal/storage"
 
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
@@ -12,52 +15,66 @@ import (
 type BalanceServicer interface {
 	GetBalance(ctx context.Context, userID string) (current, withdrawn decimal.Decimal, err error)
 	Withdraw(ctx context.Context, userID, orderNumber string, sum decimal.Decimal) error
-	ListWithdrawals(ctx context.Context, userID string) ([]postgres.Withdrawal, error)
+	ListWithdrawals(ctx context.Context, userID string, params ListParams) ([]domain.Withdrawal, string, error)
 }
 type BalanceService struct {
-	withdrawRepo *postgres.WithdrawalRepository
+	withdrawRepo storage.WithdrawalRepository
+	ledgerRepo   storage.LedgerRepository
 	logger       *zap.Logger
 }
 
-func NewBalanceService(withdrawRepo *postgres.WithdrawalRepository, logger *zap.Logger,
+func NewBalanceService(withdrawRepo storage.WithdrawalRepository, ledgerRepo storage.LedgerRepository, logger *zap.Logger,
 ) *BalanceService {
 	return &BalanceService{
 		withdrawRepo: withdrawRepo,
+		ledgerRepo:   ledgerRepo,
 		logger:       logger,
 	}
 }
 
 func (s *BalanceService) GetBalance(ctx context.Context, userID string,
 ) (current, withdrawn decimal.Decimal, err error) {
+	ctx, span := tracer.Start(ctx, "BalanceService.GetBalance")
+	defer span.End()
 
-	accrued, withdrawn, err := s.withdrawRepo.GetTotals(ctx, userID, s.logger)
-	if err != nil {
-		return decimal.Zero, decimal.Zero, err
-	}
-
-	current = accrued.Sub(withdrawn)
-	return current, withdrawn, nil
+	return s.ledgerRepo.GetBalance(ctx, userID, s.logger)
 }
 
 func (s *BalanceService) Withdraw(ctx context.Context, userID, orderNumber string, sum decimal.Decimal,
 ) error {
-	if sum.LessThanOrEqual(decimal.Zero) {
-		return postgres.ErrInvalidOrder
-	}
+	ctx, span := tracer.Start(ctx, "BalanceService.Withdraw")
+	defer span.End()
 
-	current, _, err := s.GetBalance(ctx, userID)
-	if err != nil {
-		return err
+	// баланс хранится с точностью до копеек: более точная сумма разошлась бы
+	// с проверкой остатка после округления в DECIMAL(12,2)
+	if sum.LessThanOrEqual(decimal.Zero) || !sum.Equal(sum.Round(2)) {
+		return domain.ErrInvalidSum
 	}
 
-	if current.LessThan(sum) {
-		return postgres.ErrNotEnoughFunds
+	if err := s.withdrawRepo.Withdraw(ctx, userID, orderNumber, sum, s.logger); err != nil {
+		return err
 	}
-
-	return s.withdrawRepo.Create(ctx, userID, orderNumber, sum, s.logger)
+	metrics.AddPoints(metrics.PointsWithdrawn, sum)
+	return nil
 }
 
-func (s *BalanceService) ListWithdrawals(ctx context.Context, userID string,
-) ([]postgres.Withdrawal, error) {
-	return s.withdrawRepo.ListByUser(ctx, userID, s.logger)
+// ListWithdrawals возвращает страницу списаний и курсор следующей страницы.
+// Фильтр по статусу к списаниям не применяется.
+func (s *BalanceService) ListWithdrawals(ctx context.Context, userID string, params ListParams,
+) ([]domain.Withdrawal, string, error) {
+	ctx, span := tracer.Start(ctx, "BalanceService.ListWithdrawals")
+	defer span.End()
+
+	q, err := params.query()
+	if err != nil {
+		return nil, "", err
+	}
+	list, err := s.withdrawRepo.ListByUser(ctx, userID, q, s.logger)
+	if err != nil {
+		return nil, "", err
+	}
+	list, next := page(list, params, func(w domain.Withdrawal) (time.Time, string) {
+		return w.ProcessedAt, w.ID
+	})
+	return list, next, nil
 }
@@ -0,0 +1,57 @@
+package service_test
+
+import (
+	"context"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/service"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type mockWithdrawalRepo struct {
+	calls int
+}
+
+func (m *mockWithdrawalRepo) Withdraw(ctx context.Context, userID, orderNumber string, sum decimal.Decimal, logger *zap.Logger) error {
+	m.calls++
+	return nil
+}
+
+func (m *mockWithdrawalRepo) ListByUser(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Withdrawal, error) {
+	return nil, nil
+}
+
+func TestBalanceService_Withdraw_ValidatesSum(t *testing.T) {
+	tests := []struct {
+		name    string
+		sum     string
+		wantErr error
+	}{
+		{name: "#1 целая сумма", sum: "10"},
+		{name: "#2 сумма с копейками", sum: "10.25"},
+		{name: "#3 незначащие нули после копеек", sum: "10.2500"},
+		{name: "#4 ноль", sum: "0", wantErr: domain.ErrInvalidSum},
+		{name: "#5 отрицательная сумма", sum: "-5", wantErr: domain.ErrInvalidSum},
+		{name: "#6 точнее копеек", sum: "10.001", wantErr: domain.ErrInvalidSum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockWithdrawalRepo{}
+			svc := service.NewBalanceService(repo, nil, zap.NewNop())
+
+			err := svc.Withdraw(context.Background(), "user-1", "2377225624", decimal.RequireFromString(tt.sum))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Zero(t, repo.calls, "невалидная сумма не должна доходить до хранилища")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, repo.calls)
+		})
+	}
+}
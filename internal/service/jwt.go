@@ -7,27 +7,26 @@ import (
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID   string `json:"user_id"`
+	FamilyID string `json:"sid,omitempty"` // семейство refresh-токенов; имя claim сохранено ради выданных токенов
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, secret string) (string, error) {
-	exp := time.Now().Add(time.Hour * 24)
+func GenerateToken(keys *Keyring, userID, familyID string, ttl time.Duration) (string, error) {
+	exp := time.Now().Add(ttl)
 
 	claims := &Claims{
-		UserID: userID,
+		UserID:   userID,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
-func ValidateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+func ValidateToken(keys *Keyring, tokenString string) (*Claims, error) {
+	token, err := keys.Verify(tokenString, &Claims{})
 	if err != nil {
 		return nil, err
 	}
@@ -8,41 +8,45 @@ import (
 )
 
 func TestGenerateTokenAndValidateToken(t *testing.T) {
-	secret := "mysecret"
+	keys := testKeyring(t)
 	userID := "user-123"
 
 	tests := []struct {
-		name         string
-		modifySecret bool
-		expired      bool
-		wantErr      bool
+		name      string
+		otherKeys bool
+		ttl       time.Duration
+		wantErr   bool
 	}{
 		{
-			name:         "#1 valid token",
-			modifySecret: false,
-			expired:      false,
-			wantErr:      false,
+			name:    "#1 valid token",
+			ttl:     15 * time.Minute,
+			wantErr: false,
 		},
 		{
-			name:         "#2 wrong secret",
-			modifySecret: true,
-			expired:      false,
-			wantErr:      true,
+			name:      "#2 signed by unknown keyring",
+			otherKeys: true,
+			ttl:       15 * time.Minute,
+			wantErr:   true,
+		},
+		{
+			name:    "#3 expired",
+			ttl:     -time.Minute,
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := GenerateToken(userID, secret)
+			token, err := GenerateToken(keys, userID, "session-1", tt.ttl)
 			assert.NoError(t, err, "GenerateToken() should not return error")
 			assert.NotEmpty(t, token, "GenerateToken() should return a token")
 
-			validateSecret := secret
-			if tt.modifySecret {
-				validateSecret = "wrongsecret"
+			validateKeys := keys
+			if tt.otherKeys {
+				validateKeys = testKeyring(t)
 			}
 
-			claims, err := ValidateToken(token, validateSecret)
+			claims, err := ValidateToken(validateKeys, token)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Nil(t, claims)
@@ -50,8 +54,18 @@ func TestGenerateTokenAndValidateToken(t *testing.T) {
 				assert.NoError(t, err)
 				assert.NotNil(t, claims)
 				assert.Equal(t, userID, claims.UserID)
-				assert.WithinDuration(t, time.Now().Add(24*time.Hour), claims.ExpiresAt.Time, 5*time.Second)
+				assert.Equal(t, "session-1", claims.FamilyID)
+				assert.WithinDuration(t, time.Now().Add(tt.ttl), claims.ExpiresAt.Time, 5*time.Second)
 			}
 		})
 	}
 }
+
+func testKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	keys, err := GenerateEphemeralKeyring()
+	if err != nil {
+		t.Fatalf("failed to generate keyring: %v", err)
+	}
+	return keys
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	ErrUnknownKeyID       = errors.New("unknown signing key id")
+	ErrNoActiveSigningKey = errors.New("no active signing key")
+)
+
+// SigningKey — ключ кольца. У ключей только для проверки Private == nil.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// Keyring хранит один активный ключ подписи и набор ключей для проверки,
+// что позволяет ротировать ключи без разлогина пользователей.
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeyring(active *SigningKey, verifyOnly ...*SigningKey) (*Keyring, error) {
+	if active == nil || active.Private == nil {
+		return nil, ErrNoActiveSigningKey
+	}
+
+	k := &Keyring{active: active, keys: map[string]*SigningKey{active.ID: active}}
+	for _, key := range verifyOnly {
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+	return k, nil
+}
+
+// LoadKeyring читает все *.pem из dir. Идентификатор ключа — имя файла без
+// расширения (суффикс .pub тоже отбрасывается). Приватные ключи RSA и Ed25519
+// пригодны для подписи, публичные — только для проверки. activeKID можно не
+// указывать, если в каталоге ровно один приватный ключ.
+func LoadKeyring(dir, activeKID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var (
+		active  *SigningKey
+		private []*SigningKey
+		others  []*SigningKey
+	)
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("load key %s: %w", path, err)
+		}
+		if key.Private != nil {
+			private = append(private, key)
+		}
+		if key.ID == activeKID {
+			active = key
+			continue
+		}
+		others = append(others, key)
+	}
+
+	if activeKID == "" && len(private) == 1 {
+		active = private[0]
+		others = removeKey(others, active.ID)
+	}
+	if active == nil {
+		return nil, fmt.Errorf("%w: kid %q not found in %s", ErrNoActiveSigningKey, activeKID, dir)
+	}
+
+	return NewKeyring(active, others...)
+}
+
+// GenerateEphemeralKeyring создаёт кольцо со случайным ключом Ed25519,
+// который живёт только до перезапуска процесса.
+func GenerateEphemeralKeyring() (*Keyring, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyring(&SigningKey{
+		ID:      "ephemeral",
+		Method:  jwt.SigningMethodEdDSA,
+		Private: priv,
+		Public:  pub,
+	})
+}
+
+func (k *Keyring) ActiveKeyID() string {
+	return k.active.ID
+}
+
+// Sign подписывает claims активным ключом и проставляет kid в заголовок.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.Private)
+}
+
+// Verify проверяет подпись ключом из заголовка kid. Алгоритм токена
+// должен совпадать с алгоритмом ключа.
+func (k *Keyring) Verify(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	})
+}
+
+// JWK — открытый ключ в формате RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части всех ключей кольца.
+func (k *Keyring) JWKS() JWKS {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".pem"), ".pub")
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigningKey(kid, parsed)
+}
+
+func newSigningKey(kid string, parsed any) (*SigningKey, error) {
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, Private: key, Public: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, Private: key, Public: key.Public()}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, Public: key}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, Public: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+func removeKey(keys []*SigningKey, id string) []*SigningKey {
+	res := keys[:0]
+	for _, key := range keys {
+		if key.ID != id {
+			res = append(res, key)
+		}
+	}
+	return res
+}
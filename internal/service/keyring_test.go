@@ -0,0 +1,112 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func TestLoadKeyring_Rotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePEM(t, dir, "2024-01.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "2024-02.pem", "PRIVATE KEY", der)
+
+	// старый ключ подписывает токен до ротации
+	oldRing, err := LoadKeyring(dir, "2024-01")
+	require.NoError(t, err)
+	token, err := GenerateToken(oldRing, "user-1", "family-1", time.Minute)
+	require.NoError(t, err)
+
+	// после ротации новый ключ активен, а старый токен всё ещё проходит проверку
+	newRing, err := LoadKeyring(dir, "2024-02")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02", newRing.ActiveKeyID())
+
+	claims, err := ValidateToken(newRing, token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+
+	fresh, err := GenerateToken(newRing, "user-1", "family-1", time.Minute)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(fresh, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+
+	set := newRing.JWKS()
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "RSA", set.Keys[0].KeyType)
+	assert.Equal(t, "RS256", set.Keys[0].Algorithm)
+	assert.NotEmpty(t, set.Keys[0].N)
+	assert.Equal(t, "OKP", set.Keys[1].KeyType)
+	assert.Equal(t, "Ed25519", set.Keys[1].Curve)
+	assert.NotEmpty(t, set.Keys[1].X)
+}
+
+func TestLoadKeyring_VerifyOnlyKey(t *testing.T) {
+	dir := t.TempDir()
+
+	retired, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	retiredRing, err := NewKeyring(&SigningKey{ID: "retired", Method: jwt.SigningMethodRS256, Private: retired, Public: &retired.PublicKey})
+	require.NoError(t, err)
+	token, err := GenerateToken(retiredRing, "user-1", "", time.Minute)
+	require.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&retired.PublicKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "retired.pub.pem", "PUBLIC KEY", pubDER)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "current.pem", "PRIVATE KEY", der)
+
+	ring, err := LoadKeyring(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "current", ring.ActiveKeyID())
+
+	_, err = ValidateToken(ring, token)
+	assert.NoError(t, err)
+
+	_, err = LoadKeyring(dir, "retired")
+	assert.ErrorIs(t, err, ErrNoActiveSigningKey)
+}
+
+func TestKeyring_RejectsAlgorithmMismatch(t *testing.T) {
+	ring := testKeyring(t)
+
+	// токен HS256 с тем же kid не должен проходить проверку открытым ключом
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "user-1"})
+	forged.Header["kid"] = ring.ActiveKeyID()
+	tokenString, err := forged.SignedString([]byte("guess"))
+	require.NoError(t, err)
+
+	_, err = ValidateToken(ring, tokenString)
+	assert.Error(t, err)
+}
@@ -3,16 +3,41 @@ package service
 import (
 	"context"
 	"errors"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"fmt"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/metrics"
 
 	"go.uber.org/zap"
 )
 
-var ErrOrderAlreadyUploaded = errors.New("order already uploaded by user")
+var (
+	ErrOrderAlreadyUploaded = apperr.New(apperr.CodeOrderAlreadyUploaded, "order already uploaded by user")
+	ErrOrderNumberRequired  = apperr.New(apperr.CodeOrderNumberRequired, "order number required")
+	ErrOrderBatchTooLarge   = apperr.New(apperr.CodeOrderBatchTooLarge,
+		fmt.Sprintf("batch must contain at most %d order numbers", MaxBatchOrders))
+)
+
+// MaxBatchOrders — наибольшее число номеров в одной пакетной загрузке.
+const MaxBatchOrders = 500
+
+// BatchResult — итог загрузки одного номера из пачки: Err равен nil, если заказ принят,
+// иначе ErrOrderAlreadyUploaded, domain.ErrOrderExists или domain.ErrInvalidOrder.
+type BatchResult struct {
+	Number string
+	Err    error
+}
 
 type OrdersServicer interface {
 	CreateOrder(ctx context.Context, userID, number string, logger *zap.Logger) error
-	GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]postgres.Order, error)
+	CreateOrders(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error)
+	GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error)
+	ListOrders(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error)
+	ListOrderEvents(ctx context.Context, userID string, afterID int64, logger *zap.Logger) ([]domain.OrderEvent, error)
+	GetOrderByNumber(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error)
+	ListStatusHistory(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error)
 }
 
 type OrdersService struct {
@@ -28,16 +53,19 @@ func NewOrdersService(logger *zap.Logger, orderRepo OrdersServicer) *OrdersServi
 }
 
 func (s *OrdersService) UploadOrder(ctx context.Context, userID, number string) error {
+	ctx, span := tracer.Start(ctx, "OrdersService.UploadOrder")
+	defer span.End()
+
 	if number == "" {
-		return errors.New("order number required")
+		return ErrOrderNumberRequired
 	}
 	if !isValidOrderNumber(number) {
-		return postgres.ErrInvalidOrder
+		return domain.ErrInvalidOrder
 	}
 
 	err := s.orderRepo.CreateOrder(ctx, userID, number, s.logger)
 	if err != nil {
-		if errors.Is(err, postgres.ErrOrderExists) {
+		if errors.Is(err, domain.ErrOrderExists) {
 			userOrders, getErr := s.orderRepo.GetOrderByUser(ctx, userID, s.logger)
 			if getErr == nil {
 				for _, o := range userOrders {
@@ -46,16 +74,110 @@ func (s *OrdersService) UploadOrder(ctx context.Context, userID, number string)
 					}
 				}
 			}
-			return postgres.ErrOrderExists
+			return domain.ErrOrderExists
 		}
 		return err
 	}
 
+	metrics.OrdersUploaded.Inc()
 	return nil
 }
 
-func (s *OrdersService) ListOrders(ctx context.Context, userID string) ([]postgres.Order, error) {
-	return s.orderRepo.GetOrderByUser(ctx, userID, s.logger)
+// UploadOrders загружает пачку номеров одним запросом к базе и возвращает
+// результат по каждому номеру в исходном порядке. Повтор номера внутри пачки
+// получает тот же результат, что и первое вхождение.
+func (s *OrdersService) UploadOrders(ctx context.Context, userID string, numbers []string) ([]BatchResult, error) {
+	ctx, span := tracer.Start(ctx, "OrdersService.UploadOrders")
+	defer span.End()
+
+	if len(numbers) == 0 {
+		return nil, ErrOrderNumberRequired
+	}
+	if len(numbers) > MaxBatchOrders {
+		return nil, ErrOrderBatchTooLarge
+	}
+
+	valid := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		if n != "" && isValidOrderNumber(n) {
+			valid = append(valid, n)
+		}
+	}
+
+	created := map[string]error{}
+	if len(valid) > 0 {
+		var err error
+		created, err = s.orderRepo.CreateOrders(ctx, userID, valid, s.logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]BatchResult, len(numbers))
+	accepted := make(map[string]bool)
+	for i, n := range numbers {
+		results[i].Number = n
+		err, ok := created[n]
+		switch {
+		case !ok:
+			// номер не прошёл проверку Луна и в базу не отправлялся
+			results[i].Err = domain.ErrInvalidOrder
+		case errors.Is(err, domain.ErrOrderUploadedByUser):
+			results[i].Err = ErrOrderAlreadyUploaded
+		default:
+			results[i].Err = err
+		}
+		if results[i].Err == nil {
+			accepted[n] = true
+		}
+	}
+
+	metrics.OrdersUploaded.Add(float64(len(accepted)))
+	return results, nil
+}
+
+// ListOrders возвращает страницу заказов и курсор следующей страницы.
+func (s *OrdersService) ListOrders(ctx context.Context, userID string, params ListParams) ([]domain.Order, string, error) {
+	ctx, span := tracer.Start(ctx, "OrdersService.ListOrders")
+	defer span.End()
+
+	q, err := params.query()
+	if err != nil {
+		return nil, "", err
+	}
+	orders, err := s.orderRepo.ListOrders(ctx, userID, q, s.logger)
+	if err != nil {
+		return nil, "", err
+	}
+	orders, next := page(orders, params, func(o domain.Order) (time.Time, string) {
+		return o.UploadedAt, o.ID
+	})
+	return orders, next, nil
+}
+
+// GetOrder возвращает заказ пользователя вместе с историей его статусов.
+func (s *OrdersService) GetOrder(ctx context.Context, userID, number string) (domain.Order, []domain.StatusHistoryEntry, error) {
+	ctx, span := tracer.Start(ctx, "OrdersService.GetOrder")
+	defer span.End()
+
+	order, err := s.orderRepo.GetOrderByNumber(ctx, userID, number, s.logger)
+	if err != nil {
+		return domain.Order{}, nil, err
+	}
+	history, err := s.orderRepo.ListStatusHistory(ctx, order.ID, s.logger)
+	if err != nil {
+		return domain.Order{}, nil, err
+	}
+	return order, history, nil
+}
+
+// ListOrderEvents возвращает события заказов пользователя после afterID —
+// для досылки пропущенного при переподключении SSE-клиента.
+func (s *OrdersService) ListOrderEvents(ctx context.Context, userID string, afterID int64) ([]domain.OrderEvent, error) {
+	ctx, span := tracer.Start(ctx, "OrdersService.ListOrderEvents")
+	defer span.End()
+
+	return s.orderRepo.ListOrderEvents(ctx, userID, afterID, s.logger)
 }
 
 func isValidOrderNumber(number string) bool {
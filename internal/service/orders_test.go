@@ -3,7 +3,7 @@ package service_test
 import (
 	"context"
 	"errors"
-	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/domain"
 	"go-musthave-diploma-tpl/internal/service"
 	"testing"
 	"time"
@@ -15,17 +15,41 @@ import (
 // Мок репозитория
 type mockOrdersRepo struct {
 	CreateFunc func(ctx context.Context, userID, number string, logger *zap.Logger) error
-	GetFunc    func(ctx context.Context, userID string, logger *zap.Logger) ([]postgres.Order, error)
+	BatchFunc  func(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error)
+	GetFunc    func(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error)
+	ListFunc   func(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error)
+	ByNumFunc  func(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error)
+	HistFunc   func(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error)
 }
 
 func (m *mockOrdersRepo) CreateOrder(ctx context.Context, userID, number string, logger *zap.Logger) error {
 	return m.CreateFunc(ctx, userID, number, logger)
 }
 
-func (m *mockOrdersRepo) GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]postgres.Order, error) {
+func (m *mockOrdersRepo) CreateOrders(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error) {
+	return m.BatchFunc(ctx, userID, numbers, logger)
+}
+
+func (m *mockOrdersRepo) ListOrderEvents(ctx context.Context, userID string, afterID int64, logger *zap.Logger) ([]domain.OrderEvent, error) {
+	return nil, nil
+}
+
+func (m *mockOrdersRepo) GetOrderByNumber(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error) {
+	return m.ByNumFunc(ctx, userID, number, logger)
+}
+
+func (m *mockOrdersRepo) ListStatusHistory(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error) {
+	return m.HistFunc(ctx, orderID, logger)
+}
+
+func (m *mockOrdersRepo) GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error) {
 	return m.GetFunc(ctx, userID, logger)
 }
 
+func (m *mockOrdersRepo) ListOrders(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+	return m.ListFunc(ctx, userID, q, logger)
+}
+
 func TestOrdersService_UploadOrder(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	ctx := context.Background()
@@ -71,8 +95,8 @@ func TestOrdersService_ListOrders(t *testing.T) {
 
 	t.Run("orders_exist", func(t *testing.T) {
 		mockRepo := &mockOrdersRepo{
-			GetFunc: func(ctx context.Context, userID string, logger *zap.Logger) ([]postgres.Order, error) {
-				return []postgres.Order{
+			ListFunc: func(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+				return []domain.Order{
 					{
 						ID:         "1",
 						Number:     "12345678903",
@@ -85,7 +109,7 @@ func TestOrdersService_ListOrders(t *testing.T) {
 		}
 		svc := service.NewOrdersService(logger, mockRepo)
 
-		orders, err := svc.ListOrders(ctx, "user1")
+		orders, _, err := svc.ListOrders(ctx, "user1", service.ListParams{})
 		require.NoError(t, err)
 		require.Len(t, orders, 1)
 		require.Equal(t, "12345678903", orders[0].Number)
@@ -93,27 +117,176 @@ func TestOrdersService_ListOrders(t *testing.T) {
 
 	t.Run("no_orders", func(t *testing.T) {
 		mockRepo := &mockOrdersRepo{
-			GetFunc: func(ctx context.Context, userID string, logger *zap.Logger) ([]postgres.Order, error) {
-				return []postgres.Order{}, nil
+			ListFunc: func(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+				return []domain.Order{}, nil
 			},
 		}
 		svc := service.NewOrdersService(logger, mockRepo)
 
-		orders, err := svc.ListOrders(ctx, "user1")
+		orders, _, err := svc.ListOrders(ctx, "user1", service.ListParams{})
 		require.NoError(t, err)
 		require.Len(t, orders, 0)
 	})
 
 	t.Run("error_from_repo", func(t *testing.T) {
 		mockRepo := &mockOrdersRepo{
-			GetFunc: func(ctx context.Context, userID string, logger *zap.Logger) ([]postgres.Order, error) {
+			ListFunc: func(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
 				return nil, errors.New("db error")
 			},
 		}
 		svc := service.NewOrdersService(logger, mockRepo)
 
-		orders, err := svc.ListOrders(ctx, "user1")
+		orders, _, err := svc.ListOrders(ctx, "user1", service.ListParams{})
 		require.Error(t, err)
 		require.Nil(t, orders)
 	})
 }
+
+func TestOrdersService_ListOrdersPagination(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	all := []domain.Order{
+		{ID: "00000000-0000-0000-0000-000000000003", Number: "3", UploadedAt: base.Add(3 * time.Hour)},
+		{ID: "00000000-0000-0000-0000-000000000002", Number: "2", UploadedAt: base.Add(2 * time.Hour)},
+		{ID: "00000000-0000-0000-0000-000000000001", Number: "1", UploadedAt: base.Add(time.Hour)},
+	}
+
+	var queries []domain.ListQuery
+	mockRepo := &mockOrdersRepo{
+		ListFunc: func(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error) {
+			queries = append(queries, q)
+			var res []domain.Order
+			for _, o := range all {
+				if q.After != nil && !o.UploadedAt.Before(q.After.At) {
+					continue
+				}
+				res = append(res, o)
+			}
+			if q.Limit > 0 && len(res) > q.Limit {
+				res = res[:q.Limit]
+			}
+			return res, nil
+		},
+	}
+	svc := service.NewOrdersService(logger, mockRepo)
+
+	t.Run("pages", func(t *testing.T) {
+		queries = nil
+
+		first, next, err := svc.ListOrders(ctx, "user1", service.ListParams{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, first, 2)
+		require.NotEmpty(t, next)
+		require.Equal(t, 3, queries[0].Limit, "запрашивается на одну запись больше лимита")
+
+		second, next, err := svc.ListOrders(ctx, "user1", service.ListParams{Limit: 2, Cursor: next})
+		require.NoError(t, err)
+		require.Len(t, second, 1)
+		require.Equal(t, "1", second[0].Number)
+		require.Empty(t, next)
+		require.Equal(t, all[1].ID, queries[1].After.ID)
+		require.True(t, all[1].UploadedAt.Equal(queries[1].After.At))
+	})
+
+	t.Run("invalid_cursor", func(t *testing.T) {
+		_, _, err := svc.ListOrders(ctx, "user1", service.ListParams{Limit: 2, Cursor: "garbage"})
+		require.ErrorIs(t, err, service.ErrInvalidCursor)
+	})
+
+	t.Run("cursor_direction_mismatch", func(t *testing.T) {
+		_, next, err := svc.ListOrders(ctx, "user1", service.ListParams{Limit: 1})
+		require.NoError(t, err)
+
+		_, _, err = svc.ListOrders(ctx, "user1", service.ListParams{Limit: 1, Cursor: next, Ascending: true})
+		require.ErrorIs(t, err, service.ErrInvalidCursor)
+	})
+}
+
+func TestOrdersService_UploadOrders(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	t.Run("per_item_results", func(t *testing.T) {
+		var sent []string
+		mockRepo := &mockOrdersRepo{
+			BatchFunc: func(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error) {
+				sent = numbers
+				return map[string]error{
+					"12345678903":      nil,
+					"4561261212345467": domain.ErrOrderUploadedByUser,
+					"79927398713":      domain.ErrOrderExists,
+				}, nil
+			},
+		}
+		svc := service.NewOrdersService(logger, mockRepo)
+
+		results, err := svc.UploadOrders(ctx, "user1", []string{"12345678903", "4561261212345467", "79927398713", "12345678901", ""})
+		require.NoError(t, err)
+		require.Equal(t, []string{"12345678903", "4561261212345467", "79927398713"}, sent, "невалидные номера в базу не уходят")
+		require.Len(t, results, 5)
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, service.ErrOrderAlreadyUploaded)
+		require.ErrorIs(t, results[2].Err, domain.ErrOrderExists)
+		require.ErrorIs(t, results[3].Err, domain.ErrInvalidOrder)
+		require.ErrorIs(t, results[4].Err, domain.ErrInvalidOrder)
+	})
+
+	t.Run("empty_batch", func(t *testing.T) {
+		svc := service.NewOrdersService(logger, &mockOrdersRepo{})
+
+		_, err := svc.UploadOrders(ctx, "user1", nil)
+		require.ErrorIs(t, err, service.ErrOrderNumberRequired)
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		svc := service.NewOrdersService(logger, &mockOrdersRepo{})
+
+		_, err := svc.UploadOrders(ctx, "user1", make([]string, service.MaxBatchOrders+1))
+		require.ErrorIs(t, err, service.ErrOrderBatchTooLarge)
+	})
+
+	t.Run("all_invalid_skips_repo", func(t *testing.T) {
+		svc := service.NewOrdersService(logger, &mockOrdersRepo{})
+
+		results, err := svc.UploadOrders(ctx, "user1", []string{"123"})
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, domain.ErrInvalidOrder)
+	})
+}
+
+func TestOrdersService_GetOrder(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	t.Run("with_history", func(t *testing.T) {
+		mockRepo := &mockOrdersRepo{
+			ByNumFunc: func(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error) {
+				return domain.Order{ID: "order-1", Number: number, UserID: userID, Status: "INVALID"}, nil
+			},
+			HistFunc: func(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error) {
+				require.Equal(t, "order-1", orderID)
+				return []domain.StatusHistoryEntry{{OldStatus: "NEW", NewStatus: "INVALID"}}, nil
+			},
+		}
+		svc := service.NewOrdersService(logger, mockRepo)
+
+		order, history, err := svc.GetOrder(ctx, "user1", "12345678903")
+		require.NoError(t, err)
+		require.Equal(t, domain.OrderStatusInvalid, order.Status)
+		require.Len(t, history, 1)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		mockRepo := &mockOrdersRepo{
+			ByNumFunc: func(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error) {
+				return domain.Order{}, domain.ErrOrderNotFound
+			},
+		}
+		svc := service.NewOrdersService(logger, mockRepo)
+
+		_, _, err := svc.GetOrder(ctx, "user1", "12345678903")
+		require.ErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/domain"
+)
+
+var ErrInvalidCursor = apperr.New(apperr.CodeInvalidCursor, "invalid cursor")
+
+// ListParams — параметры списка заказов или списаний.
+// Нулевой Limit без курсора означает «весь список», как до появления пагинации.
+type ListParams struct {
+	Limit     int
+	Cursor    string
+	Statuses  []domain.OrderStatus
+	From, To  time.Time
+	Ascending bool
+}
+
+// cursorToken — содержимое непрозрачного курсора. Направление сортировки
+// зашито в курсор, чтобы его нельзя было продолжить в обратную сторону.
+type cursorToken struct {
+	At  time.Time `json:"t"`
+	ID  string    `json:"id"`
+	Asc bool      `json:"asc,omitempty"`
+}
+
+func encodeCursor(at time.Time, id string, asc bool) string {
+	raw, _ := json.Marshal(cursorToken{At: at, ID: id, Asc: asc})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string, asc bool) (*domain.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var tok cursorToken
+	if err := json.Unmarshal(raw, &tok); err != nil || tok.ID == "" || tok.At.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	if tok.Asc != asc {
+		return nil, ErrInvalidCursor
+	}
+	return &domain.Cursor{At: tok.At, ID: tok.ID}, nil
+}
+
+// query переводит параметры в запрос к репозиторию. Лимит берётся с запасом
+// в одну запись, чтобы понять, есть ли следующая страница.
+func (p ListParams) query() (domain.ListQuery, error) {
+	q := domain.ListQuery{
+		Statuses:  p.Statuses,
+		From:      p.From,
+		To:        p.To,
+		Ascending: p.Ascending,
+	}
+	if p.Cursor != "" {
+		after, err := decodeCursor(p.Cursor, p.Ascending)
+		if err != nil {
+			return domain.ListQuery{}, err
+		}
+		q.After = after
+	}
+	if p.Limit > 0 {
+		q.Limit = p.Limit + 1
+	}
+	return q, nil
+}
+
+// page обрезает выборку до p.Limit и возвращает курсор следующей страницы
+// или пустую строку, если страница последняя.
+func page[T any](items []T, p ListParams, key func(T) (time.Time, string)) ([]T, string) {
+	if p.Limit <= 0 || len(items) <= p.Limit {
+		return items, ""
+	}
+	items = items[:p.Limit]
+	at, id := key(items[len(items)-1])
+	return items, encodeCursor(at, id, p.Ascending)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"slices"
+
+	"go-musthave-diploma-tpl/internal/apperr"
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/storage"
+	"go-musthave-diploma-tpl/internal/webhook"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidWebhookURL       = apperr.New(apperr.CodeInvalidWebhookURL, "webhook url must be an absolute http or https url")
+	ErrWebhookURLNotAllowed    = apperr.New(apperr.CodeWebhookURLNotAllowed, "webhook url must point to a public address")
+	ErrInvalidWebhookEventType = apperr.New(apperr.CodeInvalidWebhookEventType, "unknown or unsupported webhook event type")
+	ErrInvalidWebhookSecret    = apperr.New(apperr.CodeInvalidWebhookSecret, "webhook secret is too short")
+)
+
+const (
+	// minWebhookSecretLen — минимальная длина секрета, заданного клиентом.
+	minWebhookSecretLen = 16
+	// webhookDeliveriesLimit — сколько последних доставок отдаёт журнал.
+	webhookDeliveriesLimit = 100
+)
+
+// WebhookServicer управляет подписками владельца userID; пустой userID —
+// партнёрские подписки на события всех пользователей.
+type WebhookServicer interface {
+	Create(ctx context.Context, userID, rawURL, secret string, eventTypes []string) (domain.WebhookSubscription, error)
+	List(ctx context.Context, userID string) ([]domain.WebhookSubscription, error)
+	Delete(ctx context.Context, userID, id string) error
+	ListDeliveries(ctx context.Context, userID, id string) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, userID, id string, deliveryID int64) error
+}
+
+type WebhookService struct {
+	repo         storage.WebhookRepository
+	allowPrivate bool
+	logger       *zap.Logger
+}
+
+func NewWebhookService(repo storage.WebhookRepository, cfg *config.Config, logger *zap.Logger) *WebhookService {
+	return &WebhookService{repo: repo, allowPrivate: cfg.WebhookAllowPrivateNetworks, logger: logger}
+}
+
+// Create проверяет адрес и типы событий и сохраняет подписку. Адрес во
+// внутренней сети отвергается, если это не разрешено конфигурацией. Без
+// secret генерируется случайный; клиент видит секрет только в ответе на создание.
+func (s *WebhookService) Create(ctx context.Context, userID, rawURL, secret string, eventTypes []string,
+) (domain.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Create")
+	defer span.End()
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return domain.WebhookSubscription{}, ErrInvalidWebhookURL
+	}
+	if !s.allowPrivate && webhook.CheckHost(u.Hostname()) != nil {
+		return domain.WebhookSubscription{}, ErrWebhookURLNotAllowed
+	}
+
+	var types []string
+	for _, t := range eventTypes {
+		if !webhook.Subscribable(t) {
+			return domain.WebhookSubscription{}, ErrInvalidWebhookEventType
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return domain.WebhookSubscription{}, ErrInvalidWebhookEventType
+	}
+
+	switch {
+	case secret == "":
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return domain.WebhookSubscription{}, err
+		}
+		secret = "whsec_" + hex.EncodeToString(buf)
+	case len(secret) < minWebhookSecretLen:
+		return domain.WebhookSubscription{}, ErrInvalidWebhookSecret
+	}
+
+	return s.repo.CreateWebhook(ctx, domain.WebhookSubscription{
+		UserID:     userID,
+		URL:        u.String(),
+		Secret:     secret,
+		EventTypes: types,
+	}, s.logger)
+}
+
+func (s *WebhookService) List(ctx context.Context, userID string) ([]domain.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.List")
+	defer span.End()
+
+	return s.repo.ListWebhooks(ctx, userID, s.logger)
+}
+
+func (s *WebhookService) Delete(ctx context.Context, userID, id string) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.Delete")
+	defer span.End()
+
+	if uuid.Validate(id) != nil {
+		return domain.ErrWebhookNotFound
+	}
+	return s.repo.DeleteWebhook(ctx, userID, id, s.logger)
+}
+
+// ListDeliveries возвращает последние доставки подписки, начиная с новых.
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, id string) ([]domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	if uuid.Validate(id) != nil {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return s.repo.ListWebhookDeliveries(ctx, userID, id, webhookDeliveriesLimit, s.logger)
+}
+
+// Redeliver ставит доставку в очередь заново, каким бы ни был её итог.
+func (s *WebhookService) Redeliver(ctx context.Context, userID, id string, deliveryID int64) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	if uuid.Validate(id) != nil {
+		return domain.ErrDeliveryNotFound
+	}
+	return s.repo.RedeliverWebhook(ctx, userID, id, deliveryID, s.logger)
+}
@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"context"
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+	"go-musthave-diploma-tpl/internal/repository/memory"
+	"go-musthave-diploma-tpl/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWebhookService_Create(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		secret       string
+		eventTypes   []string
+		allowPrivate bool
+		wantTypes    []string
+		wantErr      error
+	}{
+		{
+			name:       "#1 секрет генерируется, дубликаты типов убираются",
+			url:        "https://hooks.example.com/gophermart",
+			eventTypes: []string{outbox.TypeOrderProcessed, outbox.TypeWithdrawalCreated, outbox.TypeOrderProcessed},
+			wantTypes:  []string{outbox.TypeOrderProcessed, outbox.TypeWithdrawalCreated},
+		},
+		{
+			name:         "#2 свой секрет, локальный адрес разрешён явно",
+			url:          "http://localhost:9000/hook",
+			secret:       "0123456789abcdef",
+			eventTypes:   []string{outbox.TypeOrderInvalid},
+			allowPrivate: true,
+			wantTypes:    []string{outbox.TypeOrderInvalid},
+		},
+		{name: "#3 не http", url: "ftp://example.com", eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrInvalidWebhookURL},
+		{name: "#4 относительный адрес", url: "/hook", eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrInvalidWebhookURL},
+		{name: "#5 без типов событий", url: "https://example.com", wantErr: service.ErrInvalidWebhookEventType},
+		{
+			name: "#6 на регистрацию подписаться нельзя", url: "https://example.com",
+			eventTypes: []string{outbox.TypeUserRegistered}, wantErr: service.ErrInvalidWebhookEventType,
+		},
+		{
+			name: "#7 короткий секрет", url: "https://example.com", secret: "short",
+			eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrInvalidWebhookSecret,
+		},
+		{
+			name: "#8 localhost", url: "http://localhost:9000/hook",
+			eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrWebhookURLNotAllowed,
+		},
+		{
+			name: "#9 частная сеть", url: "http://10.0.0.7/hook",
+			eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrWebhookURLNotAllowed,
+		},
+		{
+			name: "#10 метаданные облака", url: "http://169.254.169.254/latest/meta-data",
+			eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrWebhookURLNotAllowed,
+		},
+		{
+			name: "#11 loopback IPv6", url: "http://[::1]:8080/hook",
+			eventTypes: []string{outbox.TypeOrderProcessed}, wantErr: service.ErrWebhookURLNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{WebhookAllowPrivateNetworks: tt.allowPrivate}
+			svc := service.NewWebhookService(memory.New(), cfg, zap.NewNop())
+
+			sub, err := svc.Create(context.Background(), "user1", tt.url, tt.secret, tt.eventTypes)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, sub.ID)
+			require.Equal(t, tt.wantTypes, sub.EventTypes)
+			if tt.secret != "" {
+				require.Equal(t, tt.secret, sub.Secret)
+			} else {
+				require.True(t, strings.HasPrefix(sub.Secret, "whsec_"), sub.Secret)
+			}
+		})
+	}
+}
+
+func TestWebhookService_RejectsMalformedIDs(t *testing.T) {
+	svc := service.NewWebhookService(memory.New(), &config.Config{}, zap.NewNop())
+	ctx := context.Background()
+
+	require.ErrorIs(t, svc.Delete(ctx, "user1", "not-a-uuid"), domain.ErrWebhookNotFound)
+	_, err := svc.ListDeliveries(ctx, "user1", "not-a-uuid")
+	require.ErrorIs(t, err, domain.ErrWebhookNotFound)
+	require.ErrorIs(t, svc.Redeliver(ctx, "user1", "not-a-uuid", 1), domain.ErrDeliveryNotFound)
+}
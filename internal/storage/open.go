@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/repository/memory"
+	"go-musthave-diploma-tpl/internal/repository/postgres"
+	"go-musthave-diploma-tpl/internal/repository/sqlite"
+
+	"go.uber.org/zap"
+)
+
+// MemoryScheme — схема DATABASE_URI, при которой данные хранятся в памяти.
+const MemoryScheme = "memory://"
+
+// SQLiteScheme — схема DATABASE_URI для SQLite: sqlite://<путь к файлу>.
+const SQLiteScheme = "sqlite://"
+
+// IsMemory сообщает, что uri выбирает хранилище в памяти: схемой memory://
+// или пустым значением, когда DATABASE_URI не задан.
+func IsMemory(uri string) bool {
+	return uri == "" || strings.HasPrefix(uri, MemoryScheme)
+}
+
+// Open подключает бэкенд, выбранный по uri. pool применяется только к PostgreSQL.
+func Open(ctx context.Context, uri string, pool postgres.PoolConfig, logger *zap.Logger) (*Storage, error) {
+	if IsMemory(uri) {
+		return NewMemory(memory.New()), nil
+	}
+	if path, ok := strings.CutPrefix(uri, SQLiteScheme); ok {
+		return OpenSQLite(path, logger)
+	}
+	db, err := postgres.NewDBStorage(ctx, uri, pool, logger)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostgres(db, logger), nil
+}
+
+func NewMemory(store *memory.Store) *Storage {
+	return &Storage{
+		Users:       store,
+		Sessions:    store,
+		Orders:      store,
+		Withdrawals: store,
+		Ledger:      store,
+		Idempotency: store,
+		Outbox:      store,
+		Webhooks:    store,
+		Events:      store,
+		Backend:     "memory",
+	}
+}
+
+// OpenSQLite открывает файл SQLite и применяет к нему миграции: в отличие
+// от PostgreSQL, база принадлежит процессу и отдельного шага миграций нет.
+func OpenSQLite(path string, logger *zap.Logger) (*Storage, error) {
+	db, err := sqlite.NewDBStorage(path, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RunMigrations(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	events := sqlite.NewNotifier()
+	return &Storage{
+		Users:       sqlite.NewUserRepository(db.DB),
+		Sessions:    sqlite.NewSessionRepository(db.DB),
+		Orders:      sqlite.NewOrderRepository(db.DB, events),
+		Withdrawals: sqlite.NewWithdrawalRepository(db.DB),
+		Ledger:      sqlite.NewLedgerRepository(db.DB),
+		Idempotency: sqlite.NewIdempotencyRepository(db.DB),
+		Outbox:      sqlite.NewOutboxRepository(db.DB),
+		Webhooks:    sqlite.NewWebhookRepository(db.DB),
+		Events:      events,
+		Backend:     "sqlite",
+		ping:        db.PingContext,
+		close:       db.Close,
+	}, nil
+}
+
+// NewPostgres собирает репозитории поверх пула соединений PostgreSQL.
+func NewPostgres(db *postgres.DBStorage, logger *zap.Logger) *Storage {
+	return &Storage{
+		Users:       postgres.NewUserRepository(db.Pool),
+		Sessions:    postgres.NewSessionRepository(db.Pool),
+		Orders:      postgres.NewOrderRepository(db.Pool),
+		Withdrawals: postgres.NewWithdrawalRepository(db.Pool),
+		Ledger:      postgres.NewLedgerRepository(db.Pool),
+		Idempotency: postgres.NewIdempotencyRepository(db.Pool),
+		Outbox:      postgres.NewOutboxRepository(db.Pool),
+		Webhooks:    postgres.NewWebhookRepository(db.Pool),
+		Events:      postgresEvents{db: db, logger: logger},
+		Backend:     "postgres",
+		Postgres:    db,
+		ping:        db.PingContext,
+		close:       db.Close,
+	}
+}
+
+type postgresEvents struct {
+	db     *postgres.DBStorage
+	logger *zap.Logger
+}
+
+func (e postgresEvents) Listen(ctx context.Context, publish func(domain.OrderEvent)) error {
+	return postgres.NewOrderEventListener(e.db.Pool, publish, e.logger).Run(ctx)
+}
@@ -0,0 +1,130 @@
+// Package storage описывает репозитории, от которых зависят сервисы,
+// независимо от бэкенда, и выбирает бэкенд по DATABASE_URI.
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+	"go-musthave-diploma-tpl/internal/repository/postgres"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, login, passwordHash string, logger *zap.Logger) (string, error)
+	GetUserByLogin(ctx context.Context, login string, logger *zap.Logger) (*domain.User, error)
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time, logger *zap.Logger) (*domain.Session, error)
+	RotateSession(ctx context.Context, oldHash, newHash string, newExpiresAt time.Time, logger *zap.Logger) (*domain.Session, error)
+	RevokeFamily(ctx context.Context, familyID string, logger *zap.Logger) error
+	IsSessionActive(ctx context.Context, familyID string, logger *zap.Logger) (bool, error)
+}
+
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, userID, number string, logger *zap.Logger) error
+	CreateOrders(ctx context.Context, userID string, numbers []string, logger *zap.Logger) (map[string]error, error)
+	GetOrderByUser(ctx context.Context, userID string, logger *zap.Logger) ([]domain.Order, error)
+	ListOrders(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Order, error)
+	GetOrderByNumber(ctx context.Context, userID, number string, logger *zap.Logger) (domain.Order, error)
+	ListStatusHistory(ctx context.Context, orderID string, logger *zap.Logger) ([]domain.StatusHistoryEntry, error)
+	ListOrderEvents(ctx context.Context, userID string, afterID int64, logger *zap.Logger) ([]domain.OrderEvent, error)
+
+	ClaimOrdersForProcessing(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.Order, error)
+	ReleaseOrder(ctx context.Context, orderID, workerID string, logger *zap.Logger) error
+	ReleaseWorkerOrders(ctx context.Context, workerID string, logger *zap.Logger) error
+	ScheduleRetry(ctx context.Context, orderID string, nextPollAt time.Time, lastErr string, logger *zap.Logger) error
+	MarkOrderFailed(ctx context.Context, orderID, lastErr string, logger *zap.Logger) error
+	UpdateOrderStatus(ctx context.Context, orderID string, change domain.StatusChange, logger *zap.Logger) error
+	GetBacklog(ctx context.Context, logger *zap.Logger) (map[string]int, time.Time, error)
+}
+
+type WithdrawalRepository interface {
+	Withdraw(ctx context.Context, userID, orderNumber string, sum decimal.Decimal, logger *zap.Logger) error
+	ListByUser(ctx context.Context, userID string, q domain.ListQuery, logger *zap.Logger) ([]domain.Withdrawal, error)
+}
+
+// LedgerRepository — журнал транзакций с баллами и балансы, которые он поддерживает.
+type LedgerRepository interface {
+	Post(ctx context.Context, p domain.Posting, logger *zap.Logger) error
+	GetBalance(ctx context.Context, userID string, logger *zap.Logger) (current, withdrawn decimal.Decimal, err error)
+	ListEntries(ctx context.Context, userID string, logger *zap.Logger) ([]domain.LedgerEntry, error)
+	Reconcile(ctx context.Context, logger *zap.Logger) ([]string, error)
+}
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, userID, endpoint, key, requestHash string, ttl time.Duration, logger *zap.Logger) (*domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, userID, endpoint, key string, statusCode int, contentType string, body []byte, logger *zap.Logger) error
+	Release(ctx context.Context, userID, endpoint, key string, logger *zap.Logger) error
+	DeleteExpired(ctx context.Context, logger *zap.Logger) (int64, error)
+}
+
+// OutboxRepository — доменные события, записанные вместе с изменениями
+// и ожидающие доставки relay.
+type OutboxRepository interface {
+	ClaimOutbox(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]outbox.Message, error)
+	MarkOutboxPublished(ctx context.Context, seq int64, logger *zap.Logger) error
+	RetryOutbox(ctx context.Context, seq int64, nextAttemptAt time.Time, lastErr string, logger *zap.Logger) error
+}
+
+// WebhookRepository — подписки на вебхуки и журнал их доставок. Пустой
+// userID означает партнёрские подписки.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, sub domain.WebhookSubscription, logger *zap.Logger) (domain.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context, userID string, logger *zap.Logger) ([]domain.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, userID, id string, logger *zap.Logger) error
+	EnqueueWebhookDeliveries(ctx context.Context, ev outbox.Event, payload []byte, logger *zap.Logger) (int, error)
+	ClaimWebhookDeliveries(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, id int64, res domain.DeliveryResult, logger *zap.Logger) error
+	ListWebhookDeliveries(ctx context.Context, userID, webhookID string, limit int, logger *zap.Logger) ([]domain.WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, userID, webhookID string, deliveryID int64, logger *zap.Logger) error
+}
+
+// EventSource доставляет в publish события заказов, записанные бэкендом,
+// пока не отменён ctx.
+type EventSource interface {
+	Listen(ctx context.Context, publish func(domain.OrderEvent)) error
+}
+
+// Storage — репозитории одного бэкенда.
+type Storage struct {
+	Users       UserRepository
+	Sessions    SessionRepository
+	Orders      OrderRepository
+	Withdrawals WithdrawalRepository
+	Ledger      LedgerRepository
+	Idempotency IdempotencyRepository
+	Outbox      OutboxRepository
+	Webhooks    WebhookRepository
+	Events      EventSource
+
+	// Backend — имя бэкенда для логов: memory, sqlite или postgres.
+	Backend string
+
+	// Postgres — подключение к PostgreSQL для миграций и проверок схемы;
+	// nil у остальных бэкендов.
+	Postgres *postgres.DBStorage
+
+	ping  func(ctx context.Context) error
+	close func() error
+}
+
+// Ping проверяет доступность бэкенда.
+func (s *Storage) Ping(ctx context.Context) error {
+	if s.ping == nil {
+		return nil
+	}
+	return s.ping(ctx)
+}
+
+func (s *Storage) Close() error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
+}
@@ -0,0 +1,641 @@
+// Package storagetest — общий набор проверок семантики хранилища,
+// который прогоняется на каждом бэкенде.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+	"go-musthave-diploma-tpl/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// Run прогоняет проверки на хранилище из open. Данные каждой проверки
+// уникальны, поэтому бэкенд может быть общим и непустым.
+func Run(t *testing.T, open func(t *testing.T) *storage.Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s *storage.Storage)
+	}{
+		{"users", testUsers},
+		{"sessions", testSessions},
+		{"orders uniqueness", testOrdersUniqueness},
+		{"orders listing", testOrdersListing},
+		{"status transitions", testStatusTransitions},
+		{"order leases", testOrderLeases},
+		{"withdrawals and balance", testWithdrawals},
+		{"concurrent withdrawals", testConcurrentWithdrawals},
+		{"idempotency keys", testIdempotency},
+		{"outbox", testOutbox},
+		{"webhooks", testWebhooks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+var logger = zap.NewNop()
+
+func unique(prefix string) string {
+	return prefix + "-" + uuid.NewString()
+}
+
+func newUser(t *testing.T, s *storage.Storage) string {
+	t.Helper()
+	id, err := s.Users.CreateUser(context.Background(), unique("user"), "hash", logger)
+	require.NoError(t, err)
+	return id
+}
+
+func testUsers(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	login := unique("login")
+
+	id, err := s.Users.CreateUser(ctx, login, "hash", logger)
+	require.NoError(t, err)
+
+	_, err = s.Users.CreateUser(ctx, login, "other", logger)
+	assert.ErrorIs(t, err, domain.ErrUserExists)
+
+	u, err := s.Users.GetUserByLogin(ctx, login, logger)
+	require.NoError(t, err)
+	assert.Equal(t, id, u.ID)
+	assert.Equal(t, "hash", u.PasswordHash)
+
+	_, err = s.Users.GetUserByLogin(ctx, unique("missing"), logger)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func testSessions(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	first, second := unique("token"), unique("token")
+
+	sess, err := s.Sessions.CreateSession(ctx, userID, first, time.Now().Add(time.Hour), logger)
+	require.NoError(t, err)
+
+	rotated, err := s.Sessions.RotateSession(ctx, first, second, time.Now().Add(time.Hour), logger)
+	require.NoError(t, err)
+	assert.Equal(t, sess.FamilyID, rotated.FamilyID)
+
+	active, err := s.Sessions.IsSessionActive(ctx, sess.FamilyID, logger)
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	_, err = s.Sessions.RotateSession(ctx, first, unique("token"), time.Now().Add(time.Hour), logger)
+	assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+
+	active, err = s.Sessions.IsSessionActive(ctx, sess.FamilyID, logger)
+	require.NoError(t, err)
+	assert.False(t, active, "повторное предъявление токена отзывает семейство")
+
+	_, err = s.Sessions.RotateSession(ctx, unique("token"), unique("token"), time.Now().Add(time.Hour), logger)
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+}
+
+func testOrdersUniqueness(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	owner, other := newUser(t, s), newUser(t, s)
+	number, fresh := unique("order"), unique("order")
+
+	require.NoError(t, s.Orders.CreateOrder(ctx, owner, number, logger))
+	assert.ErrorIs(t, s.Orders.CreateOrder(ctx, owner, number, logger), domain.ErrOrderExists)
+	assert.ErrorIs(t, s.Orders.CreateOrder(ctx, other, number, logger), domain.ErrOrderExists)
+
+	results, err := s.Orders.CreateOrders(ctx, other, []string{number, fresh, fresh}, logger)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.ErrorIs(t, results[number], domain.ErrOrderExists)
+	assert.NoError(t, results[fresh])
+
+	results, err = s.Orders.CreateOrders(ctx, other, []string{fresh}, logger)
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[fresh], domain.ErrOrderUploadedByUser)
+
+	o, err := s.Orders.GetOrderByNumber(ctx, owner, number, logger)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusNew, o.Status)
+	assert.False(t, o.Accrual.Valid)
+
+	_, err = s.Orders.GetOrderByNumber(ctx, other, number, logger)
+	assert.ErrorIs(t, err, domain.ErrOrderNotFound, "чужой заказ неотличим от несуществующего")
+}
+
+func testOrdersListing(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	_, err := s.Orders.CreateOrders(ctx, userID, []string{unique("order"), unique("order"), unique("order")}, logger)
+	require.NoError(t, err)
+
+	first, err := s.Orders.ListOrders(ctx, userID, domain.ListQuery{Limit: 2}, logger)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	last := first[len(first)-1]
+	rest, err := s.Orders.ListOrders(ctx, userID, domain.ListQuery{
+		Limit: 2,
+		After: &domain.Cursor{At: last.UploadedAt, ID: last.ID},
+	}, logger)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+
+	seen := map[string]bool{}
+	for _, o := range append(first, rest...) {
+		assert.False(t, seen[o.ID], "страницы не пересекаются")
+		seen[o.ID] = true
+	}
+
+	asc, err := s.Orders.ListOrders(ctx, userID, domain.ListQuery{Ascending: true}, logger)
+	require.NoError(t, err)
+	require.Len(t, asc, 3)
+	assert.Equal(t, rest[0].ID, asc[0].ID)
+
+	processed, err := s.Orders.ListOrders(ctx, userID, domain.ListQuery{Statuses: []domain.OrderStatus{domain.OrderStatusProcessed}}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, processed)
+
+	later, err := s.Orders.ListOrders(ctx, userID, domain.ListQuery{From: time.Now().Add(time.Hour)}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, later)
+}
+
+func testStatusTransitions(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	number := unique("order")
+	require.NoError(t, s.Orders.CreateOrder(ctx, userID, number, logger))
+	o, err := s.Orders.GetOrderByNumber(ctx, userID, number, logger)
+	require.NoError(t, err)
+
+	events, err := s.Orders.ListOrderEvents(ctx, userID, 0, logger)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	err = s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+		From: domain.OrderStatusNew, Status: domain.OrderStatusProcessing, Source: domain.StatusSourceWorker,
+	}, logger)
+	require.NoError(t, err)
+
+	err = s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+		From: domain.OrderStatusNew, Status: domain.OrderStatusInvalid, Source: domain.StatusSourceWorker,
+	}, logger)
+	assert.ErrorIs(t, err, domain.ErrStatusConflict)
+
+	points := decimal.RequireFromString("100.5")
+	err = s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+		From: domain.OrderStatusProcessing, Status: domain.OrderStatusProcessed, Accrual: &points,
+		Source: domain.StatusSourceWorker, Response: []byte(`{"status":"PROCESSED"}`),
+	}, logger)
+	require.NoError(t, err)
+
+	err = s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+		From: domain.OrderStatusProcessed, Status: domain.OrderStatusInvalid, Source: domain.StatusSourceAdmin,
+	}, logger)
+	assert.ErrorIs(t, err, domain.ErrTransitionNotAllowed)
+
+	o, err = s.Orders.GetOrderByNumber(ctx, userID, number, logger)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusProcessed, o.Status)
+	assert.True(t, o.Accrual.Decimal.Equal(points))
+
+	current, withdrawn, err := s.Ledger.GetBalance(ctx, userID, logger)
+	require.NoError(t, err)
+	assert.True(t, current.Equal(points), "начисление зачислено на баланс: %s", current)
+	assert.True(t, withdrawn.IsZero())
+
+	history, err := s.Orders.ListStatusHistory(ctx, o.ID, logger)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, domain.OrderStatusNew, history[0].OldStatus)
+	assert.Equal(t, domain.OrderStatusProcessed, history[1].NewStatus)
+	assert.JSONEq(t, `{"status":"PROCESSED"}`, string(history[1].Response))
+
+	events, err = s.Orders.ListOrderEvents(ctx, userID, 0, logger)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Less(t, events[0].ID, events[1].ID)
+	assert.Equal(t, domain.OrderStatusProcessed, events[1].Status)
+
+	tail, err := s.Orders.ListOrderEvents(ctx, userID, events[0].ID, logger)
+	require.NoError(t, err)
+	assert.Len(t, tail, 1)
+}
+
+func testOrderLeases(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	number := unique("order")
+	require.NoError(t, s.Orders.CreateOrder(ctx, userID, number, logger))
+	o, err := s.Orders.GetOrderByNumber(ctx, userID, number, logger)
+	require.NoError(t, err)
+
+	workerA, workerB := unique("worker"), unique("worker")
+	t.Cleanup(func() {
+		_ = s.Orders.ReleaseWorkerOrders(ctx, workerA, logger)
+		_ = s.Orders.ReleaseWorkerOrders(ctx, workerB, logger)
+	})
+
+	claimed := func(worker string) bool {
+		orders, err := s.Orders.ClaimOrdersForProcessing(ctx, worker, 1000, time.Minute, logger)
+		require.NoError(t, err)
+		for _, c := range orders {
+			if c.ID == o.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.True(t, claimed(workerA))
+	assert.False(t, claimed(workerB), "заказ в аренде у другого воркера")
+
+	require.NoError(t, s.Orders.ReleaseOrder(ctx, o.ID, workerA, logger))
+	require.NoError(t, s.Orders.ScheduleRetry(ctx, o.ID, time.Now().Add(time.Hour), "later", logger))
+	assert.False(t, claimed(workerB), "опрос отложен")
+
+	require.NoError(t, s.Orders.ScheduleRetry(ctx, o.ID, time.Now().Add(-time.Second), "", logger))
+	assert.True(t, claimed(workerB))
+
+	counts, oldest, err := s.Orders.GetBacklog(ctx, logger)
+	require.NoError(t, err)
+	assert.Positive(t, counts[string(domain.OrderStatusNew)])
+	assert.False(t, oldest.IsZero())
+
+	require.NoError(t, s.Orders.MarkOrderFailed(ctx, o.ID, "gave up", logger))
+	require.NoError(t, s.Orders.MarkOrderFailed(ctx, o.ID, "again", logger), "терминальный статус не меняется")
+	o, err = s.Orders.GetOrderByNumber(ctx, userID, number, logger)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusInvalid, o.Status)
+
+	history, err := s.Orders.ListStatusHistory(ctx, o.ID, logger)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "gave up", history[0].Reason.String)
+}
+
+func testWithdrawals(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	ref := unique("accrual")
+
+	current, withdrawn, err := s.Ledger.GetBalance(ctx, userID, logger)
+	require.NoError(t, err)
+	assert.True(t, current.IsZero())
+	assert.True(t, withdrawn.IsZero())
+
+	posting := domain.Posting{
+		Kind:      domain.LedgerKindAccrual,
+		Reference: ref,
+		UserID:    userID,
+		From:      domain.AccountAccrualSource,
+		To:        domain.AccountUserPoints,
+		Amount:    decimal.NewFromInt(100),
+	}
+	require.NoError(t, s.Ledger.Post(ctx, posting, logger))
+	assert.ErrorIs(t, s.Ledger.Post(ctx, posting, logger), domain.ErrPostingExists)
+
+	posting.Reference, posting.Amount = unique("accrual"), decimal.Zero
+	assert.ErrorIs(t, s.Ledger.Post(ctx, posting, logger), domain.ErrInvalidPosting)
+
+	number := unique("withdrawal")
+	err = s.Withdrawals.Withdraw(ctx, userID, number, decimal.NewFromInt(150), logger)
+	assert.ErrorIs(t, err, domain.ErrNotEnoughFunds)
+
+	require.NoError(t, s.Withdrawals.Withdraw(ctx, userID, number, decimal.RequireFromString("40.5"), logger))
+	err = s.Withdrawals.Withdraw(ctx, userID, number, decimal.NewFromInt(1), logger)
+	assert.ErrorIs(t, err, domain.ErrInvalidOrder)
+
+	current, withdrawn, err = s.Ledger.GetBalance(ctx, userID, logger)
+	require.NoError(t, err)
+	assert.True(t, current.Equal(decimal.RequireFromString("59.5")), "current = %s", current)
+	assert.True(t, withdrawn.Equal(decimal.RequireFromString("40.5")), "withdrawn = %s", withdrawn)
+
+	list, err := s.Withdrawals.ListByUser(ctx, userID, domain.ListQuery{}, logger)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, number, list[0].OrderNumber)
+	assert.Equal(t, "40.50", list[0].Sum)
+
+	entries, err := s.Ledger.ListEntries(ctx, userID, logger)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	mismatched, err := s.Ledger.Reconcile(ctx, logger)
+	require.NoError(t, err)
+	assert.NotContains(t, mismatched, userID)
+}
+
+// testConcurrentWithdrawals: параллельные списания не уводят баланс в минус,
+// проходит ровно столько, сколько покрывает баланс.
+func testConcurrentWithdrawals(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	require.NoError(t, s.Ledger.Post(ctx, domain.Posting{
+		Kind:      domain.LedgerKindAccrual,
+		Reference: unique("accrual"),
+		UserID:    userID,
+		From:      domain.AccountAccrualSource,
+		To:        domain.AccountUserPoints,
+		Amount:    decimal.NewFromInt(100),
+	}, logger))
+
+	const attempts = 25
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+	)
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Withdrawals.Withdraw(ctx, userID, unique("withdrawal"), decimal.NewFromInt(10), logger)
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrNotEnoughFunds)
+		}()
+	}
+	wg.Wait()
+
+	current, withdrawn, err := s.Ledger.GetBalance(ctx, userID, logger)
+	require.NoError(t, err)
+	assert.Equal(t, int32(10), succeeded.Load())
+	assert.True(t, current.IsZero(), "current = %s", current)
+	assert.True(t, withdrawn.Equal(decimal.NewFromInt(100)), "withdrawn = %s", withdrawn)
+}
+
+func testIdempotency(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	key := unique("key")
+
+	rec, reserved, err := s.Idempotency.Reserve(ctx, userID, "POST /api/user/orders", key, "hash-1", time.Hour, logger)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, "hash-1", rec.RequestHash)
+
+	rec, reserved, err = s.Idempotency.Reserve(ctx, userID, "POST /api/user/orders", key, "hash-2", time.Hour, logger)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, "hash-1", rec.RequestHash)
+	assert.Zero(t, rec.StatusCode, "первый запрос ещё выполняется")
+
+	require.NoError(t, s.Idempotency.Complete(ctx, userID, "POST /api/user/orders", key, 202, "text/plain", []byte("ok"), logger))
+	rec, _, err = s.Idempotency.Reserve(ctx, userID, "POST /api/user/orders", key, "hash-1", time.Hour, logger)
+	require.NoError(t, err)
+	assert.Equal(t, 202, rec.StatusCode)
+	assert.Equal(t, []byte("ok"), rec.ResponseBody)
+
+	require.NoError(t, s.Idempotency.Release(ctx, userID, "POST /api/user/orders", key, logger))
+	_, reserved, err = s.Idempotency.Reserve(ctx, userID, "POST /api/user/orders", key, "hash-3", time.Hour, logger)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func testOutbox(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	userID := newUser(t, s)
+	number := unique("order")
+	require.NoError(t, s.Orders.CreateOrder(ctx, userID, number, logger))
+	o, err := s.Orders.GetOrderByNumber(ctx, userID, number, logger)
+	require.NoError(t, err)
+
+	points := decimal.NewFromInt(10)
+	err = s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+		From: domain.OrderStatusNew, Status: domain.OrderStatusProcessed, Accrual: &points, Source: domain.StatusSourceWorker,
+	}, logger)
+	require.NoError(t, err)
+	require.NoError(t, s.Withdrawals.Withdraw(ctx, userID, unique("withdrawal"), decimal.RequireFromString("2.5"), logger))
+
+	// неудачный переход и отказ в списании событий не создают
+	err = s.Orders.UpdateOrderStatus(ctx, o.ID, domain.StatusChange{
+		From: domain.OrderStatusNew, Status: domain.OrderStatusInvalid, Source: domain.StatusSourceWorker,
+	}, logger)
+	require.ErrorIs(t, err, domain.ErrStatusConflict)
+	err = s.Withdrawals.Withdraw(ctx, userID, unique("withdrawal"), decimal.NewFromInt(100), logger)
+	require.ErrorIs(t, err, domain.ErrNotEnoughFunds)
+
+	worker := unique("relay")
+	msgs := claimUserOutbox(t, s, worker, userID)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, outbox.TypeUserRegistered, msgs[0].Event.Type)
+	assert.Equal(t, outbox.TypeOrderProcessed, msgs[1].Event.Type)
+	assert.Equal(t, outbox.TypeWithdrawalCreated, msgs[2].Event.Type)
+	for _, m := range msgs {
+		assert.Equal(t, 1, m.Attempts)
+		assert.Equal(t, outbox.SchemaVersion, m.Event.SchemaVersion)
+		assert.NotEmpty(t, m.Event.ID)
+		assert.False(t, m.Event.OccurredAt.IsZero())
+	}
+	assert.JSONEq(t, `{"order_id":"`+o.ID+`","number":"`+number+`","previous_status":"NEW","status":"PROCESSED","accrual":"10"}`,
+		string(msgs[1].Event.Data))
+
+	assert.Empty(t, claimUserOutbox(t, s, unique("relay"), userID), "события в аренде другого relay")
+
+	require.NoError(t, s.Outbox.MarkOutboxPublished(ctx, msgs[0].Seq, logger))
+	require.NoError(t, s.Outbox.RetryOutbox(ctx, msgs[1].Seq, time.Now().Add(time.Hour), "sink is down", logger))
+	require.NoError(t, s.Outbox.RetryOutbox(ctx, msgs[2].Seq, time.Now().Add(-time.Second), "sink is down", logger))
+
+	again := claimUserOutbox(t, s, worker, userID)
+	require.Len(t, again, 1)
+	assert.Equal(t, msgs[2].Seq, again[0].Seq)
+	assert.Equal(t, 2, again[0].Attempts)
+	assert.Equal(t, msgs[2].Event.ID, again[0].Event.ID)
+	require.NoError(t, s.Outbox.MarkOutboxPublished(ctx, again[0].Seq, logger))
+}
+
+// claimUserOutbox берёт в аренду все доступные события outbox и возвращает
+// события userID; чужие события, оставшиеся от других проверок, освобождаются.
+func claimUserOutbox(t *testing.T, s *storage.Storage, worker, userID string) []outbox.Message {
+	t.Helper()
+	ctx := context.Background()
+
+	var res []outbox.Message
+	for {
+		msgs, err := s.Outbox.ClaimOutbox(ctx, worker, 100, time.Minute, logger)
+		require.NoError(t, err)
+		if len(msgs) == 0 {
+			break
+		}
+		for _, m := range msgs {
+			if m.Event.UserID == userID {
+				res = append(res, m)
+				continue
+			}
+			defer func() {
+				require.NoError(t, s.Outbox.RetryOutbox(ctx, m.Seq, time.Now(), "", logger))
+			}()
+		}
+	}
+	return res
+}
+
+func testWebhooks(t *testing.T, s *storage.Storage) {
+	ctx := context.Background()
+	alice, bob := newUser(t, s), newUser(t, s)
+
+	create := func(userID string, types ...string) domain.WebhookSubscription {
+		t.Helper()
+		sub, err := s.Webhooks.CreateWebhook(ctx, domain.WebhookSubscription{
+			UserID: userID, URL: "https://hooks.example.com/" + unique("hook"), Secret: unique("secret"), EventTypes: types,
+		}, logger)
+		require.NoError(t, err)
+		require.NotEmpty(t, sub.ID)
+		assert.False(t, sub.CreatedAt.IsZero())
+		return sub
+	}
+	aliceSub := create(alice, outbox.TypeOrderProcessed, outbox.TypeWithdrawalCreated)
+	create(bob, outbox.TypeOrderProcessed)
+	partner := create("", outbox.TypeWithdrawalCreated)
+	// партнёрская подписка общая для всех пользователей бэкенда
+	defer func() {
+		require.NoError(t, s.Webhooks.DeleteWebhook(ctx, "", partner.ID, logger))
+	}()
+
+	subs, err := s.Webhooks.ListWebhooks(ctx, alice, logger)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, aliceSub.ID, subs[0].ID)
+	assert.Equal(t, aliceSub.Secret, subs[0].Secret)
+	assert.Equal(t, []string{outbox.TypeOrderProcessed, outbox.TypeWithdrawalCreated}, subs[0].EventTypes)
+
+	partners, err := s.Webhooks.ListWebhooks(ctx, "", logger)
+	require.NoError(t, err)
+	assert.Contains(t, webhookIDs(partners), partner.ID)
+	assert.NotContains(t, webhookIDs(partners), aliceSub.ID)
+
+	enqueue := func(ev outbox.Event) int {
+		t.Helper()
+		payload, err := json.Marshal(ev)
+		require.NoError(t, err)
+		n, err := s.Webhooks.EnqueueWebhookDeliveries(ctx, ev, payload, logger)
+		require.NoError(t, err)
+		return n
+	}
+	withdrawal := outbox.NewWithdrawalCreated(alice, outbox.WithdrawalCreated{
+		WithdrawalID: unique("withdrawal"), Order: unique("order"), Sum: decimal.NewFromInt(5), ProcessedAt: time.Now(),
+	})
+	assert.Equal(t, 2, enqueue(withdrawal), "подписка владельца и партнёрская")
+	assert.Equal(t, 0, enqueue(withdrawal), "повторная постановка не дублирует доставки")
+	assert.Equal(t, 1, enqueue(outbox.NewOrderStatusChanged(alice, outbox.OrderStatusChanged{
+		OrderID: unique("order"), Number: unique("number"), PreviousStatus: "PROCESSING", Status: "PROCESSED",
+	})), "подписка другого пользователя событие не получает")
+	assert.Equal(t, 0, enqueue(outbox.NewOrderStatusChanged(alice, outbox.OrderStatusChanged{
+		OrderID: unique("order"), Number: unique("number"), PreviousStatus: "NEW", Status: "INVALID",
+	})), "на тип события никто не подписан")
+
+	ours := map[string]bool{aliceSub.ID: true, partner.ID: true}
+	worker := unique("webhooks")
+	claimed := claimDeliveries(t, s, worker, ours)
+	require.Len(t, claimed, 3)
+	var own []domain.WebhookDelivery
+	for _, d := range claimed {
+		assert.Equal(t, 1, d.Attempts)
+		assert.NotEmpty(t, d.Payload)
+		if d.SubscriptionID == aliceSub.ID {
+			assert.Equal(t, aliceSub.URL, d.URL)
+			assert.Equal(t, aliceSub.Secret, d.Secret)
+			own = append(own, d)
+		}
+	}
+	require.Len(t, own, 2)
+	assert.Empty(t, claimDeliveries(t, s, unique("webhooks"), ours), "доставки в аренде другого воркера")
+
+	for _, d := range claimed {
+		res := domain.DeliveryResult{Status: domain.DeliverySucceeded, ResponseStatus: 204}
+		if d.EventType == outbox.TypeOrderProcessed {
+			res = domain.DeliveryResult{
+				Status: domain.DeliveryPending, ResponseStatus: 502, Error: "bad gateway", NextAttemptAt: time.Now().Add(time.Hour),
+			}
+		}
+		require.NoError(t, s.Webhooks.CompleteWebhookDelivery(ctx, d.ID, res, logger))
+	}
+	assert.Empty(t, claimDeliveries(t, s, worker, ours), "повтор отложен, остальные доставлены")
+
+	log, err := s.Webhooks.ListWebhookDeliveries(ctx, alice, aliceSub.ID, 10, logger)
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	assert.Greater(t, log[0].ID, log[1].ID, "сначала последние")
+	byType := map[string]domain.WebhookDelivery{}
+	for _, d := range log {
+		byType[d.EventType] = d
+	}
+	delivered := byType[outbox.TypeWithdrawalCreated]
+	assert.Equal(t, domain.DeliverySucceeded, delivered.Status)
+	assert.Equal(t, 204, delivered.ResponseStatus)
+	assert.False(t, delivered.DeliveredAt.IsZero())
+	assert.Equal(t, withdrawal.ID, delivered.EventID)
+	failed := byType[outbox.TypeOrderProcessed]
+	assert.Equal(t, domain.DeliveryPending, failed.Status)
+	assert.Equal(t, 502, failed.ResponseStatus)
+	assert.Equal(t, "bad gateway", failed.LastError)
+	assert.True(t, failed.DeliveredAt.IsZero())
+
+	_, err = s.Webhooks.ListWebhookDeliveries(ctx, bob, aliceSub.ID, 10, logger)
+	assert.ErrorIs(t, err, domain.ErrWebhookNotFound)
+	err = s.Webhooks.RedeliverWebhook(ctx, bob, aliceSub.ID, delivered.ID, logger)
+	assert.ErrorIs(t, err, domain.ErrDeliveryNotFound)
+	err = s.Webhooks.RedeliverWebhook(ctx, "", partner.ID, delivered.ID, logger)
+	assert.ErrorIs(t, err, domain.ErrDeliveryNotFound, "доставка другой подписки")
+
+	require.NoError(t, s.Webhooks.RedeliverWebhook(ctx, alice, aliceSub.ID, delivered.ID, logger))
+	again := claimDeliveries(t, s, worker, ours)
+	require.Len(t, again, 1)
+	assert.Equal(t, delivered.ID, again[0].ID)
+	assert.Equal(t, 1, again[0].Attempts, "счётчик попыток обнулён")
+
+	assert.ErrorIs(t, s.Webhooks.DeleteWebhook(ctx, bob, aliceSub.ID, logger), domain.ErrWebhookNotFound)
+	require.NoError(t, s.Webhooks.DeleteWebhook(ctx, alice, aliceSub.ID, logger))
+	_, err = s.Webhooks.ListWebhookDeliveries(ctx, alice, aliceSub.ID, 10, logger)
+	assert.ErrorIs(t, err, domain.ErrWebhookNotFound)
+}
+
+func webhookIDs(subs []domain.WebhookSubscription) []string {
+	ids := make([]string, 0, len(subs))
+	for _, s := range subs {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// claimDeliveries берёт в аренду все доступные доставки и возвращает
+// доставки подписок subs; чужие доставки освобождаются.
+func claimDeliveries(t *testing.T, s *storage.Storage, worker string, subs map[string]bool) []domain.WebhookDelivery {
+	t.Helper()
+	ctx := context.Background()
+
+	var res []domain.WebhookDelivery
+	for {
+		deliveries, err := s.Webhooks.ClaimWebhookDeliveries(ctx, worker, 100, time.Minute, logger)
+		require.NoError(t, err)
+		if len(deliveries) == 0 {
+			break
+		}
+		for _, d := range deliveries {
+			if subs[d.SubscriptionID] {
+				res = append(res, d)
+				continue
+			}
+			defer func() {
+				require.NoError(t, s.Webhooks.CompleteWebhookDelivery(ctx, d.ID, domain.DeliveryResult{
+					Status: domain.DeliveryPending, NextAttemptAt: time.Now(),
+				}, logger))
+			}()
+		}
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+// Package tracingtest подключает in-process экспортёр спанов для тестов.
+package tracingtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	once     sync.Once
+	exporter = tracetest.NewInMemoryExporter()
+)
+
+// Install делает глобальным провайдер с синхронным in-memory экспортёром
+// и очищает ранее записанные спаны. Глобальный провайдер в otel можно
+// подменить лишь один раз для уже созданных трейсеров, поэтому он общий
+// на весь тестовый бинарник, а тесты, записывающие спаны, не должны идти параллельно.
+func Install(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	once.Do(func() {
+		if _, err := tracing.Setup(context.Background(), tracing.ExporterNone); err != nil {
+			t.Fatal(err)
+		}
+		otel.SetTracerProvider(tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter)))
+	})
+	exporter.Reset()
+	t.Cleanup(exporter.Reset)
+	return exporter
+}
+
+// SpanNames возвращает имена записанных спанов в порядке завершения.
+func SpanNames(exporter *tracetest.InMemoryExporter) []string {
+	var names []string
+	for _, s := range exporter.GetSpans() {
+		names = append(names, s.Name)
+	}
+	return names
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrPrivateAddress — адрес доставки ведёт во внутреннюю сеть. Иначе
+// подписка позволяла бы обращаться от имени сервера к сервисам рядом с ним.
+var ErrPrivateAddress = errors.New("webhook destination is not a public address")
+
+// reservedPrefixes — непубличные диапазоны, которых нет среди проверок netip.Addr.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // «эта» сеть
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // служебные адреса IETF
+	netip.MustParsePrefix("198.18.0.0/15"), // тестирование производительности
+	netip.MustParsePrefix("240.0.0.0/4"),   // зарезервировано, включая broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64 может вести в частную сеть IPv4
+}
+
+// PublicIP сообщает, что в ip можно доставлять вебхуки: он не loopback,
+// не link-local, не из частных, служебных или multicast-диапазонов.
+func PublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost отвергает хосты, которые заведомо ведут во внутреннюю сеть:
+// непубличные IP-литералы и имена localhost. Имя, разрешающееся во
+// внутренний адрес, отвергается позже, при соединении.
+func CheckHost(host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !PublicIP(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// dialControl проверяет адрес уже после разрешения имени, поэтому DNS-запись,
+// указывающая во внутреннюю сеть или изменённая после создания подписки,
+// не обходит проверку.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicIP(addr.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package webhook_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/webhook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "224.0.0.1", want: false},
+		{ip: "255.255.255.255", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "64:ff9b::a00:1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, webhook.PublicIP(netip.MustParseAddr(tt.ip)))
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{host: "hooks.example.com"},
+		{host: "93.184.216.34"},
+		{host: "localhost", wantErr: true},
+		{host: "LOCALHOST.", wantErr: true},
+		{host: "api.localhost", wantErr: true},
+		{host: "127.0.0.1", wantErr: true},
+		{host: "::1", wantErr: true},
+		{host: "169.254.169.254", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := webhook.CheckHost(tt.host)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, webhook.ErrPrivateAddress)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package webhook доставляет доменные события в HTTP-подписки пользователей
+// и партнёров. Тело запроса — конверт события outbox, подписанный секретом
+// подписки по HMAC-SHA256.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/outbox"
+)
+
+// Заголовки запроса доставки.
+const (
+	SignatureHeader = "X-Gophermart-Signature"
+	EventHeader     = "X-Gophermart-Event"
+	EventIDHeader   = "X-Gophermart-Event-ID"
+	DeliveryHeader  = "X-Gophermart-Delivery"
+)
+
+// EventTypes — события, на которые можно подписаться: изменения
+// статуса заказа и списания баллов.
+var EventTypes = []string{
+	outbox.TypeOrderProcessing,
+	outbox.TypeOrderProcessed,
+	outbox.TypeOrderInvalid,
+	outbox.TypeWithdrawalCreated,
+}
+
+// Subscribable сообщает, что на событие eventType можно подписаться.
+func Subscribable(eventType string) bool {
+	return slices.Contains(EventTypes, eventType)
+}
+
+var (
+	ErrMalformedSignature = errors.New("malformed webhook signature header")
+	ErrSignatureMismatch  = errors.New("webhook signature mismatch")
+	ErrSignatureExpired   = errors.New("webhook signature timestamp is out of tolerance")
+)
+
+// Sign возвращает значение заголовка подписи
+// t=<unix-время>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>. Время входит
+// в подпись, чтобы получатель мог отвергать перехваченные старые запросы.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+// Verify проверяет заголовок подписи header для тела body на момент now.
+// Подпись, созданная дальше tolerance от now, отвергается; нулевой
+// tolerance отключает проверку времени.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var (
+		t    string
+		sigs []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrMalformedSignature
+	}
+	if tolerance > 0 {
+		if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	want := signature(secret, t, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func signature(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
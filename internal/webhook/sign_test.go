@@ -0,0 +1,47 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/webhook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+	signedAt := time.Unix(1_700_000_000, 0)
+	header := webhook.Sign("secret", signedAt, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "#1 верная подпись", secret: "secret", header: header, body: body, now: signedAt.Add(time.Minute)},
+		{
+			name: "#2 одна из нескольких подписей при смене секрета", secret: "secret",
+			header: header + ",v1=" + "00ff", body: body, now: signedAt,
+		},
+		{name: "#3 другой секрет", secret: "other", header: header, body: body, now: signedAt, wantErr: webhook.ErrSignatureMismatch},
+		{name: "#4 изменённое тело", secret: "secret", header: header, body: []byte(`{"id":"event-2"}`), now: signedAt, wantErr: webhook.ErrSignatureMismatch},
+		{name: "#5 устаревшая подпись", secret: "secret", header: header, body: body, now: signedAt.Add(10 * time.Minute), wantErr: webhook.ErrSignatureExpired},
+		{name: "#6 без времени", secret: "secret", header: "v1=abc", body: body, now: signedAt, wantErr: webhook.ErrMalformedSignature},
+		{name: "#7 мусор", secret: "secret", header: "garbage", body: body, now: signedAt, wantErr: webhook.ErrMalformedSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+// Queue — очередь доставок вебхуков в хранилище.
+type Queue interface {
+	EnqueueWebhookDeliveries(ctx context.Context, ev outbox.Event, payload []byte, logger *zap.Logger) (int, error)
+	ClaimWebhookDeliveries(ctx context.Context, workerID string, limit int, lease time.Duration, logger *zap.Logger) ([]domain.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, id int64, res domain.DeliveryResult, logger *zap.Logger) error
+}
+
+// Sink — приёмник outbox, который превращает событие в доставки по
+// подпискам. Сам он ничего не отправляет: запросы шлёт Worker, поэтому
+// недоступный адрес одного партнёра не задерживает relay.
+type Sink struct {
+	queue  Queue
+	logger *zap.Logger
+}
+
+func NewSink(queue Queue, logger *zap.Logger) *Sink {
+	return &Sink{queue: queue, logger: logger}
+}
+
+func (s *Sink) Publish(ctx context.Context, ev outbox.Event) error {
+	if !Subscribable(ev.Type) {
+		return nil
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	n, err := s.queue.EnqueueWebhookDeliveries(ctx, ev, payload, s.logger)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.Debug("webhook deliveries enqueued",
+			zap.String("event_id", ev.ID),
+			zap.String("type", ev.Type),
+			zap.Int("count", n),
+		)
+	}
+	return nil
+}
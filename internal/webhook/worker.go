@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/metrics"
+	"go-musthave-diploma-tpl/internal/outbox"
+
+	"go.uber.org/zap"
+)
+
+// dbTimeout ограничивает отдельные обращения воркера к базе.
+const dbTimeout = 2 * time.Second
+
+// Worker отправляет доставки из очереди. Ответ 2xx завершает доставку,
+// иначе она повторяется с экспоненциальной задержкой, пока не исчерпает
+// maxAttempts и не станет failed. Реплики делят очередь через аренду.
+type Worker struct {
+	Queue  Queue
+	Client *http.Client
+	Logger *zap.Logger
+
+	workerID     string
+	batchSize    int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+}
+
+func NewWorker(queue Queue, cfg *config.Config, logger *zap.Logger) *Worker {
+	dialer := &net.Dialer{Timeout: cfg.WebhookTimeout}
+	if !cfg.WebhookAllowPrivateNetworks {
+		dialer.Control = dialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// через прокси соединение шло бы к нему, и проверка адреса теряла бы смысл
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Worker{
+		Queue: queue,
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.WebhookTimeout,
+			// перенаправление считается неудачной доставкой: подписан исходный адрес
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Logger:       logger,
+		workerID:     cfg.AccrualWorkerID,
+		batchSize:    cfg.WebhookBatchSize,
+		pollInterval: cfg.WebhookPollInterval,
+		// аренда переживает запрос с запасом на запись результата
+		lease:       2*cfg.WebhookTimeout + dbTimeout,
+		maxAttempts: cfg.WebhookMaxAttempts,
+		backoffBase: cfg.WebhookBackoffBase,
+		backoffMax:  cfg.WebhookBackoffMax,
+	}
+}
+
+// Run отправляет доставки каждые pollInterval до отмены ctx.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.Logger.Info("webhook worker stopped", zap.Error(ctx.Err()))
+			return
+		case <-ticker.C:
+			for w.Flush(ctx) == w.batchSize && ctx.Err() == nil {
+			}
+		}
+	}
+}
+
+// Flush берёт в аренду пачку доставок и отправляет их параллельно.
+// Возвращает число взятых доставок.
+func (w *Worker) Flush(ctx context.Context) int {
+	dbCtx, cancel := context.WithTimeout(ctx, dbTimeout)
+	deliveries, err := w.Queue.ClaimWebhookDeliveries(dbCtx, w.workerID, w.batchSize, w.lease, w.Logger)
+	cancel()
+	if err != nil {
+		w.Logger.Error("failed to claim webhook deliveries", zap.Error(err))
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.process(ctx, d)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+func (w *Worker) process(ctx context.Context, d domain.WebhookDelivery) {
+	log := w.Logger.With(
+		zap.Int64("delivery_id", d.ID),
+		zap.String("subscription_id", d.SubscriptionID),
+		zap.String("event_id", d.EventID),
+		zap.Int("attempts", d.Attempts),
+	)
+
+	res := w.send(ctx, d)
+	switch {
+	case res.Status == domain.DeliverySucceeded:
+		metrics.WebhookDeliveries.WithLabelValues("succeeded").Inc()
+	case d.Attempts >= w.maxAttempts:
+		res.Status = domain.DeliveryFailed
+		metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+		log.Warn("webhook delivery failed permanently", zap.String("error", res.Error))
+	default:
+		res.Status = domain.DeliveryPending
+		res.NextAttemptAt = time.Now().Add(outbox.Backoff(d.Attempts-1, w.backoffBase, w.backoffMax))
+		metrics.WebhookDeliveries.WithLabelValues("retry").Inc()
+		log.Info("webhook delivery will be retried",
+			zap.Time("next_attempt_at", res.NextAttemptAt),
+			zap.String("error", res.Error),
+		)
+	}
+
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dbTimeout)
+	defer cancel()
+	if err := w.Queue.CompleteWebhookDelivery(dbCtx, d.ID, res, log); err != nil {
+		// доставка уйдёт повторно после истечения аренды
+		log.Error("failed to record webhook delivery attempt", zap.Error(err))
+	}
+}
+
+// send выполняет одну попытку доставки. Подпись считается заново при
+// каждой попытке, чтобы её время было свежим.
+func (w *Worker) send(ctx context.Context, d domain.WebhookDelivery) domain.DeliveryResult {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return domain.DeliveryResult{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gophermart-webhooks/1")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(EventIDHeader, d.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, time.Now(), d.Payload))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return domain.DeliveryResult{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	// дочитываем немного тела, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return domain.DeliveryResult{
+			ResponseStatus: resp.StatusCode,
+			Error:          fmt.Sprintf("unexpected status %d", resp.StatusCode),
+		}
+	}
+	return domain.DeliveryResult{Status: domain.DeliverySucceeded, ResponseStatus: resp.StatusCode}
+}
@@ -0,0 +1,142 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/config"
+	"go-musthave-diploma-tpl/internal/domain"
+	"go-musthave-diploma-tpl/internal/outbox"
+	"go-musthave-diploma-tpl/internal/repository/memory"
+	"go-musthave-diploma-tpl/internal/storage"
+	"go-musthave-diploma-tpl/internal/webhook"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+var workerConfig = &config.Config{
+	AccrualWorkerID:     "webhooks-1",
+	WebhookBatchSize:    10,
+	WebhookPollInterval: 10 * time.Millisecond,
+	WebhookTimeout:      time.Second,
+	WebhookMaxAttempts:  2,
+	WebhookBackoffBase:  time.Microsecond,
+	WebhookBackoffMax:   time.Microsecond,
+	// httptest слушает на 127.0.0.1
+	WebhookAllowPrivateNetworks: true,
+}
+
+func TestWorkerDeliversSignedEvents(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   domain.DeliveryStatus
+		wantAttempts int
+	}{
+		{name: "#1 2xx с первой попытки", statuses: []int{http.StatusNoContent}, wantStatus: domain.DeliverySucceeded, wantAttempts: 1},
+		{name: "#2 повтор после 5xx", statuses: []int{http.StatusBadGateway, http.StatusOK}, wantStatus: domain.DeliverySucceeded, wantAttempts: 2},
+		{
+			name: "#3 попытки исчерпаны", statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantStatus: domain.DeliveryFailed, wantAttempts: 2,
+		},
+		{name: "#4 перенаправление не выполняется", statuses: []int{http.StatusFound, http.StatusOK}, wantStatus: domain.DeliverySucceeded, wantAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			logger := zaptest.NewLogger(t)
+			s := storage.NewMemory(memory.New())
+			ev := outbox.NewWithdrawalCreated("user-1", outbox.WithdrawalCreated{
+				WithdrawalID: "withdrawal-1", Order: "2377225624", Sum: decimal.NewFromInt(5), ProcessedAt: time.Now(),
+			})
+
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.NoError(t, webhook.Verify("secret", r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()))
+				assert.Equal(t, outbox.TypeWithdrawalCreated, r.Header.Get(webhook.EventHeader))
+				assert.Equal(t, ev.ID, r.Header.Get(webhook.EventIDHeader))
+				assert.NotEmpty(t, r.Header.Get(webhook.DeliveryHeader))
+				if tt.statuses[n-1] == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			sub, err := s.Webhooks.CreateWebhook(ctx, domain.WebhookSubscription{
+				URL: srv.URL, Secret: "secret", EventTypes: []string{outbox.TypeWithdrawalCreated},
+			}, logger)
+			require.NoError(t, err)
+
+			sink := webhook.NewSink(s.Webhooks, logger)
+			require.NoError(t, sink.Publish(ctx, ev))
+			require.NoError(t, sink.Publish(ctx, outbox.NewUserRegistered("user-1", outbox.UserRegistered{Login: "alice"})),
+				"на регистрацию подписаться нельзя, событие пропускается")
+
+			worker := webhook.NewWorker(s.Webhooks, workerConfig, logger)
+			for range tt.statuses {
+				time.Sleep(time.Millisecond) // ждём окончания задержки перед повтором
+				assert.Equal(t, 1, worker.Flush(ctx))
+			}
+			assert.Zero(t, worker.Flush(ctx), "доставка завершена")
+			assert.Equal(t, len(tt.statuses), int(calls.Load()))
+
+			log, err := s.Webhooks.ListWebhookDeliveries(ctx, "", sub.ID, 10, logger)
+			require.NoError(t, err)
+			require.Len(t, log, 1)
+			assert.Equal(t, tt.wantStatus, log[0].Status)
+			assert.Equal(t, tt.wantAttempts, log[0].Attempts)
+			assert.Equal(t, tt.statuses[len(tt.statuses)-1], log[0].ResponseStatus)
+			assert.Equal(t, ev.ID, log[0].EventID)
+			if tt.wantStatus == domain.DeliveryFailed {
+				assert.Equal(t, "unexpected status "+strconv.Itoa(http.StatusInternalServerError), log[0].LastError)
+			}
+		})
+	}
+}
+
+func TestWorkerRefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+	s := storage.NewMemory(memory.New())
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	sub, err := s.Webhooks.CreateWebhook(ctx, domain.WebhookSubscription{
+		URL: srv.URL, Secret: "secret", EventTypes: []string{outbox.TypeWithdrawalCreated},
+	}, logger)
+	require.NoError(t, err)
+	ev := outbox.NewWithdrawalCreated("user-1", outbox.WithdrawalCreated{
+		WithdrawalID: "withdrawal-1", Order: "2377225624", Sum: decimal.NewFromInt(5), ProcessedAt: time.Now(),
+	})
+	require.NoError(t, webhook.NewSink(s.Webhooks, logger).Publish(ctx, ev))
+
+	cfg := *workerConfig
+	cfg.WebhookAllowPrivateNetworks = false
+	worker := webhook.NewWorker(s.Webhooks, &cfg, logger)
+	assert.Equal(t, 1, worker.Flush(ctx))
+	assert.Zero(t, calls.Load(), "соединение с 127.0.0.1 не устанавливается")
+
+	log, err := s.Webhooks.ListWebhookDeliveries(ctx, "", sub.ID, 10, logger)
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.Equal(t, domain.DeliveryPending, log[0].Status)
+	assert.Contains(t, log[0].LastError, webhook.ErrPrivateAddress.Error())
+}